@@ -5,18 +5,16 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/go-redis/redis/v8"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
 type dataSources struct {
-	DB            *sqlx.DB
-	RedisClient   *redis.Client
-	StorageClient *storage.Client
+	DB          *sqlx.DB
+	RedisClient *redis.Client
+	Images      *imageStorage
 }
 
 // initDS establishes connections to fields in dataSources
@@ -63,21 +61,16 @@ func initDS() (*dataSources, error) {
 		return nil, fmt.Errorf("error connecting to redis: %w", err)
 	}
 
-	// Initialize google cloud
-	log.Printf("Connecting to cloud storage\n")
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel() // release operation if slow Operation completes before time elapses
-
-	storage, err := storage.NewClient(ctx)
+	// images are stored wherever IMAGE_STORAGE selects
+	images, err := initImageStorage()
 	if err != nil {
-		return nil, fmt.Errorf("error creating cloud storage: %w", err)
+		return nil, err
 	}
 
 	return &dataSources{
-		DB:            db,
-		RedisClient:   rdb,
-		StorageClient: storage,
+		DB:          db,
+		RedisClient: rdb,
+		Images:      images,
 	}, nil
 }
 
@@ -91,8 +84,8 @@ func (d *dataSources) close() error {
 		return fmt.Errorf("error closing Redis client: %w", err)
 	}
 
-	if err := d.StorageClient.Close(); err != nil {
-		return fmt.Errorf("error closing Cloud storage client: %w", err)
+	if err := d.Images.close(); err != nil {
+		return fmt.Errorf("error closing image storage: %w", err)
 	}
 
 	return nil
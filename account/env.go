@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// envInt parses the environment variable name as an int,
+// returning def when it isn't set
+func envInt(name string, def int64) (int64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	i, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %s as int: %w", name, err)
+	}
+
+	return i, nil
+}
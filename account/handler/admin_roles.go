@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type assignRoleReq struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ListRoles handler
+func (h *Handler) ListRoles(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	roles, err := h.AdminService.ListRoles(ctx)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles": roles,
+	})
+}
+
+// AssignRole handler
+func (h *Handler) AssignRole(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	var req assignRoleReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.AdminService.AssignRole(ctx, authUser, uid, req.Role); err != nil {
+		log.Printf("Failed to assign role: %v to uid: %v: %v\n", req.Role, uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// RevokeRole handler
+func (h *Handler) RevokeRole(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	role := c.Param("role")
+
+	ctx := c.Request.Context()
+
+	if err := h.AdminService.RevokeRole(ctx, authUser, uid, role); err != nil {
+		log.Printf("Failed to revoke role: %v from uid: %v: %v\n", role, uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// uidParam parses the :uid path parameter, responding
+// with a bad request if it is not a valid uuid
+func uidParam(c *gin.Context) (uuid.UUID, bool) {
+	return uuidParam(c, "uid")
+}
+
+// uuidParam parses the named path param as a uuid, responding with a bad request when invalid
+func uuidParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		e := apperrors.NewBadRequest(fmt.Sprintf("%s must be a valid uuid", name))
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		roles := []*model.Role{
+			{Name: model.RoleAdmin, Permissions: []string{model.PermissionUsersRead}},
+		}
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("ListRoles", mock.Anything).Return(roles, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin/roles", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"roles": roles,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockAdminService.AssertExpectations(t)
+	})
+}
+
+func TestAssignRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   adminUID,
+		Roles: []string{model.RoleAdmin},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("AssignRole", mock.Anything, ctxUser, uid, model.RoleSupport).Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"role": model.RoleSupport,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/roles", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid uid", func(t *testing.T) {
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"role": model.RoleSupport,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/admin/users/notauuid/roles", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "AssignRole")
+	})
+
+	t.Run("Missing role", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/roles", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "AssignRole")
+	})
+}
+
+func TestRevokeRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   adminUID,
+		Roles: []string{model.RoleAdmin},
+	}
+
+	t.Run("Error from AdminService", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockErr := apperrors.NewBadRequest("cannot revoke the last remaining admin")
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("RevokeRole", mock.Anything, ctxUser, uid, model.RoleAdmin).Return(mockErr)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s/roles/%s", uid, model.RoleAdmin), nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, mockErr.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockAdminService.AssertExpectations(t)
+	})
+}
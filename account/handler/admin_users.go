@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type searchUsersReq struct {
+	Query  string `form:"q"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// fields left out are left as they are. status is only accepted to refuse
+// it, as changing it also signs the user out and needs a reason
+type updateUserReq struct {
+	Name          *string `json:"name" binding:"omitempty,max=40"`
+	Email         *string `json:"email" binding:"omitempty,email"`
+	Website       *string `json:"website" binding:"omitempty,url"`
+	EmailVerified *bool   `json:"email_verified"`
+	Status        string  `json:"status"`
+}
+
+type resetPasswordReq struct {
+	Password string `json:"password" binding:"required,gte=6,lte=30"`
+}
+
+type disableUserReq struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// until is optional, a suspension without it lasts until the user is enabled
+type suspendUserReq struct {
+	Reason string     `json:"reason" binding:"required,max=500"`
+	Until  *time.Time `json:"until"`
+}
+
+// SearchUsers handler finds users by email, name or uid
+func (h *Handler) SearchUsers(c *gin.Context) {
+	var req searchUsersReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	page, err := h.AdminService.SearchUsers(ctx, req.Query, req.Cursor, req.Limit)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUser handler returns any user along with their roles
+func (h *Handler) GetUser(c *gin.Context) {
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	u, err := h.AdminService.GetUser(ctx, uid)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":        u,
+		"roles":       u.Roles,
+		"permissions": u.Permissions,
+	})
+}
+
+// UpdateUser handler lets an admin edit the details of any user and mark
+// their email as verified or not. Details left out of the request are kept.
+// Their status is changed by suspending, disabling or enabling them instead
+func (h *Handler) UpdateUser(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	var req updateUserReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if req.Status != "" {
+		err := apperrors.NewBadRequest("status is changed by suspending, disabling or enabling the user")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	current, err := h.AdminService.GetUser(ctx, uid)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	u := &model.User{
+		UID:     uid,
+		Name:    current.Name,
+		Email:   current.Email,
+		Website: current.Website,
+	}
+
+	if req.Name != nil {
+		u.Name = *req.Name
+	}
+
+	if req.Email != nil {
+		u.Email = *req.Email
+	}
+
+	if req.Website != nil {
+		u.Website = *req.Website
+	}
+
+	if err := h.AdminService.UpdateUser(ctx, authUser, u, req.EmailVerified); err != nil {
+		log.Printf("failed to update user: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u,
+	})
+}
+
+// ResetUserPassword handler sets a new password for any user
+func (h *Handler) ResetUserPassword(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	var req resetPasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.AdminService.ResetPassword(ctx, authUser, uid, req.Password); err != nil {
+		log.Printf("failed to reset password for uid: %v: %v\n", uid, err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// SignoutUser handler revokes all refresh tokens of any user
+func (h *Handler) SignoutUser(c *gin.Context) {
+	h.adminUserAction(c, h.AdminService.ForceSignout)
+}
+
+// SuspendUser handler prevents any user from signing in, optionally until a given time
+func (h *Handler) SuspendUser(c *gin.Context) {
+	var req suspendUserReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	h.adminUserAction(c, func(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+		return h.AdminService.SuspendUser(ctx, actor, uid, req.Reason, req.Until)
+	})
+}
+
+// DisableUser handler prevents any user from signing in
+func (h *Handler) DisableUser(c *gin.Context) {
+	var req disableUserReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	h.adminUserAction(c, func(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+		return h.AdminService.DisableUser(ctx, actor, uid, req.Reason)
+	})
+}
+
+// EnableUser handler lets a suspended or disabled user sign in again
+func (h *Handler) EnableUser(c *gin.Context) {
+	h.adminUserAction(c, h.AdminService.EnableUser)
+}
+
+// DeleteUser handler permanently deletes any user
+func (h *Handler) DeleteUser(c *gin.Context) {
+	h.adminUserAction(c, h.AdminService.DeleteUser)
+}
+
+// ImpersonateUser handler mints a short lived ID token letting the
+// signed in admin act as any user. No refresh token is issued
+func (h *Handler) ImpersonateUser(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	u, err := h.AdminService.GetUser(ctx, uid)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	token, err := h.TokenService.NewImpersonationToken(ctx, authUser, u)
+	if err != nil {
+		log.Printf("Failed to impersonate uid: %v: %v\n", uid, err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// adminUserAction runs an AdminService method which only
+// needs the acting admin and the uid from the path
+func (h *Handler) adminUserAction(c *gin.Context, action func(ctx context.Context, actor *model.User, uid uuid.UUID) error) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := action(ctx, authUser, uid); err != nil {
+		log.Printf("admin action on uid: %v failed: %v\n", uid, err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
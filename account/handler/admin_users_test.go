@@ -0,0 +1,528 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearchUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		page := &model.UserPage{
+			Users:      []*model.User{{UID: uid, Email: "bob@bob.com"}},
+			NextCursor: uid.String(),
+		}
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("SearchUsers", mock.Anything, "bob", "", 1).Return(page, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin/users?q=bob&limit=1", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(page)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockAdminService.AssertExpectations(t)
+	})
+
+	t.Run("Limit out of range", func(t *testing.T) {
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin/users?limit=1000", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "SearchUsers")
+	})
+}
+
+func TestGetUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Not found", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockErr := apperrors.NewNotFound("uid", uid.String())
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("GetUser", mock.Anything, uid).Return(nil, mockErr)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/admin/users/%s", uid), nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: adminUID}
+
+	t.Run("Marks email verified", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		verified := true
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("GetUser", mock.Anything, uid).Return(&model.User{UID: uid, Name: "Bob", Email: "bob@bob.com", Website: "https://bob.com"}, nil)
+		mockAdminService.On("UpdateUser", mock.Anything, ctxUser, &model.User{UID: uid, Name: "Bobby", Email: "bob@bob.com", Website: "https://bob.com"}, &verified).Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":           "Bobby",
+			"email_verified": true,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+
+	t.Run("Only email verified sent", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		current := &model.User{UID: uid, Name: "Bob", Email: "bob@bob.com", Website: "https://bob.com"}
+		verified := true
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("GetUser", mock.Anything, uid).Return(current, nil)
+		mockAdminService.On("UpdateUser", mock.Anything, ctxUser, &model.User{UID: uid, Name: "Bob", Email: "bob@bob.com", Website: "https://bob.com"}, &verified).Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email_verified": true,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+
+	t.Run("Empty email", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("GetUser", mock.Anything, uid).Return(nil, apperrors.NewNotFound("uid", uid.String()))
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name": "Bob",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockAdminService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Status refused", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":   "Bob",
+			"status": model.UserStatusDisabled,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResetUserPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: adminUID}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("ResetPassword", mock.Anything, ctxUser, uid, "anewpassword").Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"password": "anewpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/password", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+
+	t.Run("Impersonation token", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{UID: uid, ActorUID: &adminUID})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"password": "anewpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/password", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockAdminService.AssertNotCalled(t, "ResetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDisableUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: adminUID}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("DisableUser", mock.Anything, ctxUser, uid, "spam").Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"reason": "spam",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/disable", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+
+	t.Run("Reason required", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockAdminService := new(mocks.MockAdminService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/disable", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "DisableUser")
+	})
+}
+
+func TestSuspendUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: adminUID}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		until := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("SuspendUser", mock.Anything, ctxUser, uid, "abuse", mock.MatchedBy(func(u *time.Time) bool {
+			return u != nil && u.Equal(until)
+		})).Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"reason": "abuse",
+			"until":  until,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/suspend", uid), bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertExpectations(t)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: adminUID}
+
+	t.Run("Error from AdminService", func(t *testing.T) {
+		mockErr := apperrors.NewBadRequest("admins cannot delete their own account")
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("DeleteUser", mock.Anything, ctxUser, adminUID).Return(mockErr)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s", adminUID), nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, mockErr.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockAdminService.AssertExpectations(t)
+	})
+}
+
+func TestImpersonateUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: adminUID, Roles: []string{model.RoleAdmin}}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, Email: "bob@bob.com"}
+		token := &model.ImpersonationToken{
+			IDToken:   model.IDToken{SS: "impersonationIDToken"},
+			ExpiresAt: time.Now().Add(5 * time.Minute).Truncate(time.Second).UTC(),
+		}
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("GetUser", mock.Anything, uid).Return(u, nil)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("NewImpersonationToken", mock.Anything, ctxUser, u).Return(token, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/impersonate", uid), nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(token)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		// impersonation tokens are never refreshed
+		assert.NotContains(t, rr.Body.String(), "refreshToken")
+		mockAdminService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, Roles: []string{model.RoleSupport}}
+		mockErr := apperrors.NewForbidden("users with roles cannot be impersonated")
+
+		mockAdminService := new(mocks.MockAdminService)
+		mockAdminService.On("GetUser", mock.Anything, uid).Return(u, nil)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("NewImpersonationToken", mock.Anything, ctxUser, u).Return(nil, mockErr)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AdminService: mockAdminService,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/impersonate", uid), nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type myAuditEventsReq struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// all filters are optional, since and until are RFC 3339 times
+type listAuditEventsReq struct {
+	UID      string    `form:"uid" binding:"omitempty,uuid"`
+	ActorUID string    `form:"actor" binding:"omitempty,uuid"`
+	Event    string    `form:"event"`
+	Outcome  string    `form:"outcome" binding:"omitempty,oneof=success failure"`
+	IP       string    `form:"ip"`
+	Since    time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until    time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor   string    `form:"cursor"`
+	Limit    int       `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// MyAuditEvents handler returns the security history of the signed in user
+func (h *Handler) MyAuditEvents(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req myAuditEventsReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	uid := authUser.UID
+
+	h.listAuditEvents(c, &model.AuditFilter{
+		UID:   &uid,
+		Limit: req.Limit,
+	}, req.Cursor)
+}
+
+// ListAuditEvents handler queries the security history of every user
+func (h *Handler) ListAuditEvents(c *gin.Context) {
+	var req listAuditEventsReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	f := &model.AuditFilter{
+		Event:   req.Event,
+		Outcome: req.Outcome,
+		IP:      req.IP,
+		Limit:   req.Limit,
+	}
+
+	// already validated as uuids when binding
+	if req.UID != "" {
+		uid := uuid.MustParse(req.UID)
+		f.UID = &uid
+	}
+
+	if req.ActorUID != "" {
+		actor := uuid.MustParse(req.ActorUID)
+		f.ActorUID = &actor
+	}
+
+	if !req.Since.IsZero() {
+		f.Since = &req.Since
+	}
+
+	if !req.Until.IsZero() {
+		f.Until = &req.Until
+	}
+
+	h.listAuditEvents(c, f, req.Cursor)
+}
+
+func (h *Handler) listAuditEvents(c *gin.Context, f *model.AuditFilter, cursor string) {
+	ctx := c.Request.Context()
+
+	page, err := h.AuditService.ListEvents(ctx, f, cursor)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMyAuditEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Only lists the user's own events", func(t *testing.T) {
+		page := &model.AuditPage{
+			Events: []*model.AuditEvent{{ID: 1, UID: &uid, Event: model.AuditEventSignin, Outcome: model.AuditOutcomeSuccess}},
+		}
+
+		mockAuditService := new(mocks.MockAuditService)
+		mockAuditService.On("ListEvents", mock.Anything, mock.MatchedBy(func(f *model.AuditFilter) bool {
+			return *f.UID == uid && f.Limit == 10
+		}), "42").Return(page, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AuditService: mockAuditService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me/audit?cursor=42&limit=10", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(page)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockAuditService.AssertExpectations(t)
+	})
+}
+
+func TestListAuditEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Applies filters", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		since := time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)
+		page := &model.AuditPage{
+			Events: []*model.AuditEvent{},
+		}
+
+		mockAuditService := new(mocks.MockAuditService)
+		mockAuditService.On("ListEvents", mock.Anything, mock.MatchedBy(func(f *model.AuditFilter) bool {
+			return *f.UID == uid &&
+				f.ActorUID == nil &&
+				f.Event == model.AuditEventSignin &&
+				f.Outcome == model.AuditOutcomeFailure &&
+				f.IP == "10.0.0.1" &&
+				f.Since.Equal(since) &&
+				f.Until == nil
+		}), "").Return(page, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:            router,
+			AuditService: mockAuditService,
+		})
+
+		url := fmt.Sprintf("/admin/audit?uid=%s&event=signin&outcome=failure&ip=10.0.0.1&since=2021-03-02T00:00:00Z", uid)
+		request, _ := http.NewRequest(http.MethodGet, url, nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid filter", func(t *testing.T) {
+		mockAuditService := new(mocks.MockAuditService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:            router,
+			AuditService: mockAuditService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin/audit?uid=notauuid", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAuditService.AssertNotCalled(t, "ListEvents")
+	})
+}
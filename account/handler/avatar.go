@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// avatarVersion is bumped whenever the way default avatars are drawn changes,
+// so clients holding the old ones in cache fetch them again
+const avatarVersion = "v1"
+
+// the query string of a default avatar
+type avatarReq struct {
+	Size int `form:"size"`
+}
+
+// Avatar handler serves the default avatar of a user. It is public, as are
+// the links to it handed out in place of a profile image, and since it only
+// depends on the uid it may be cached for a long time
+func (h *Handler) Avatar(c *gin.Context) {
+	uid, ok := uuidParam(c, "uid")
+	if !ok {
+		return
+	}
+
+	var req avatarReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %+v\n", err)
+		e := apperrors.NewBadRequest("size must be a number")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if req.Size == 0 {
+		req.Size = model.ProfileImageSizes[len(model.ProfileImageSizes)-1]
+	}
+
+	ctx := c.Request.Context()
+
+	data, err := h.UserService.DefaultAvatar(ctx, uid, req.Size)
+	if err != nil {
+		log.Printf("Failed to render default avatar for user: %v: %v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	etag := fmt.Sprintf("\"%s-%d-%s\"", uid, req.Size, avatarVersion)
+	c.Header("Cache-Control", "public, max-age=604800")
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", data)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAvatar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	avatar := []byte("\x89PNG avatar")
+
+	newRouter := func(mockUserService *mocks.MockUserService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("DefaultAvatar", mock.Anything, uid, 128).Return(avatar, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/avatars/"+uid.String()+"?size=128", nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=604800", rr.Header().Get("Cache-Control"))
+		assert.Equal(t, "\""+uid.String()+"-128-v1\"", rr.Header().Get("ETag"))
+		assert.Equal(t, avatar, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Defaults to the largest size", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("DefaultAvatar", mock.Anything, uid, 512).Return(avatar, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/avatars/"+uid.String(), nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("DefaultAvatar", mock.Anything, uid, 64).Return(avatar, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/avatars/"+uid.String()+"?size=64", nil)
+		request.Header.Set("If-None-Match", "\""+uid.String()+"-64-v1\"")
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.Bytes())
+	})
+
+	t.Run("Invalid uid", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/avatars/not-a-uid", nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "DefaultAvatar", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unsupported size", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("DefaultAvatar", mock.Anything, uid, 100).Return(nil, apperrors.NewBadRequest("size must be one of [64 128 512]"))
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/avatars/"+uid.String()+"?size=100", nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Empty(t, rr.Header().Get("ETag"))
+	})
+}
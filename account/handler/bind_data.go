@@ -42,7 +42,7 @@ func bindData(c *gin.Context, req interface{}) bool {
 			for _, err := range errs {
 				invalidArgs = append(invalidArgs, invalidArgument{
 					err.Field(),
-					err.Value().(string),
+					fmt.Sprintf("%v", err.Value()),
 					err.Tag(),
 					err.Param(),
 				})
@@ -69,3 +69,17 @@ func bindData(c *gin.Context, req interface{}) bool {
 
 	return true
 }
+
+// bindQuery binds query parameters, responding with a bad request when they are invalid
+func bindQuery(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindQuery(req); err != nil {
+		log.Printf("Error binding query: %+v\n", err)
+		e := apperrors.NewBadRequest("Invalid query parameters")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return false
+	}
+
+	return true
+}
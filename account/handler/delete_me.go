@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// deleting an account requires the user to re-enter their password
+type deleteMeReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteMe handler deletes the signed in user's account
+func (h *Handler) DeleteMe(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req deleteMeReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.UserService.Delete(ctx, authUser.UID, req.Password); err != nil {
+		log.Printf("Failed to delete account for uid: %v: %v\n", authUser.UID, err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "account deleted",
+	})
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeleteMe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("Delete", mock.Anything, uid, "mypassword").Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"password": "mypassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/me", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Password required", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/me", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "Delete")
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockErr := apperrors.NewAuthorization("Invalid password")
+
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("Delete", mock.Anything, uid, "wrongpassword").Return(mockErr)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"password": "wrongpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/me", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Impersonation token", func(t *testing.T) {
+		actorUID, _ := uuid.NewRandom()
+		mockUserService := new(mocks.MockUserService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{UID: uid, ActorUID: &actorUID})
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"password": "mypassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/me", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockUserService.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -86,13 +86,13 @@ func TestDetails(t *testing.T) {
 			On("UpdateDetails", updateArgs...).
 			Run(func(args mock.Arguments) {
 				userArg := args.Get(1).(*model.User) // arg 0 is context, arg 1 is *User
-				userArg.ImageURL = dbImageURL
+				userArg.ImageURLs = model.ImageURLs{512: dbImageURL}
 			}).
 			Return(nil)
 
 		router.ServeHTTP(rr, request)
 
-		userToUpdate.ImageURL = dbImageURL
+		userToUpdate.ImageURLs = model.ImageURLs{512: dbImageURL}
 		respBody, _ := json.Marshal(gin.H{
 			"user": userToUpdate,
 		})
@@ -146,4 +146,31 @@ func TestDetails(t *testing.T) {
 		assert.Equal(t, respBody, rr.Body.Bytes())
 		mockUserService.AssertCalled(t, "UpdateDetails", updateArgs...)
 	})
+
+	t.Run("Impersonation token", func(t *testing.T) {
+		actorUID, _ := uuid.NewRandom()
+		mockUserService := new(mocks.MockUserService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{UID: uid, ActorUID: &actorUID})
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "jacob@jacob.com",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockUserService.AssertNotCalled(t, "UpdateDetails", mock.Anything, mock.Anything)
+	})
 }
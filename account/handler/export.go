@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type requestExportReq struct {
+	Format string `json:"format" binding:"required,oneof=json zip"`
+}
+
+// the signed query string of an export download link
+type downloadExportReq struct {
+	Expires   int64  `form:"expires" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+// RequestExport handler starts generating an export of the signed in user's data
+func (h *Handler) RequestExport(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req requestExportReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	e, err := h.ExportService.RequestExport(ctx, authUser.UID, req.Format)
+	if err != nil {
+		log.Printf("Failed to request export for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"export": e,
+	})
+}
+
+// GetExport handler returns the status of one of the signed in user's exports
+func (h *Handler) GetExport(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	e, err := h.ExportService.GetExport(ctx, authUser.UID, id)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"export": e,
+	})
+}
+
+// DownloadExport handler serves a ready export to anyone holding its signed link
+func (h *Handler) DownloadExport(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req downloadExportReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %+v\n", err)
+		e := apperrors.NewBadRequest("Invalid download link")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	e, archive, err := h.ExportService.Download(ctx, id, req.Expires, req.Signature)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	contentType := "application/json"
+	if e.Format == model.ExportFormatZIP {
+		contentType = "application/zip"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"export-%s.%s\"", e.ID, e.Format))
+	c.Data(http.StatusOK, contentType, archive)
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequestExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Accepted", func(t *testing.T) {
+		e := &model.DataExport{
+			ID:     uuid.New(),
+			UID:    uid,
+			Format: model.ExportFormatZIP,
+			Status: model.ExportStatusPending,
+		}
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("RequestExport", mock.Anything, uid, model.ExportFormatZIP).Return(e, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"format": "zip",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/me/export", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"export": e,
+		})
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockExportService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		mockExportService := new(mocks.MockExportService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"format": "csv",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/me/export", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockExportService.AssertNotCalled(t, "RequestExport")
+	})
+}
+
+func TestGetExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		e := &model.DataExport{
+			ID:          uuid.New(),
+			UID:         uid,
+			Status:      model.ExportStatusReady,
+			DownloadURL: "/exports/id/download?expires=1&signature=abc",
+		}
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("GetExport", mock.Anything, uid, e.ID).Return(e, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/me/export/%s", e.ID), nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"export": e,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockExportService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid id", func(t *testing.T) {
+		mockExportService := new(mocks.MockExportService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me/export/notauuid", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockExportService.AssertNotCalled(t, "GetExport")
+	})
+}
+
+func TestDownloadExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		e := &model.DataExport{
+			ID:     id,
+			Format: model.ExportFormatZIP,
+			Status: model.ExportStatusReady,
+		}
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("Download", mock.Anything, id, int64(1700000000), "abc").Return(e, []byte("archive"), nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/exports/%s/download?expires=1700000000&signature=abc", id), nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+		assert.Equal(t, fmt.Sprintf("attachment; filename=\"export-%s.zip\"", id), rr.Header().Get("Content-Disposition"))
+		assert.Equal(t, "archive", rr.Body.String())
+		mockExportService.AssertExpectations(t)
+	})
+
+	t.Run("Missing signature", func(t *testing.T) {
+		mockExportService := new(mocks.MockExportService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/exports/%s/download?expires=1700000000", id), nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockExportService.AssertNotCalled(t, "Download")
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		mockErr := apperrors.NewAuthorization("Invalid download link")
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("Download", mock.Anything, id, int64(1700000000), "bad").Return(nil, nil, mockErr)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:             router,
+			ExportService: mockExportService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/exports/%s/download?expires=1700000000&signature=bad", id), nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockExportService.AssertExpectations(t)
+	})
+}
@@ -11,20 +11,36 @@ import (
 
 // Handler struct holds required services for handler to function
 type Handler struct {
-	UserService  model.UserService
-	TokenService model.TokenService
-	MaxBodyBytes int64
+	UserService         model.UserService
+	TokenService        model.TokenService
+	AdminService        model.AdminService
+	ExportService       model.ExportService
+	AuditService        model.AuditService
+	WebhookService      model.WebhookService
+	OrganizationService model.OrganizationService
+	InvitationService   model.InvitationService
+	RealmService        model.RealmService
+	ImageCollector      model.ImageCollector
+	MaxBodyBytes        int64
 }
 
 // Config will hold services that will eventually be injected
 // into this handler layer on handler initialization
 type Config struct {
-	R               *gin.Engine
-	UserService     model.UserService
-	TokenService    model.TokenService
-	BaseURL         string
-	TimeoutDuration time.Duration
-	MaxBodyBytes    int64
+	R                   *gin.Engine
+	UserService         model.UserService
+	TokenService        model.TokenService
+	AdminService        model.AdminService
+	ExportService       model.ExportService
+	AuditService        model.AuditService
+	WebhookService      model.WebhookService
+	OrganizationService model.OrganizationService
+	InvitationService   model.InvitationService
+	RealmService        model.RealmService
+	ImageCollector      model.ImageCollector
+	BaseURL             string
+	TimeoutDuration     time.Duration
+	MaxBodyBytes        int64
 }
 
 // NewHandler initializes the handler with required injected services along
@@ -32,30 +48,157 @@ type Config struct {
 func NewHandler(c *Config) {
 	// create a handler (which will later have injected services)
 	h := &Handler{
-		UserService:  c.UserService,
-		TokenService: c.TokenService,
-		MaxBodyBytes: c.MaxBodyBytes,
+		UserService:         c.UserService,
+		TokenService:        c.TokenService,
+		AdminService:        c.AdminService,
+		ExportService:       c.ExportService,
+		AuditService:        c.AuditService,
+		WebhookService:      c.WebhookService,
+		OrganizationService: c.OrganizationService,
+		InvitationService:   c.InvitationService,
+		RealmService:        c.RealmService,
+		ImageCollector:      c.ImageCollector,
+		MaxBodyBytes:        c.MaxBodyBytes,
 	} // currently has no properties
 
 	// Create an account group
 	g := c.R.Group(c.BaseURL)
 
+	// impersonation tokens are for looking at an account, so they can't be
+	// used to change, export or sign out of it, nor to reach admin routes
+	noImpersonation := middleware.DenyImpersonation()
+
 	if gin.Mode() != gin.TestMode {
+		// record where requests come from for the audit log
+		g.Use(middleware.ClientInfo())
 		g.Use(middleware.Timeout(c.TimeoutDuration, apperrors.NewServiceUnavailable()))
-		g.GET("/me", middleware.AuthUser(h.TokenService), h.Me)
-		g.POST("/signout", middleware.AuthUser(h.TokenService), h.Signout)
-		g.PUT("/details", middleware.AuthUser(h.TokenService), h.Details)
-		g.POST("/image", middleware.AuthUser(h.TokenService), h.Image)
-		g.DELETE("/image", middleware.AuthUser(h.TokenService), h.DeleteImage)
+		g.GET("/me", middleware.AuthUser(h.TokenService, h.UserService), h.Me)
+		g.DELETE("/me", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.DeleteMe)
+		g.POST("/me/export", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.RequestExport)
+		g.GET("/me/export/:id", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.GetExport)
+		g.GET("/me/audit", middleware.AuthUser(h.TokenService, h.UserService), h.MyAuditEvents)
+		g.POST("/signout", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.Signout)
+		g.PUT("/details", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.Details)
+		g.POST("/image", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.Image)
+		g.DELETE("/image", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.DeleteImage)
+		g.POST("/image/upload-url", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.ImageUploadURL)
+		g.POST("/image/commit", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.CommitImage)
+		g.GET("/image/history", middleware.AuthUser(h.TokenService, h.UserService), h.ImageHistory)
+		g.POST("/image/revert", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation, h.RevertImage)
+
+		// organization roles are checked by the service, as members can belong to many
+		og := g.Group("/orgs", middleware.AuthUser(h.TokenService, h.UserService))
+		og.POST("", noImpersonation, h.CreateOrg)
+		og.GET("", h.ListOrgs)
+		og.GET("/:id", h.GetOrg)
+		og.PUT("/:id", noImpersonation, h.UpdateOrg)
+		og.DELETE("/:id", noImpersonation, h.DeleteOrg)
+		og.GET("/:id/members", h.ListOrgMembers)
+		og.POST("/:id/members", noImpersonation, h.AddOrgMember)
+		og.PUT("/:id/members/:uid", noImpersonation, h.UpdateOrgMember)
+		og.DELETE("/:id/members/:uid", noImpersonation, h.RemoveOrgMember)
+		og.POST("/:id/invitations", noImpersonation, h.Invite)
+
+		ig := g.Group("/invitations", middleware.AuthUser(h.TokenService, h.UserService))
+		ig.GET("/sent", h.SentInvitations)
+		ig.GET("/received", h.ReceivedInvitations)
+		ig.POST("/accept", noImpersonation, h.AcceptInvitation)
+		ig.DELETE("/:id", noImpersonation, h.RevokeInvitation)
+
+		// admin routes check the roles and permissions carried in the ID token
+		ag := g.Group("/admin", middleware.AuthUser(h.TokenService, h.UserService), noImpersonation)
+		ag.GET("/roles", middleware.RequirePermission(model.PermissionRolesRead), h.ListRoles)
+		ag.POST("/users/:uid/roles", middleware.RequirePermission(model.PermissionRolesWrite), h.AssignRole)
+		ag.DELETE("/users/:uid/roles/:role", middleware.RequirePermission(model.PermissionRolesWrite), h.RevokeRole)
+		ag.GET("/users", middleware.RequirePermission(model.PermissionUsersRead), h.SearchUsers)
+		ag.GET("/users/:uid", middleware.RequirePermission(model.PermissionUsersRead), h.GetUser)
+		ag.PUT("/users/:uid", middleware.RequirePermission(model.PermissionUsersWrite), h.UpdateUser)
+		ag.POST("/users/:uid/password", middleware.RequirePermission(model.PermissionUsersWrite), h.ResetUserPassword)
+		ag.POST("/users/:uid/signout", middleware.RequirePermission(model.PermissionUsersWrite), h.SignoutUser)
+		ag.POST("/users/:uid/suspend", middleware.RequirePermission(model.PermissionUsersWrite), h.SuspendUser)
+		ag.POST("/users/:uid/disable", middleware.RequirePermission(model.PermissionUsersWrite), h.DisableUser)
+		ag.POST("/users/:uid/enable", middleware.RequirePermission(model.PermissionUsersWrite), h.EnableUser)
+		ag.DELETE("/users/:uid", middleware.RequirePermission(model.PermissionUsersWrite), h.DeleteUser)
+		ag.POST("/users/:uid/impersonate", middleware.RequirePermission(model.PermissionUsersImpersonate), h.ImpersonateUser)
+		ag.GET("/audit", middleware.RequirePermission(model.PermissionAuditRead), h.ListAuditEvents)
+		ag.GET("/webhooks", middleware.RequirePermission(model.PermissionWebhooks), h.ListWebhooks)
+		ag.POST("/webhooks", middleware.RequirePermission(model.PermissionWebhooks), h.CreateWebhook)
+		ag.PUT("/webhooks/:id", middleware.RequirePermission(model.PermissionWebhooks), h.UpdateWebhook)
+		ag.DELETE("/webhooks/:id", middleware.RequirePermission(model.PermissionWebhooks), h.DeleteWebhook)
+		ag.GET("/webhooks/:id/deliveries", middleware.RequirePermission(model.PermissionWebhooks), h.ListWebhookDeliveries)
+		ag.POST("/webhooks/:id/deliveries/:delivery/replay", middleware.RequirePermission(model.PermissionWebhooks), h.ReplayWebhookDelivery)
+		ag.GET("/images/orphans", middleware.RequirePermission(model.PermissionImages), h.OrphanedImages)
+		ag.GET("/images/reviews", middleware.RequirePermission(model.PermissionImages), h.ListImageReviews)
+		ag.POST("/images/reviews/:id/approve", middleware.RequirePermission(model.PermissionImages), h.ApproveImage)
+		ag.POST("/images/reviews/:id/reject", middleware.RequirePermission(model.PermissionImages), h.RejectImage)
 	} else {
 		g.GET("/me", h.Me)
-		g.POST("/signout", h.Signout)
-		g.PUT("/details", h.Details)
-		g.POST("/image", h.Image)
-		g.DELETE("/image", h.DeleteImage)
+		g.DELETE("/me", noImpersonation, h.DeleteMe)
+		g.POST("/me/export", noImpersonation, h.RequestExport)
+		g.GET("/me/export/:id", noImpersonation, h.GetExport)
+		g.GET("/me/audit", h.MyAuditEvents)
+		g.POST("/signout", noImpersonation, h.Signout)
+		g.PUT("/details", noImpersonation, h.Details)
+		g.POST("/image", noImpersonation, h.Image)
+		g.DELETE("/image", noImpersonation, h.DeleteImage)
+		g.POST("/image/upload-url", noImpersonation, h.ImageUploadURL)
+		g.POST("/image/commit", noImpersonation, h.CommitImage)
+		g.GET("/image/history", h.ImageHistory)
+		g.POST("/image/revert", noImpersonation, h.RevertImage)
+
+		og := g.Group("/orgs")
+		og.POST("", noImpersonation, h.CreateOrg)
+		og.GET("", h.ListOrgs)
+		og.GET("/:id", h.GetOrg)
+		og.PUT("/:id", noImpersonation, h.UpdateOrg)
+		og.DELETE("/:id", noImpersonation, h.DeleteOrg)
+		og.GET("/:id/members", h.ListOrgMembers)
+		og.POST("/:id/members", noImpersonation, h.AddOrgMember)
+		og.PUT("/:id/members/:uid", noImpersonation, h.UpdateOrgMember)
+		og.DELETE("/:id/members/:uid", noImpersonation, h.RemoveOrgMember)
+		og.POST("/:id/invitations", noImpersonation, h.Invite)
+
+		ig := g.Group("/invitations")
+		ig.GET("/sent", h.SentInvitations)
+		ig.GET("/received", h.ReceivedInvitations)
+		ig.POST("/accept", noImpersonation, h.AcceptInvitation)
+		ig.DELETE("/:id", noImpersonation, h.RevokeInvitation)
+
+		ag := g.Group("/admin", noImpersonation)
+		ag.GET("/roles", h.ListRoles)
+		ag.POST("/users/:uid/roles", h.AssignRole)
+		ag.DELETE("/users/:uid/roles/:role", h.RevokeRole)
+		ag.GET("/users", h.SearchUsers)
+		ag.GET("/users/:uid", h.GetUser)
+		ag.PUT("/users/:uid", h.UpdateUser)
+		ag.POST("/users/:uid/password", h.ResetUserPassword)
+		ag.POST("/users/:uid/signout", h.SignoutUser)
+		ag.POST("/users/:uid/suspend", h.SuspendUser)
+		ag.POST("/users/:uid/disable", h.DisableUser)
+		ag.POST("/users/:uid/enable", h.EnableUser)
+		ag.DELETE("/users/:uid", h.DeleteUser)
+		ag.POST("/users/:uid/impersonate", h.ImpersonateUser)
+		ag.GET("/audit", h.ListAuditEvents)
+		ag.GET("/webhooks", h.ListWebhooks)
+		ag.POST("/webhooks", h.CreateWebhook)
+		ag.PUT("/webhooks/:id", h.UpdateWebhook)
+		ag.DELETE("/webhooks/:id", h.DeleteWebhook)
+		ag.GET("/webhooks/:id/deliveries", h.ListWebhookDeliveries)
+		ag.POST("/webhooks/:id/deliveries/:delivery/replay", h.ReplayWebhookDelivery)
+		ag.GET("/images/orphans", h.OrphanedImages)
+		ag.GET("/images/reviews", h.ListImageReviews)
+		ag.POST("/images/reviews/:id/approve", h.ApproveImage)
+		ag.POST("/images/reviews/:id/reject", h.RejectImage)
 	}
 
 	g.POST("/signup", h.Signup)
 	g.POST("/signin", h.Signin)
 	g.POST("/tokens", h.Tokens)
+	g.GET("/realm", h.Realm)
+	// export downloads are authorized by the signature on the link
+	g.GET("/exports/:id/download", h.DownloadExport)
+	// default avatars are linked to in place of a missing profile image
+	g.GET("/avatars/:uid", h.Avatar)
+	// public images are served in place of the storage they are kept in
+	g.GET("/images/:obj", h.ProxyImage)
 }
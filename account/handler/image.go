@@ -3,6 +3,7 @@ package handler
 import (
 	"fmt"
 	"log"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,8 @@ import (
 	"github.com/ndenisj/go_mem/account/model/apperrors"
 )
 
+const imageTypeMessage = "imageFile must be a JPEG, PNG, GIF or WebP image"
+
 // Image handler
 func (h *Handler) Image(c *gin.Context) {
 	authUser := c.MustGet("user").(*model.User)
@@ -45,7 +48,29 @@ func (h *Handler) Image(c *gin.Context) {
 	// Validate the image mime type is allowed
 	if valid := isAllowedImageType(mimeType); !valid {
 		log.Println("Image is not an allowed mime-type")
-		e := apperrors.NewBadRequest("imageFile must be 'image/jpeg' or 'image/png'")
+		e := apperrors.NewBadRequest(imageTypeMessage)
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	// the declared type is only a label, so check the file's
+	// contents are one of the allowed formats too. The service
+	// decodes the whole image, rejecting corrupt files
+	sniffedType, err := sniffFormFile(imageFileHeader)
+	if err != nil {
+		log.Printf("unable to read imageFile: %v\n", err)
+		e := apperrors.NewBadRequest("Unable to read imageFile")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if valid := isAllowedImageType(sniffedType); !valid {
+		log.Printf("Image content is not an allowed mime-type: %v\n", sniffedType)
+		e := apperrors.NewBadRequest(imageTypeMessage)
 		c.JSON(e.Status(), gin.H{
 			"error": e,
 		})
@@ -62,8 +87,16 @@ func (h *Handler) Image(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"imageUrl": updatedUser.ImageURL,
-		"message":  "success",
-	})
+	imageUpdated(c, updatedUser)
+}
+
+// sniffFormFile detects the mime type of an uploaded file from its contents
+func sniffFormFile(fileHeader *multipart.FileHeader) (string, error) {
+	f, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return sniffImageType(f)
 }
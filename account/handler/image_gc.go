@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// OrphanedImages handler reports the images in storage which no user refers
+// to, and which of them the next collection would delete, without deleting any
+func (h *Handler) OrphanedImages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	report, err := h.ImageCollector.Collect(ctx, true)
+	if err != nil {
+		log.Printf("Failed to report orphaned images: %v\n", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrphanedImages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Reports without deleting", func(t *testing.T) {
+		report := &model.ImageGCReport{
+			Scanned:    2,
+			Referenced: 1,
+			Orphans:    []*model.StoredImage{{ObjectName: "orphan-64", Size: 10}},
+			Deleted:    []string{"orphan-64"},
+			DryRun:     true,
+		}
+
+		mockImageCollector := new(mocks.MockImageCollector)
+		mockImageCollector.On("Collect", mock.Anything, true).Return(report, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			ImageCollector: mockImageCollector,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin/images/orphans", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"report": report,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockImageCollector.AssertExpectations(t)
+		mockImageCollector.AssertNotCalled(t, "Collect", mock.Anything, false)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockImageCollector := new(mocks.MockImageCollector)
+		mockImageCollector.On("Collect", mock.Anything, true).Return(nil, apperrors.NewInternal())
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			ImageCollector: mockImageCollector,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin/images/orphans", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
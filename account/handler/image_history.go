@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type revertImageReq struct {
+	Version string `json:"version" binding:"required,uuid"`
+}
+
+// ImageHistory handler lists the previous profile images of the signed in user
+func (h *Handler) ImageHistory(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+
+	history, err := h.UserService.ImageHistory(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to list image history for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+	})
+}
+
+// RevertImage handler makes one of the signed in user's
+// previous images their profile image again
+func (h *Handler) RevertImage(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req revertImageReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	// already validated as a uuid when binding
+	versionID := uuid.MustParse(req.Version)
+
+	ctx := c.Request.Context()
+
+	updatedUser, err := h.UserService.RevertProfileImage(ctx, authUser.UID, versionID)
+	if err != nil {
+		log.Printf("Failed to revert image for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imageUrls": updatedUser.ImageURLs,
+		"message":   "success",
+	})
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImageHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	setup := func() (*gin.Engine, *mocks.MockUserService) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router, mockUserService
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		history := model.ImageHistory{{
+			ID:         uuid.New(),
+			ImageURLs:  model.ImageURLs{64: "http://imageurl.com/older-64"},
+			ReplacedAt: time.Now().UTC(),
+		}}
+		mockUserService.On("ImageHistory", mock.Anything, uid).Return(history, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/image/history", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"history": history,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		router, mockUserService := setup()
+		mockUserService.On("ImageHistory", mock.Anything, uid).Return(nil, apperrors.NewInternal())
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/image/history", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestRevertImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+	versionID := uuid.New()
+
+	setup := func() (*gin.Engine, *mocks.MockUserService) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router, mockUserService
+	}
+
+	revert := func(router *gin.Engine, body gin.H) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/revert", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		imageURLs := model.ImageURLs{64: "http://imageurl.com/older-64"}
+		mockUserService.On("RevertProfileImage", mock.Anything, uid, versionID).Return(&model.User{UID: uid, ImageURLs: imageURLs}, nil)
+
+		rr := revert(router, gin.H{"version": versionID.String()})
+
+		respBody, _ := json.Marshal(gin.H{
+			"imageUrls": imageURLs,
+			"message":   "success",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid version", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		rr := revert(router, gin.H{"version": "notauuid"})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "RevertProfileImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		router, mockUserService := setup()
+		mockUserService.On("RevertProfileImage", mock.Anything, uid, versionID).Return(nil, apperrors.NewNotFound("image version", versionID.String()))
+
+		rr := revert(router, gin.H{"version": versionID.String()})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// imageCacheControl lets browsers and CDNs keep images for a year. A new
+// image is always stored under a new object name, so objects never change
+const imageCacheControl = "public, max-age=31536000, immutable"
+
+// ProxyImage handler streams a public image from storage, so that clients
+// link to this service, or a CDN in front of it, rather than to the bucket
+func (h *Handler) ProxyImage(c *gin.Context) {
+	objName := c.Param("obj")
+	ctx := c.Request.Context()
+
+	rc, err := h.UserService.OpenImage(ctx, objName)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+	defer rc.Close()
+
+	// only users' images are opened, but objects are still
+	// checked so that nothing but an image is ever served
+	br := bufio.NewReaderSize(rc, 512)
+	header, err := br.Peek(512)
+	if err != nil && len(header) == 0 {
+		log.Printf("Failed to read image object: %v: %v\n", objName, err)
+		e := apperrors.NewInternal()
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	contentType := http.DetectContentType(header)
+	if !isAllowedImageType(contentType) {
+		log.Printf("Image object is not an allowed mime-type: %v: %v\n", objName, contentType)
+		e := apperrors.NewNotFound("image", objName)
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	// the object name is its ETag, as its contents never change. It is only
+	// matched once the object is found to be an image, so that images which
+	// have been deleted, or are no longer a user's, aren't kept in caches
+	etag := fmt.Sprintf("\"%s\"", objName)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Header("Cache-Control", imageCacheControl)
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, br, map[string]string{
+		"Cache-Control":          imageCacheControl,
+		"ETag":                   etag,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag,
+// comparing weakly as the header allows
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProxyImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	image := "\x89PNG\r\n\x1a\n image"
+
+	newRouter := func(mockUserService *mocks.MockUserService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "image-64").Return(io.NopCloser(strings.NewReader(image)), nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/image-64", nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+		assert.Equal(t, "\"image-64\"", rr.Header().Get("ETag"))
+		assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, image, rr.Body.String())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "image-64").Return(io.NopCloser(strings.NewReader(image)), nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/image-64", nil)
+		request.Header.Set("If-None-Match", "\"other\", W/\"image-64\"")
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Equal(t, "\"image-64\"", rr.Header().Get("ETag"))
+		assert.Empty(t, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Not modified but deleted", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "image-64").Return(nil, apperrors.NewNotFound("image", "image-64"))
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/image-64", nil)
+		request.Header.Set("If-None-Match", "\"image-64\"")
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		// caches must not be told to keep an image which is gone
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Header().Get("Cache-Control"))
+		assert.Empty(t, rr.Header().Get("ETag"))
+	})
+
+	t.Run("Not modified but not an image", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "upload").Return(io.NopCloser(strings.NewReader("<html><script></script></html>")), nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/upload", nil)
+		request.Header.Set("If-None-Match", "\"upload\"")
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Stale ETag", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "image-64").Return(io.NopCloser(strings.NewReader(image)), nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/image-64", nil)
+		request.Header.Set("If-None-Match", "\"image-128\"")
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Not an image", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "upload").Return(io.NopCloser(strings.NewReader("<html><script></script></html>")), nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/upload", nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "script")
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("OpenImage", mock.Anything, "missing").Return(nil, apperrors.NewNotFound("image", "missing"))
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/missing", nil)
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Header().Get("Cache-Control"))
+	})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type listImageReviewsReq struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending quarantined approved rejected"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// reason is shown to nobody but admins, and is optional
+type reviewImageReq struct {
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+// imageUpdated responds with a user's new profile image, or with their
+// current image and the review when the new one is held for review. The
+// held image is hidden until it is approved, so its URLs are left out
+func imageUpdated(c *gin.Context, u *model.User) {
+	if u.ImageReview != nil {
+		review := *u.ImageReview
+		review.ImageURLs = nil
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"imageUrls": u.ImageURLs,
+			"review":    &review,
+			"message":   "image is held for review",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imageUrls": u.ImageURLs,
+		"message":   "success",
+	})
+}
+
+// ListImageReviews handler lists the profile images held for review,
+// the quarantined ones unless another status is asked for
+func (h *Handler) ListImageReviews(c *gin.Context) {
+	var req listImageReviewsReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	reviews, err := h.UserService.ImageReviews(c.Request.Context(), req.Status, req.Limit)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews": reviews,
+	})
+}
+
+// ApproveImage handler makes an image held for review the user's profile image
+func (h *Handler) ApproveImage(c *gin.Context) {
+	h.reviewImage(c, true)
+}
+
+// RejectImage handler deletes an image held for review
+func (h *Handler) RejectImage(c *gin.Context) {
+	h.reviewImage(c, false)
+}
+
+func (h *Handler) reviewImage(c *gin.Context, approve bool) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req reviewImageReq
+
+	// the body is optional
+	if c.Request.ContentLength > 0 {
+		if ok := bindData(c, &req); !ok {
+			return
+		}
+	}
+
+	review, err := h.UserService.ReviewImage(c.Request.Context(), authUser, id, approve, req.Reason)
+	if err != nil {
+		log.Printf("Failed to review image: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"review": review,
+	})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImageReviews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	admin := &model.User{
+		UID: uuid.New(),
+	}
+
+	setup := func() (*gin.Engine, *mocks.MockUserService) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", admin)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router, mockUserService
+	}
+
+	id := uuid.New()
+
+	t.Run("List", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		reviews := []*model.ImageReview{{ID: id, Status: model.ImageReviewPending}}
+		mockUserService.On("ImageReviews", mock.Anything, model.ImageReviewPending, 10).Return(reviews, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/admin/images/reviews?status=pending&limit=10", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"reviews": reviews,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("List invalid status", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/admin/images/reviews?status=unknown", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "ImageReviews", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		review := &model.ImageReview{ID: id, Status: model.ImageReviewApproved, ReviewedBy: &admin.UID}
+		mockUserService.On("ReviewImage", mock.Anything, admin, id, true, "").Return(review, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/admin/images/reviews/"+id.String()+"/approve", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"review": review,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Reject with reason", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		review := &model.ImageReview{ID: id, Status: model.ImageReviewRejected, Reason: "not a face"}
+		mockUserService.On("ReviewImage", mock.Anything, admin, id, false, "not a face").Return(review, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"reason": "not a face",
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/admin/images/reviews/"+id.String()+"/reject", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid id", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/admin/images/reviews/not-a-uuid/approve", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "ReviewImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already reviewed", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		mockError := apperrors.NewBadRequest("image has already been approved")
+		mockUserService.On("ReviewImage", mock.Anything, admin, id, false, "").Return(nil, mockError)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/admin/images/reviews/"+id.String()+"/reject", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -38,10 +39,32 @@ func TestImage(t *testing.T) {
 		MaxBodyBytes: 4 * 1024 * 1024,
 	})
 
+	// subtests checking whether the service is called get a mock of their own
+	newRouter := func() (*gin.Engine, *mocks.MockUserService) {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &ctxUser)
+		})
+
+		mockUserService := new(mocks.MockUserService)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			MaxBodyBytes: 4 * 1024 * 1024,
+		})
+
+		return router, mockUserService
+	}
+
 	t.Run("Success", func(t *testing.T) {
 		rr := httptest.NewRecorder()
 
-		imageURL := "https://www.imageURL.com/1234"
+		imageURLs := model.ImageURLs{
+			64:  "https://www.imageURL.com/1234-64",
+			128: "https://www.imageURL.com/1234-128",
+			512: "https://www.imageURL.com/1234-512",
+		}
 
 		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
 		defer multipartImageFixture.Close()
@@ -53,7 +76,7 @@ func TestImage(t *testing.T) {
 		}
 
 		updatedUser := ctxUser
-		updatedUser.ImageURL = imageURL
+		updatedUser.ImageURLs = imageURLs
 
 		mockUserService.On("SetProfileImage", setProfileImageArgs...).Return(&updatedUser, nil)
 
@@ -63,8 +86,8 @@ func TestImage(t *testing.T) {
 		router.ServeHTTP(rr, request)
 
 		respBody, _ := json.Marshal(gin.H{
-			"imageUrl": imageURL,
-			"message":  "success",
+			"imageUrls": imageURLs,
+			"message":   "success",
 		})
 
 		assert.Equal(t, http.StatusOK, rr.Code)
@@ -74,6 +97,7 @@ func TestImage(t *testing.T) {
 	})
 
 	t.Run("Disallowed mimetype", func(t *testing.T) {
+		router, mockUserService := newRouter()
 		rr := httptest.NewRecorder()
 
 		multipartImageFixture := fixture.NewMultipartImage("image.txt", "mage/svg+xml")
@@ -86,10 +110,52 @@ func TestImage(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 
-		mockUserService.AssertNotCalled(t, "SetProfileImage")
+		mockUserService.AssertNotCalled(t, "SetProfileImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Accepts GIF content", func(t *testing.T) {
+		router, mockUserService := newRouter()
+		rr := httptest.NewRecorder()
+
+		multipartFileFixture := fixture.NewMultipartFile("image.gif", "image/gif", []byte("GIF89a\x01\x00\x01\x00"))
+		defer multipartFileFixture.Close()
+
+		gifHeader := mock.MatchedBy(func(fh *multipart.FileHeader) bool {
+			return fh.Filename == "image.gif" && fh.Header.Get("Content-Type") == "image/gif"
+		})
+
+		mockUserService.On("SetProfileImage", mock.Anything, ctxUser.UID, gifHeader).Return(&ctxUser, nil)
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", multipartFileFixture.MultipartBody)
+		request.Header.Set("Content-Type", multipartFileFixture.ContentType)
+
+		router.ServeHTTP(rr, request)
+
+		// decoding the rest of the file is left to the service
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "SetProfileImage", mock.Anything, ctxUser.UID, gifHeader)
+	})
+
+	t.Run("Content is not an image", func(t *testing.T) {
+		router, mockUserService := newRouter()
+		rr := httptest.NewRecorder()
+
+		// labeled as a png, but its bytes are not
+		multipartFileFixture := fixture.NewMultipartFile("image.png", "image/png", []byte("<svg></svg>"))
+		defer multipartFileFixture.Close()
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", multipartFileFixture.MultipartBody)
+		request.Header.Set("Content-Type", multipartFileFixture.ContentType)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		mockUserService.AssertNotCalled(t, "SetProfileImage", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("No image file provided", func(t *testing.T) {
+		router, mockUserService := newRouter()
 		rr := httptest.NewRecorder()
 
 		request, _ := http.NewRequest(http.MethodPost, "/image", nil)
@@ -99,7 +165,7 @@ func TestImage(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 
-		mockUserService.AssertNotCalled(t, "SetProfileImage")
+		mockUserService.AssertNotCalled(t, "SetProfileImage", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("Error from SetProfileImage", func(t *testing.T) {
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type imageUploadURLReq struct {
+	ContentType string `json:"contentType" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+}
+
+type commitImageReq struct {
+	ObjectName string `json:"objectName" binding:"required"`
+}
+
+// ImageUploadURL handler returns a pre-signed request with which the
+// signed in user uploads an image straight to storage
+func (h *Handler) ImageUploadURL(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req imageUploadURLReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	// the content of the upload is checked when it is committed
+	if valid := isAllowedImageType(req.ContentType); !valid {
+		e := apperrors.NewBadRequest(imageTypeMessage)
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	upload, err := h.UserService.NewImageUpload(ctx, authUser.UID, req.ContentType, req.Size)
+	if err != nil {
+		log.Printf("Failed to create image upload for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"upload": upload,
+	})
+}
+
+// CommitImage handler makes an image uploaded straight to
+// storage the signed in user's profile image
+func (h *Handler) CommitImage(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req commitImageReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	updatedUser, err := h.UserService.CommitImageUpload(ctx, authUser.UID, req.ObjectName)
+	if err != nil {
+		log.Printf("Failed to commit image upload for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	imageUpdated(c, updatedUser)
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImageUploadURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	setup := func() (*gin.Engine, *mocks.MockUserService) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router, mockUserService
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		upload := &model.ImageUpload{
+			ObjectName: "upload-object",
+			URL:        "https://storage.test/upload-object?signature=abc",
+			Method:     http.MethodPut,
+			Headers:    map[string]string{"Content-Type": "image/webp"},
+			ExpiresAt:  time.Now().Add(time.Minute).UTC(),
+		}
+		mockUserService.On("NewImageUpload", mock.Anything, uid, "image/webp", int64(1024)).Return(upload, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"contentType": "image/webp",
+			"size":        1024,
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/upload-url", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"upload": upload,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Disallowed type", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"contentType": "image/svg+xml",
+			"size":        1024,
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/upload-url", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "NewImageUpload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Missing size", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"contentType": "image/png",
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/upload-url", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "NewImageUpload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error from NewImageUpload", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		mockError := apperrors.NewBadRequest("size must be between 1 and 100 bytes")
+		mockUserService.On("NewImageUpload", mock.Anything, uid, "image/png", int64(1024)).Return(nil, mockError)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"contentType": "image/png",
+			"size":        1024,
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/upload-url", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+}
+
+func TestCommitImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	setup := func() (*gin.Engine, *mocks.MockUserService) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		return router, mockUserService
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		imageURLs := model.ImageURLs{
+			64:  "https://www.imageURL.com/1234-64",
+			128: "https://www.imageURL.com/1234-128",
+			512: "https://www.imageURL.com/1234-512",
+		}
+		mockUserService.On("CommitImageUpload", mock.Anything, uid, "upload-object").Return(&model.User{UID: uid, ImageURLs: imageURLs}, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"objectName": "upload-object",
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/commit", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"imageUrls": imageURLs,
+			"message":   "success",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Held for review", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		imageURLs := model.ImageURLs{64: "http://imageurl.com/current-64"}
+
+		review := &model.ImageReview{
+			ID:        uuid.New(),
+			UID:       uid,
+			ImageURLs: model.ImageURLs{64: "http://imageurl.com/held-64"},
+			Status:    model.ImageReviewPending,
+		}
+		mockUserService.On("CommitImageUpload", mock.Anything, uid, "upload-object").Return(&model.User{UID: uid, ImageURLs: imageURLs, ImageReview: review}, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"objectName": "upload-object",
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/commit", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		// the user's current image is kept until the new one is approved,
+		// and the held image is not linked to until then
+		respBody, _ := json.Marshal(gin.H{
+			"imageUrls": imageURLs,
+			"review": &model.ImageReview{
+				ID:     review.ID,
+				UID:    uid,
+				Status: model.ImageReviewPending,
+			},
+			"message": "image is held for review",
+		})
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Missing objectName", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/commit", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "CommitImageUpload", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error from CommitImageUpload", func(t *testing.T) {
+		router, mockUserService := setup()
+
+		mockError := apperrors.NewNotFound("image", "upload-object")
+		mockUserService.On("CommitImageUpload", mock.Anything, uid, "upload-object").Return(nil, mockError)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"objectName": "upload-object",
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/image/commit", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type inviteReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type acceptInvitationReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Invite handler invites an email into an organization. The response holds
+// the token for the invitee, to be sent to the invited email by the caller
+// as it is not revealed again
+func (h *Handler) Invite(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req inviteReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	inv, token, err := h.InvitationService.Invite(c.Request.Context(), authUser, id, req.Email, req.Role)
+	if err != nil {
+		log.Printf("Failed to invite email: %v to organization: %v: %v\n", req.Email, id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": inv,
+		"token":      token,
+	})
+}
+
+// SentInvitations handler lists the invitations sent by the signed in user
+func (h *Handler) SentInvitations(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	invitations, err := h.InvitationService.ListSent(c.Request.Context(), authUser)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+	})
+}
+
+// ReceivedInvitations handler lists the pending invitations
+// sent to the signed in user's email
+func (h *Handler) ReceivedInvitations(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	invitations, err := h.InvitationService.ListReceived(c.Request.Context(), authUser)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+	})
+}
+
+// RevokeInvitation handler
+func (h *Handler) RevokeInvitation(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.InvitationService.Revoke(c.Request.Context(), authUser, id); err != nil {
+		log.Printf("Failed to revoke invitation: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// AcceptInvitation handler adds the signed in user to the organization
+// they were invited to. Invitees without an account sign up with the token
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req acceptInvitationReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	m, err := h.InvitationService.Accept(c.Request.Context(), authUser, req.Token)
+	if err != nil {
+		log.Printf("Failed to accept invitation for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"member": m,
+	})
+}
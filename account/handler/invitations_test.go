@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInvite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctxUser := &model.User{
+		UID: uuid.New(),
+	}
+	orgID := uuid.New()
+
+	inv := &model.Invitation{ID: uuid.New(), OrgID: orgID, Email: "alice@example.com", Role: model.OrgRoleMember, Status: model.InvitationPending}
+
+	mockInvitationService := new(mocks.MockInvitationService)
+	mockInvitationService.On("Invite", mock.Anything, ctxUser, orgID, "alice@example.com", model.OrgRoleMember).Return(inv, "invitation-token", nil)
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	NewHandler(&Config{
+		R:                 router,
+		InvitationService: mockInvitationService,
+	})
+
+	reqBody, _ := json.Marshal(gin.H{
+		"email": "alice@example.com",
+		"role":  model.OrgRoleMember,
+	})
+
+	request, _ := http.NewRequest(http.MethodPost, "/orgs/"+orgID.String()+"/invitations", bytes.NewBuffer(reqBody))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(gin.H{
+		"invitation": inv,
+		"token":      "invitation-token",
+	})
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	mockInvitationService.AssertExpectations(t)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctxUser := &model.User{
+		UID:   uuid.New(),
+		Email: "alice@example.com",
+	}
+
+	newRouter := func(mockInvitationService *mocks.MockInvitationService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:                 router,
+			InvitationService: mockInvitationService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		m := &model.OrgMember{OrgID: uuid.New(), UID: ctxUser.UID, Role: model.OrgRoleMember}
+
+		mockInvitationService := new(mocks.MockInvitationService)
+		mockInvitationService.On("Accept", mock.Anything, ctxUser, "invitation-token").Return(m, nil)
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockInvitationService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "invitation-token",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/invitations/accept", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"member": m,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Token required", func(t *testing.T) {
+		mockInvitationService := new(mocks.MockInvitationService)
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockInvitationService)
+
+		request, _ := http.NewRequest(http.MethodPost, "/invitations/accept", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockInvitationService.AssertNotCalled(t, "Accept", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Sent to another email", func(t *testing.T) {
+		mockInvitationService := new(mocks.MockInvitationService)
+		mockInvitationService.On("Accept", mock.Anything, ctxUser, "invitation-token").
+			Return(nil, apperrors.NewForbidden("invitation was sent to another email"))
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockInvitationService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "invitation-token",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/invitations/accept", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestReceivedInvitations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctxUser := &model.User{
+		UID:   uuid.New(),
+		Email: "alice@example.com",
+	}
+
+	invitations := []*model.Invitation{{ID: uuid.New(), Email: ctxUser.Email, Status: model.InvitationPending}}
+
+	mockInvitationService := new(mocks.MockInvitationService)
+	mockInvitationService.On("ListReceived", mock.Anything, ctxUser).Return(invitations, nil)
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	NewHandler(&Config{
+		R:                 router,
+		InvitationService: mockInvitationService,
+	})
+
+	request, _ := http.NewRequest(http.MethodGet, "/invitations/received", nil)
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(gin.H{
+		"invitations": invitations,
+	})
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+}
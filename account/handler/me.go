@@ -33,7 +33,7 @@ func (h *Handler) Me(c *gin.Context) {
 
 	// use the Request Context
 	ctx := c.Request.Context()
-	u, err := h.UserService.Get(ctx, uid)
+	u, err := h.UserService.Profile(ctx, uid)
 
 	if err != nil {
 		log.Printf("Unable to find user: %v\n%v", uid, err)
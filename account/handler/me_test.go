@@ -33,7 +33,7 @@ func TestMe(t *testing.T) {
 			}
 
 			mockUserService := new(mocks.MockUserService)
-			mockUserService.On("Get", mock.AnythingOfType("*context.emptyCtx"), uid).Return(mockUserResp, nil)
+			mockUserService.On("Profile", mock.AnythingOfType("*context.emptyCtx"), uid).Return(mockUserResp, nil)
 
 			// a response recorder for getting written http response
 			rr := httptest.NewRecorder()
@@ -74,7 +74,7 @@ func TestMe(t *testing.T) {
 		"NoContextUser",
 		func(t *testing.T) {
 			mockUserService := new(mocks.MockUserService)
-			mockUserService.On("Get", mock.Anything, mock.Anything).Return(nil, nil)
+			mockUserService.On("Profile", mock.Anything, mock.Anything).Return(nil, nil)
 
 			// a response recorder for getting written http response
 			rr := httptest.NewRecorder()
@@ -92,7 +92,7 @@ func TestMe(t *testing.T) {
 			router.ServeHTTP(rr, request)
 
 			assert.Equal(t, 500, rr.Code)
-			mockUserService.AssertNotCalled(t, "Get", mock.Anything)
+			mockUserService.AssertNotCalled(t, "Profile", mock.Anything)
 		},
 	)
 
@@ -101,7 +101,7 @@ func TestMe(t *testing.T) {
 		func(t *testing.T) {
 			uid, _ := uuid.NewRandom()
 			mockUserService := new(mocks.MockUserService)
-			mockUserService.On("Get", mock.Anything, uid).Return(nil, fmt.Errorf("Some error down call chain"))
+			mockUserService.On("Profile", mock.Anything, uid).Return(nil, fmt.Errorf("Some error down call chain"))
 
 			// a response recorder for getting written http response
 			rr := httptest.NewRecorder()
@@ -24,7 +24,8 @@ type invalidArgument struct {
 // AuthUser extracts a user from the Authorization header
 // which is of the form "Bearer token"
 // It sets the user to the context if the user exists
-func AuthUser(s model.TokenService) gin.HandlerFunc {
+// and their account has not been suspended or disabled
+func AuthUser(s model.TokenService, us model.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := authHeader{}
 
@@ -87,6 +88,26 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 			return
 		}
 
+		// the token may have been issued before the account was
+		// suspended or disabled, so check the current status
+		current, err := us.Get(c.Request.Context(), user.UID)
+		if err != nil {
+			err := apperrors.NewAuthorization("Provided token is invalid")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		if err := current.StatusError(); err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
 		c.Set("user", user)
 
 		c.Next()
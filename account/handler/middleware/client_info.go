@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// ClientInfo stores the IP address and user agent of the client in the
+// request context, so the service layer can record where requests came from
+func ClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := model.WithClientInfo(c.Request.Context(), model.ClientInfo{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// DenyImpersonation turns away requests made with an impersonation token,
+// so an admin acting as a user can look at the account but not change or
+// export it. Requests without a user are left to AuthUser to reject.
+// It must be used after AuthUser
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, exists := c.Get("user"); exists && user.(*model.User).ActorUID != nil {
+			abortForbidden(c, "Not allowed while impersonating a user")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// Realm resolves the realm of each request before it reaches the router,
+// storing it in the request context. Requests to baseURL/realms/:id/... are
+// served by realm :id with the prefix removed, so the same routes serve every
+// realm. Otherwise the realm serving the request's host is used, falling
+// back to the default realm. It wraps the router rather than being a gin
+// middleware since it changes the path used for routing
+func Realm(rs model.RealmService, baseURL string, next http.Handler) http.Handler {
+	prefix := baseURL + "/realms/"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		realmID := model.DefaultRealmID
+
+		if rest := strings.TrimPrefix(r.URL.Path, prefix); rest != r.URL.Path {
+			id, path, _ := strings.Cut(rest, "/")
+
+			realm, err := rs.Get(id)
+			if err != nil {
+				e := apperrors.NewNotFound("realm", id)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(e.Status())
+				json.NewEncoder(w).Encode(gin.H{
+					"error": e,
+				})
+				return
+			}
+
+			realmID = realm.ID
+
+			// copy the URL so the original request is left untouched
+			u := *r.URL
+			u.Path = baseURL + "/" + path
+			u.RawPath = ""
+			r = r.Clone(r.Context())
+			r.URL = &u
+		} else if realm, ok := rs.ResolveHost(hostname(r.Host)); ok {
+			realmID = realm.ID
+		}
+
+		next.ServeHTTP(w, r.WithContext(model.WithRealm(r.Context(), realmID)))
+	})
+}
+
+// hostname strips the port, if any, from a Host header
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// RequireRole only lets the request through if the user set by
+// AuthUser holds at least one of the given roles.
+// It must be used after AuthUser
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := contextUser(c)
+		if !ok {
+			return
+		}
+
+		if !user.HasRole(roles...) {
+			abortForbidden(c, fmt.Sprintf("Requires one of roles: %s", strings.Join(roles, ", ")))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission only lets the request through if one of the roles
+// of the user set by AuthUser grants at least one of the given permissions.
+// It must be used after AuthUser
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := contextUser(c)
+		if !ok {
+			return
+		}
+
+		if !user.HasPermission(permissions...) {
+			abortForbidden(c, fmt.Sprintf("Requires one of permissions: %s", strings.Join(permissions, ", ")))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// contextUser extracts the user set by AuthUser, aborting if there is none
+func contextUser(c *gin.Context) (*model.User, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		err := apperrors.NewAuthorization("Must be signed in")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	return user.(*model.User), true
+}
+
+func abortForbidden(c *gin.Context, reason string) {
+	err := apperrors.NewForbidden(reason)
+	c.JSON(err.Status(), gin.H{
+		"error": err,
+	})
+	c.Abort()
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// SignedURL only lets requests for files through when their expires and
+// signature query parameters are accepted by verify, which gets the name
+// of the requested file. It guards routes serving private images
+func SignedURL(verify func(objName string, expires int64, signature string) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+		if err != nil {
+			e := apperrors.NewAuthorization("Invalid image link")
+			c.JSON(e.Status(), gin.H{
+				"error": e,
+			})
+			c.Abort()
+			return
+		}
+
+		if err := verify(path.Base(c.Request.URL.Path), expires, c.Query("signature")); err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
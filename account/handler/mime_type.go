@@ -1,8 +1,15 @@
 package handler
 
+import (
+	"io"
+	"net/http"
+)
+
 var validImageTypes = map[string]bool{
 	"image/jpeg": true,
 	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
 }
 
 func isAllowedImageType(mimeType string) bool {
@@ -10,3 +17,17 @@ func isAllowedImageType(mimeType string) bool {
 
 	return exists
 }
+
+// sniffImageType detects the mime type of a file from the magic
+// number at its start, rather than trusting its Content-Type
+func sniffImageType(r io.Reader) (string, error) {
+	// DetectContentType considers at most the first 512 bytes
+	header := make([]byte, 512)
+
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	return http.DetectContentType(header[:n]), nil
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+type orgReq struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type addMemberReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type updateMemberReq struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+// CreateOrg handler creates an organization owned by the signed in user
+func (h *Handler) CreateOrg(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req orgReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	o, err := h.OrganizationService.CreateOrganization(c.Request.Context(), authUser, req.Name)
+	if err != nil {
+		log.Printf("Failed to create organization for uid: %v: %v\n", authUser.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"organization": o,
+	})
+}
+
+// ListOrgs handler returns the organizations the signed in user belongs to
+func (h *Handler) ListOrgs(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	orgs, err := h.OrganizationService.ListOrganizations(c.Request.Context(), authUser)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organizations": orgs,
+	})
+}
+
+// GetOrg handler
+func (h *Handler) GetOrg(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	o, err := h.OrganizationService.GetOrganization(c.Request.Context(), authUser, id)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": o,
+	})
+}
+
+// UpdateOrg handler
+func (h *Handler) UpdateOrg(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req orgReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	o, err := h.OrganizationService.UpdateOrganization(c.Request.Context(), authUser, id, req.Name)
+	if err != nil {
+		log.Printf("Failed to update organization: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": o,
+	})
+}
+
+// DeleteOrg handler
+func (h *Handler) DeleteOrg(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.OrganizationService.DeleteOrganization(c.Request.Context(), authUser, id); err != nil {
+		log.Printf("Failed to delete organization: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// ListOrgMembers handler
+func (h *Handler) ListOrgMembers(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	members, err := h.OrganizationService.ListMembers(c.Request.Context(), authUser, id)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+	})
+}
+
+// AddOrgMember handler adds an existing user to an organization by email
+func (h *Handler) AddOrgMember(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req addMemberReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	m, err := h.OrganizationService.AddMember(c.Request.Context(), authUser, id, req.Email, req.Role)
+	if err != nil {
+		log.Printf("Failed to add member to organization: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"member": m,
+	})
+}
+
+// UpdateOrgMember handler changes the role of a member
+func (h *Handler) UpdateOrgMember(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	var req updateMemberReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	m, err := h.OrganizationService.UpdateMember(c.Request.Context(), authUser, id, uid, req.Role)
+	if err != nil {
+		log.Printf("Failed to update member: %v of organization: %v: %v\n", uid, id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"member": m,
+	})
+}
+
+// RemoveOrgMember handler removes a member, or lets the signed in user leave
+func (h *Handler) RemoveOrgMember(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	uid, ok := uidParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.OrganizationService.RemoveMember(c.Request.Context(), authUser, id, uid); err != nil {
+		log.Printf("Failed to remove member: %v from organization: %v: %v\n", uid, id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateOrg(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctxUser := &model.User{
+		UID: uuid.New(),
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		o := &model.Organization{ID: uuid.New(), Name: "Acme", Role: model.OrgRoleOwner}
+
+		mockOrganizationService := new(mocks.MockOrganizationService)
+		mockOrganizationService.On("CreateOrganization", mock.Anything, ctxUser, "Acme").Return(o, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:                   router,
+			OrganizationService: mockOrganizationService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name": "Acme",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/orgs", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"organization": o,
+		})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockOrganizationService.AssertExpectations(t)
+	})
+
+	t.Run("Missing name", func(t *testing.T) {
+		mockOrganizationService := new(mocks.MockOrganizationService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:                   router,
+			OrganizationService: mockOrganizationService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/orgs", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockOrganizationService.AssertNotCalled(t, "CreateOrganization", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAddOrgMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctxUser := &model.User{
+		UID: uuid.New(),
+	}
+	orgID := uuid.New()
+
+	newRouter := func(mockOrganizationService *mocks.MockOrganizationService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+		})
+
+		NewHandler(&Config{
+			R:                   router,
+			OrganizationService: mockOrganizationService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		m := &model.OrgMember{OrgID: orgID, UID: uuid.New(), Role: model.OrgRoleAdmin, Email: "alice@example.com"}
+
+		mockOrganizationService := new(mocks.MockOrganizationService)
+		mockOrganizationService.On("AddMember", mock.Anything, ctxUser, orgID, "alice@example.com", model.OrgRoleAdmin).Return(m, nil)
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockOrganizationService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "alice@example.com",
+			"role":  model.OrgRoleAdmin,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/orgs/"+orgID.String()+"/members", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"member": m,
+		})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Invalid role", func(t *testing.T) {
+		mockOrganizationService := new(mocks.MockOrganizationService)
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockOrganizationService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "alice@example.com",
+			"role":  "superuser",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/orgs/"+orgID.String()+"/members", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockOrganizationService.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		mockOrganizationService := new(mocks.MockOrganizationService)
+		mockOrganizationService.On("AddMember", mock.Anything, ctxUser, orgID, "alice@example.com", model.OrgRoleOwner).
+			Return(nil, apperrors.NewForbidden("only owners can add owners"))
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockOrganizationService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "alice@example.com",
+			"role":  model.OrgRoleOwner,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/orgs/"+orgID.String()+"/members", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestRemoveOrgMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctxUser := &model.User{
+		UID: uuid.New(),
+	}
+	orgID := uuid.New()
+
+	mockOrganizationService := new(mocks.MockOrganizationService)
+	mockOrganizationService.On("RemoveMember", mock.Anything, ctxUser, orgID, ctxUser.UID).Return(nil)
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	NewHandler(&Config{
+		R:                   router,
+		OrganizationService: mockOrganizationService,
+	})
+
+	request, _ := http.NewRequest(http.MethodDelete, "/orgs/"+orgID.String()+"/members/"+ctxUser.UID.String(), nil)
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockOrganizationService.AssertExpectations(t)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// Realm handler returns the realm serving the request along with its
+// branding, so apps can present themselves before anyone signs in
+func (h *Handler) Realm(c *gin.Context) {
+	realmID := model.RealmIDFromContext(c.Request.Context())
+
+	realm, err := h.RealmService.Get(realmID)
+	if err != nil {
+		log.Printf("Unable to find realm: %v\n%v", realmID, err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"realm": realm,
+	})
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/handler/middleware"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	defaultRealm := &model.Realm{ID: model.DefaultRealmID, Name: "Default"}
+	acme := &model.Realm{
+		ID:   "acme",
+		Name: "Acme",
+		Branding: model.RealmBranding{
+			DisplayName:  "Acme Corp",
+			PrimaryColor: "#ff0000",
+		},
+	}
+
+	mockRealmService := new(mocks.MockRealmService)
+	mockRealmService.On("Get", model.DefaultRealmID).Return(defaultRealm, nil)
+	mockRealmService.On("Get", "acme").Return(acme, nil)
+	mockRealmService.On("Get", "nope").Return(nil, apperrors.NewNotFound("realm", "nope"))
+	mockRealmService.On("ResolveHost", "accounts.acme.test").Return(acme, true)
+	mockRealmService.On("ResolveHost", "localhost").Return(nil, false)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		RealmService: mockRealmService,
+		BaseURL:      "/api/account",
+	})
+
+	server := middleware.Realm(mockRealmService, "/api/account", router)
+
+	realmFrom := func(rr *httptest.ResponseRecorder) *model.Realm {
+		body := struct {
+			Realm *model.Realm `json:"realm"`
+		}{}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		return body.Realm
+	}
+
+	t.Run("Default realm", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/api/account/realm", nil)
+		server.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, model.DefaultRealmID, realmFrom(rr).ID)
+	})
+
+	t.Run("Realm from host", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "http://accounts.acme.test/api/account/realm", nil)
+		server.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		realm := realmFrom(rr)
+		assert.Equal(t, "acme", realm.ID)
+		assert.Equal(t, acme.Branding, realm.Branding)
+	})
+
+	t.Run("Realm from path", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/account/realms/acme/realm", nil)
+		server.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "acme", realmFrom(rr).ID)
+	})
+
+	t.Run("Unknown realm", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/account/realms/nope/realm", nil)
+		server.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": apperrors.NewNotFound("realm", "nope"),
+		})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.JSONEq(t, string(respBody), rr.Body.String())
+	})
+}
@@ -11,9 +11,11 @@ import (
 
 // signupReq is not exported, hence the lowercase name
 // it is used for validation and json marshalling
+// Invitation is the optional token of an invitation sent to the email
 type signupReq struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,gte=6,lte=30"`
+	Invitation string `json:"invitation"`
 }
 
 // Signup handler
@@ -33,7 +35,14 @@ func (h *Handler) Signup(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	err := h.UserService.Signup(ctx, u)
+
+	var err error
+	if req.Invitation != "" {
+		// invitees join the organization they were invited to as they sign up
+		err = h.InvitationService.Signup(ctx, u, req.Invitation)
+	} else {
+		err = h.UserService.Signup(ctx, u)
+	}
 
 	if err != nil {
 		log.Printf("Failed to sign up user: %v\n", err.Error())
@@ -338,4 +338,60 @@ func TestSignup(t *testing.T) {
 			mockTokenService.AssertExpectations(t)
 		},
 	)
+
+	t.Run(
+		"Signup with invitation",
+		func(t *testing.T) {
+			u := &model.User{
+				Email:    "alice@example.com",
+				Password: "avalidpassword",
+			}
+
+			mockTokenResp := &model.TokenPair{
+				IDToken:      model.IDToken{SS: "idToken"},
+				RefreshToken: model.RefreshToken{SS: "refreshToken"},
+			}
+
+			mockUserService := new(mocks.MockUserService)
+			mockInvitationService := new(mocks.MockInvitationService)
+			mockTokenService := new(mocks.MockTokenService)
+
+			mockInvitationService.
+				On("Signup", mock.AnythingOfType("*context.emptyCtx"), u, "invitation-token").
+				Return(nil)
+			mockTokenService.
+				On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "").
+				Return(mockTokenResp, nil)
+
+			rr := httptest.NewRecorder()
+
+			router := gin.Default()
+
+			NewHandler(&Config{
+				R:                 router,
+				UserService:       mockUserService,
+				InvitationService: mockInvitationService,
+				TokenService:      mockTokenService,
+			})
+
+			reqBody, err := json.Marshal(gin.H{
+				"email":      u.Email,
+				"password":   u.Password,
+				"invitation": "invitation-token",
+			})
+			assert.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(reqBody))
+			assert.NoError(t, err)
+
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			mockInvitationService.AssertExpectations(t)
+			mockTokenService.AssertExpectations(t)
+			mockUserService.AssertNotCalled(t, "Signup", mock.Anything, mock.Anything)
+		},
+	)
 }
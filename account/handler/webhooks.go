@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// secret is optional and generated when empty
+type createWebhookReq struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+type updateWebhookReq struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active" binding:"required"`
+}
+
+type listDeliveriesReq struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending delivered failed"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// ListWebhooks handler
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.WebhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+	})
+}
+
+// CreateWebhook handler responds with the webhook's secret,
+// which is not revealed again
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req createWebhookReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	w := &model.Webhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: req.Secret,
+		Active: true,
+	}
+
+	if err := h.WebhookService.CreateWebhook(c.Request.Context(), w); err != nil {
+		log.Printf("Failed to create webhook for url: %v: %v\n", req.URL, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": w,
+		"secret":  w.Secret,
+	})
+}
+
+// UpdateWebhook handler
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req updateWebhookReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	w := &model.Webhook{
+		ID:     id,
+		URL:    req.URL,
+		Events: req.Events,
+		Active: *req.Active,
+	}
+
+	if err := h.WebhookService.UpdateWebhook(c.Request.Context(), w); err != nil {
+		log.Printf("Failed to update webhook: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook": w,
+	})
+}
+
+// DeleteWebhook handler
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.WebhookService.DeleteWebhook(c.Request.Context(), id); err != nil {
+		log.Printf("Failed to delete webhook: %v: %v\n", id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// ListWebhookDeliveries handler returns a page of a webhook's delivery log
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req listDeliveriesReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	page, err := h.WebhookService.ListDeliveries(c.Request.Context(), id, req.Status, req.Cursor, req.Limit)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ReplayWebhookDelivery handler queues a failed delivery to be sent again
+func (h *Handler) ReplayWebhookDelivery(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(c.Param("delivery"), 10, 64)
+	if err != nil {
+		e := apperrors.NewBadRequest("delivery must be a valid id")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	d, err := h.WebhookService.ReplayDelivery(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		log.Printf("Failed to replay delivery: %v of webhook: %v: %v\n", deliveryID, id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivery": d,
+	})
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mockWebhookService *mocks.MockWebhookService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			WebhookService: mockWebhookService,
+		})
+
+		return router
+	}
+
+	t.Run("Responds with secret", func(t *testing.T) {
+		mockWebhookService := new(mocks.MockWebhookService)
+		mockWebhookService.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(w *model.Webhook) bool {
+			return w.URL == "https://partner.example.com/hooks" &&
+				len(w.Events) == 1 && w.Events[0] == model.EventEmailChanged &&
+				w.Active
+		})).Run(func(args mock.Arguments) {
+			w := args.Get(1).(*model.Webhook)
+			w.Secret = "generated-secret"
+		}).Return(nil)
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockWebhookService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"url":    "https://partner.example.com/hooks",
+			"events": []string{model.EventEmailChanged},
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		var resp struct {
+			Secret string `json:"secret"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "generated-secret", resp.Secret)
+		mockWebhookService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid url", func(t *testing.T) {
+		mockWebhookService := new(mocks.MockWebhookService)
+
+		rr := httptest.NewRecorder()
+		router := newRouter(mockWebhookService)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"url": "not a url",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockWebhookService.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+	})
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	page := &model.WebhookDeliveryPage{
+		Deliveries: []*model.WebhookDelivery{{ID: 3, WebhookID: id, Status: model.WebhookDeliveryFailed}},
+	}
+
+	mockWebhookService := new(mocks.MockWebhookService)
+	mockWebhookService.On("ListDeliveries", mock.Anything, id, model.WebhookDeliveryFailed, "4", 1).Return(page, nil)
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:              router,
+		WebhookService: mockWebhookService,
+	})
+
+	request, _ := http.NewRequest(http.MethodGet, "/admin/webhooks/"+id.String()+"/deliveries?status=failed&cursor=4&limit=1", nil)
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(page)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	mockWebhookService.AssertExpectations(t)
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		d := &model.WebhookDelivery{ID: 12, WebhookID: id, Status: model.WebhookDeliveryPending}
+
+		mockWebhookService := new(mocks.MockWebhookService)
+		mockWebhookService.On("ReplayDelivery", mock.Anything, id, int64(12)).Return(d, nil)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			WebhookService: mockWebhookService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/admin/webhooks/"+id.String()+"/deliveries/12/replay", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"delivery": d,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Not failed", func(t *testing.T) {
+		mockWebhookService := new(mocks.MockWebhookService)
+		mockWebhookService.On("ReplayDelivery", mock.Anything, id, int64(13)).Return(nil, apperrors.NewBadRequest("only failed deliveries can be replayed"))
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			WebhookService: mockWebhookService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/admin/webhooks/"+id.String()+"/deliveries/13/replay", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Invalid delivery id", func(t *testing.T) {
+		mockWebhookService := new(mocks.MockWebhookService)
+
+		rr := httptest.NewRecorder()
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			WebhookService: mockWebhookService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/admin/webhooks/"+id.String()+"/deliveries/abc/replay", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockWebhookService.AssertNotCalled(t, "ReplayDelivery", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/repository"
+)
+
+// initImageModerator selects the moderator for new profile images with
+// IMAGE_MODERATOR, which allows every image when unset or "none"
+func initImageModerator() (model.ImageModerator, error) {
+	switch imageModerator := os.Getenv("IMAGE_MODERATOR"); imageModerator {
+	case "", "none":
+		return repository.NewNoopImageModerator(), nil
+	case "rules":
+		return initRulesImageModerator()
+	default:
+		return nil, fmt.Errorf("unknown IMAGE_MODERATOR: %s", imageModerator)
+	}
+}
+
+// initRulesImageModerator rejects images smaller than IMAGE_MIN_DIMENSION, 64 by
+// default, or whose SHA-256 is listed in the IMAGE_HASH_BLOCKLIST file, and
+// quarantines those longer than IMAGE_MAX_ASPECT_RATIO times their width, 3 by
+// default. Either limit is turned off by setting it to 0
+func initRulesImageModerator() (model.ImageModerator, error) {
+	imd, err := envInt("IMAGE_MIN_DIMENSION", 64)
+	if err != nil {
+		return nil, err
+	}
+
+	imar := 3.0
+	if imageMaxAspectRatio := os.Getenv("IMAGE_MAX_ASPECT_RATIO"); imageMaxAspectRatio != "" {
+		if imar, err = strconv.ParseFloat(imageMaxAspectRatio, 64); err != nil {
+			return nil, fmt.Errorf("could not parse IMAGE_MAX_ASPECT_RATIO as float: %w", err)
+		}
+	}
+
+	var blocklist []string
+	if path := os.Getenv("IMAGE_HASH_BLOCKLIST"); path != "" {
+		if blocklist, err = readHashBlocklist(path); err != nil {
+			return nil, fmt.Errorf("could not read IMAGE_HASH_BLOCKLIST: %w", err)
+		}
+	}
+
+	return repository.NewRulesImageModerator(int(imd), imar, blocklist), nil
+}
+
+// readHashBlocklist reads one hex encoded hash per line,
+// skipping blank lines and those starting with #
+func readHashBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hashes []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hashes = append(hashes, strings.ToLower(line))
+	}
+
+	return hashes, scanner.Err()
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/repository"
+)
+
+// imageStorage holds the ImageRepository selected by IMAGE_STORAGE
+// Dir and URL are only set when images are stored on disk, and
+// tell the router where to serve them from. URLTTL is only set
+// when the storage is private, and images are linked to by URLs
+// signed for that long. Otherwise PublicURL is where images are
+// linked to. Secret signs links to files on disk, so without it
+// they can neither be private nor uploaded directly
+type imageStorage struct {
+	Repository model.ImageRepository
+	Dir        string
+	URL        string
+	URLTTL     time.Duration
+	PublicURL  string
+	Secret     string
+	close      func() error
+}
+
+// initImageStorage connects to the storage selected by IMAGE_STORAGE,
+// defaulting to Google Cloud Storage. With IMAGE_PRIVATE the storage
+// is not publicly readable, and links to images expire after IMAGE_URL_TTL,
+// including those in ID tokens and events, which are signed as they are sent.
+// Public images are linked to at IMAGE_PUBLIC_URL, such as a CDN, and are
+// otherwise served by this service under ACCOUNT_API_URL, hiding the storage
+func initImageStorage() (*imageStorage, error) {
+	private := false
+	if imagePrivate := os.Getenv("IMAGE_PRIVATE"); imagePrivate != "" {
+		var err error
+		if private, err = strconv.ParseBool(imagePrivate); err != nil {
+			return nil, fmt.Errorf("could not parse IMAGE_PRIVATE as bool: %w", err)
+		}
+	}
+
+	var urlTTL time.Duration
+	if private {
+		// signed links last 15 minutes unless IMAGE_URL_TTL says otherwise
+		ttl, err := envInt("IMAGE_URL_TTL", 15*60)
+		if err != nil {
+			return nil, err
+		}
+
+		if ttl <= 0 {
+			return nil, fmt.Errorf("IMAGE_URL_TTL must be positive for IMAGE_PRIVATE storage")
+		}
+
+		urlTTL = time.Duration(ttl) * time.Second
+	}
+
+	var images *imageStorage
+	var err error
+
+	switch imageStorageType := os.Getenv("IMAGE_STORAGE"); imageStorageType {
+	case "", "gcs":
+		images, err = initGCImageStorage()
+	case "s3":
+		images, err = initS3ImageStorage()
+	case "fs":
+		images, err = initFSImageStorage(private)
+	default:
+		return nil, fmt.Errorf("unknown IMAGE_STORAGE: %s", imageStorageType)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	images.URLTTL = urlTTL
+
+	if !private {
+		images.PublicURL = os.Getenv("IMAGE_PUBLIC_URL")
+		if images.PublicURL == "" {
+			images.PublicURL = path.Join(os.Getenv("ACCOUNT_API_URL"), "images")
+		}
+	}
+
+	return images, nil
+}
+
+func initGCImageStorage() (*imageStorage, error) {
+	log.Printf("Connecting to cloud storage\n")
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel() // release operation if slow Operation completes before time elapses
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cloud storage: %w", err)
+	}
+
+	return &imageStorage{
+		Repository: repository.NewImageRepository(client, os.Getenv("GC_IMAGE_BUCKET")),
+		close:      client.Close,
+	}, nil
+}
+
+// initS3ImageStorage uses S3_BUCKET, which is required, in S3_REGION, defaulting
+// to us-east-1 which most storage other than AWS accepts. S3_ENDPOINT is only needed
+// for storage other than AWS, which often also requires S3_PATH_STYLE addressing.
+// Images are stored with links to S3_PUBLIC_URL, or where the bucket serves them otherwise
+func initS3ImageStorage() (*imageStorage, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	bucket := os.Getenv("S3_BUCKET")
+
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET is required for IMAGE_STORAGE s3")
+	}
+
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	pathStyle := false
+	if s3PathStyle := os.Getenv("S3_PATH_STYLE"); s3PathStyle != "" {
+		var err error
+		if pathStyle, err = strconv.ParseBool(s3PathStyle); err != nil {
+			return nil, fmt.Errorf("could not parse S3_PATH_STYLE as bool: %w", err)
+		}
+	}
+
+	opts := s3.Options{
+		Region:       region,
+		UsePathStyle: pathStyle,
+		Credentials:  credentials.NewStaticCredentialsProvider(os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY"), ""),
+	}
+
+	if endpoint != "" {
+		opts.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+	}
+
+	baseURL := os.Getenv("S3_PUBLIC_URL")
+	if baseURL == "" {
+		var err error
+		if baseURL, err = s3BucketURL(endpoint, bucket, region, pathStyle); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("Using S3 bucket: %v\n", bucket)
+
+	return &imageStorage{
+		Repository: repository.NewS3ImageRepository(s3.New(opts), bucket, baseURL),
+		close:      func() error { return nil },
+	}, nil
+}
+
+// s3BucketURL returns the URL objects of bucket are served from
+func s3BucketURL(endpoint string, bucket string, region string, pathStyle bool) (string, error) {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("could not parse S3_ENDPOINT: %w", err)
+	}
+
+	if pathStyle {
+		return u.JoinPath(bucket).String(), nil
+	}
+
+	u.Host = bucket + "." + u.Host
+
+	return u.String(), nil
+}
+
+// initFSImageStorage stores images under IMAGE_DIR, which the
+// router serves at FS_IMAGE_URL. Links to private files and
+// uploads straight to the directory are signed with FS_IMAGE_SECRET
+func initFSImageStorage(private bool) (*imageStorage, error) {
+	dir := os.Getenv("IMAGE_DIR")
+	imageURL := os.Getenv("FS_IMAGE_URL")
+
+	if dir == "" || imageURL == "" {
+		return nil, fmt.Errorf("IMAGE_DIR and FS_IMAGE_URL are required for IMAGE_STORAGE fs")
+	}
+
+	secret := os.Getenv("FS_IMAGE_SECRET")
+	if private && secret == "" {
+		return nil, fmt.Errorf("FS_IMAGE_SECRET is required for IMAGE_PRIVATE fs storage")
+	}
+
+	return &imageStorage{
+		Repository: repository.NewFSImageRepository(dir, imageURL, secret),
+		Dir:        dir,
+		URL:        imageURL,
+		Secret:     secret,
+		close:      func() error { return nil },
+	}, nil
+}
+
+// storeFSUpload stands in for cloud storage when images are stored on disk,
+// saving files put at links created by the repository's SignUpload method
+func storeFSUpload(images *imageStorage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		objName := c.Param("obj")
+		size := c.Request.ContentLength
+
+		expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+		if err == nil {
+			err = repository.VerifyFSImageUpload(images.Secret, objName, c.GetHeader("Content-Type"), size, expires, c.Query("signature"))
+		} else {
+			err = apperrors.NewAuthorization("Invalid image link")
+		}
+
+		if err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, size))
+		if err != nil || int64(len(data)) != size {
+			e := apperrors.NewBadRequest("body does not match Content-Length")
+			c.JSON(e.Status(), gin.H{
+				"error": e,
+			})
+			return
+		}
+
+		if _, err := images.Repository.UpdateProfile(c.Request.Context(), objName, uploadedFile{bytes.NewReader(data)}); err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+// uploadedFile is an in memory multipart.File holding an upload's body
+type uploadedFile struct {
+	*bytes.Reader
+}
+
+// Close does nothing as there is nothing to release
+func (uploadedFile) Close() error { return nil }
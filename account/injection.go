@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/ndenisj/go_mem/account/handler"
+	"github.com/ndenisj/go_mem/account/handler/middleware"
+	"github.com/ndenisj/go_mem/account/model"
 	"github.com/ndenisj/go_mem/account/repository"
 	"github.com/ndenisj/go_mem/account/service"
 )
@@ -18,7 +24,7 @@ import (
 // which inject into repository layer
 // which inject into service layer
 // which inject into handler layer
-func inject(d *dataSources) (*gin.Engine, error) {
+func inject(d *dataSources, bg *jobs) (http.Handler, error) {
 	log.Println("Injecting data sources")
 
 	/*
@@ -26,17 +32,396 @@ func inject(d *dataSources) (*gin.Engine, error) {
 	 */
 	userRepository := repository.NewUserRepository(d.DB)
 
+	roleRepository := repository.NewRoleRepository(d.DB)
+
+	adminActionRepository := repository.NewAdminActionRepository(d.DB)
+
+	auditEventRepository := repository.NewAuditEventRepository(d.DB)
+
+	outboxRepository := repository.NewOutboxRepository(d.DB)
+
+	webhookRepository := repository.NewWebhookRepository(d.DB)
+
+	organizationRepository := repository.NewOrganizationRepository(d.DB)
+
+	invitationRepository := repository.NewInvitationRepository(d.DB)
+
+	realmRepository := repository.NewRealmRepository(d.DB)
+
+	webhookDeliveryRepository := repository.NewWebhookDeliveryRepository(d.DB)
+
+	imageReviewRepository := repository.NewImageReviewRepository(d.DB)
+
+	transactor := repository.NewTransactor(d.DB)
+
 	tokenRepository := repository.NewTokenRepository(d.RedisClient)
 
-	bucketName := os.Getenv("GC_IMAGE_BUCKET")
-	imageRepository := repository.NewImageRepository(d.StorageClient, bucketName)
+	exportRepository := repository.NewExportRepository(d.RedisClient)
+
+	// EVENTS_BROKER selects where user events are published, defaulting to a redis stream
+	// named EVENTS_STREAM, "account-events" by default, trimmed to about
+	// EVENTS_STREAM_MAX_LEN entries, 100000 by default, or left to grow when 0
+	var eventsBroker model.EventsBroker
+
+	switch broker := os.Getenv("EVENTS_BROKER"); broker {
+	case "memory":
+		eventsBroker = repository.NewMemoryEventsBroker()
+	case "", "redis":
+		esml, err := envInt("EVENTS_STREAM_MAX_LEN", 100000)
+		if err != nil {
+			return nil, err
+		}
+
+		eventsStream := os.Getenv("EVENTS_STREAM")
+		if eventsStream == "" {
+			eventsStream = "account-events"
+		}
+
+		eventsBroker = repository.NewEventsBroker(d.RedisClient, eventsStream, esml)
+	default:
+		return nil, fmt.Errorf("unknown EVENTS_BROKER: %s", broker)
+	}
+
+	imageRepository := d.Images.Repository
 
 	/*
 	 * service layer
 	 */
+	// realms are loaded at startup and reloaded every REALM_RELOAD_INTERVAL, a minute by default
+	rri, err := envInt("REALM_RELOAD_INTERVAL", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	realmService := service.NewRealmService(&service.RSConfig{
+		RealmRepository: realmRepository,
+	})
+
+	if err := realmService.Reload(context.Background()); err != nil {
+		return nil, fmt.Errorf("could not load realms: %w", err)
+	}
+
+	bg.every("reload realms", time.Duration(rri)*time.Second, func(ctx context.Context) {
+		if err := realmService.Reload(ctx); err != nil {
+			log.Printf("failed to reload realms: %v\n", err)
+		}
+	})
+
+	// deleted accounts are kept for DELETION_GRACE_PERIOD, 30 days by default,
+	// before being purged by a job running every PURGE_INTERVAL, an hour by default
+	dgp, err := envInt("DELETION_GRACE_PERIOD", 30*24*60*60)
+	if err != nil {
+		return nil, err
+	}
+
+	pi, err := envInt("PURGE_INTERVAL", 60*60)
+	if err != nil {
+		return nil, err
+	}
+
+	// uploaded images wider or taller than MAX_IMAGE_DIMENSION are refused before
+	// decoding, with the user service's own limit used when it is unset
+	mid, err := envInt("MAX_IMAGE_DIMENSION", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// images uploaded straight to storage are held to the same size as request bodies
+	maxBodyBytes := os.Getenv("MAX_BODY_BYTES")
+	mbb, err := strconv.ParseInt(maxBodyBytes, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MAX_BODY_BYTES as int: %w", err)
+	}
+
+	// uploads straight to storage must be started within IMAGE_UPLOAD_TTL, 15 minutes by default
+	iuttl, err := envInt("IMAGE_UPLOAD_TTL", 15*60)
+	if err != nil {
+		return nil, err
+	}
+
+	// replaced profile images are kept for users to revert to, up to IMAGE_HISTORY_LIMIT
+	// of them, 5 by default. They are deleted straight away when it is 0
+	ihl, err := envInt("IMAGE_HISTORY_LIMIT", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	imageModerator, err := initImageModerator()
+	if err != nil {
+		return nil, err
+	}
+
+	// with IMAGE_MODERATION_ASYNC new images are held for review while the moderator decides
+	moderateAsync := false
+	if async := os.Getenv("IMAGE_MODERATION_ASYNC"); async != "" {
+		if moderateAsync, err = strconv.ParseBool(async); err != nil {
+			return nil, fmt.Errorf("could not parse IMAGE_MODERATION_ASYNC as bool: %w", err)
+		}
+	}
+
 	userService := service.NewUserService(&service.USConfig{
+		UserRepository:        userRepository,
+		ImageRepository:       imageRepository,
+		TokenRepository:       tokenRepository,
+		AuditEventRepository:  auditEventRepository,
+		OutboxRepository:      outboxRepository,
+		Transactor:            transactor,
+		DeletionGracePeriod:   time.Duration(dgp) * time.Second,
+		MaxImageDimension:     int(mid),
+		ImageURLTTL:           d.Images.URLTTL,
+		ImagePublicURL:        d.Images.PublicURL,
+		ImageUploadTTL:        time.Duration(iuttl) * time.Second,
+		MaxImageBytes:         mbb,
+		BaseURL:               os.Getenv("ACCOUNT_API_URL"),
+		ImageHistoryLimit:     int(ihl),
+		ImageModerator:        imageModerator,
+		ImageReviewRepository: imageReviewRepository,
+		ModerateAsync:         moderateAsync,
+	})
+
+	bg.every("purge deleted users", time.Duration(pi)*time.Second, func(ctx context.Context) {
+		purged, err := userService.PurgeDeleted(ctx)
+		if err != nil {
+			log.Printf("failed to purge deleted users: %v\n", err)
+			return
+		}
+
+		if purged > 0 {
+			log.Printf("purged %d deleted users\n", purged)
+		}
+	})
+
+	// images whose moderation never finished, for example because the service stopped,
+	// are quarantined for an admin every IMAGE_REVIEW_SWEEP_INTERVAL, a minute by default
+	ris, err := envInt("IMAGE_REVIEW_SWEEP_INTERVAL", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	bg.every("sweep image reviews", time.Duration(ris)*time.Second, func(ctx context.Context) {
+		swept, err := userService.SweepImageReviews(ctx)
+		if err != nil {
+			log.Printf("failed to sweep image reviews: %v\n", err)
+			return
+		}
+
+		if swept > 0 {
+			log.Printf("quarantined %d image reviews whose moderation did not finish\n", swept)
+		}
+	})
+
+	// images no user refers to are collected every IMAGE_GC_INTERVAL, an hour by default,
+	// once they are older than IMAGE_GC_GRACE_PERIOD, a day by default, only being
+	// reported when IMAGE_GC_DRY_RUN is set
+	igci, err := envInt("IMAGE_GC_INTERVAL", 60*60)
+	if err != nil {
+		return nil, err
+	}
+
+	igcgp, err := envInt("IMAGE_GC_GRACE_PERIOD", 24*60*60)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Duration(igcgp)*time.Second <= time.Duration(iuttl)*time.Second {
+		return nil, fmt.Errorf("IMAGE_GC_GRACE_PERIOD must be longer than IMAGE_UPLOAD_TTL")
+	}
+
+	imageGCDryRun := false
+	if dryRun := os.Getenv("IMAGE_GC_DRY_RUN"); dryRun != "" {
+		if imageGCDryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return nil, fmt.Errorf("could not parse IMAGE_GC_DRY_RUN as bool: %w", err)
+		}
+	}
+
+	imageCollector := service.NewImageCollector(&service.ICConfig{
 		UserRepository:  userRepository,
 		ImageRepository: imageRepository,
+		GracePeriod:     time.Duration(igcgp) * time.Second,
+	})
+
+	bg.every("collect orphaned images", time.Duration(igci)*time.Second, func(ctx context.Context) {
+		report, err := imageCollector.Collect(ctx, imageGCDryRun)
+		if err != nil {
+			log.Printf("failed to collect orphaned images: %v\n", err)
+			return
+		}
+
+		if report.DryRun {
+			for _, objName := range report.Deleted {
+				log.Printf("dry run: would delete orphaned image: %v\n", objName)
+			}
+		}
+
+		if len(report.Orphans) > 0 {
+			log.Printf("scanned %d images: %d orphaned, %d deleted, dry run: %v\n", report.Scanned, len(report.Orphans), len(report.Deleted), report.DryRun)
+		}
+	})
+
+	// webhook deliveries are sent every WEBHOOK_DELIVERY_INTERVAL, 5 seconds by default,
+	// waiting up to WEBHOOK_TIMEOUT, 10 seconds by default, for the receiver. Failed
+	// deliveries are retried up to WEBHOOK_MAX_ATTEMPTS times, 8 by default, waiting
+	// WEBHOOK_RETRY_BACKOFF, a minute by default, doubling with every attempt
+	wdi, err := envInt("WEBHOOK_DELIVERY_INTERVAL", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	wt, err := envInt("WEBHOOK_TIMEOUT", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	wma, err := envInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	wrb, err := envInt("WEBHOOK_RETRY_BACKOFF", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookService := service.NewWebhookService(&service.WSConfig{
+		WebhookRepository:         webhookRepository,
+		WebhookDeliveryRepository: webhookDeliveryRepository,
+		HTTPClient:                &http.Client{Timeout: time.Duration(wt) * time.Second},
+		MaxAttempts:               int(wma),
+		RetryBackoff:              time.Duration(wrb) * time.Second,
+	})
+
+	bg.every("deliver webhooks", time.Duration(wdi)*time.Second, func(ctx context.Context) {
+		if _, err := webhookService.Deliver(ctx); err != nil {
+			log.Printf("failed to deliver webhooks: %v\n", err)
+		}
+	})
+
+	// events written to the outbox are relayed to the broker every OUTBOX_RELAY_INTERVAL,
+	// 5 seconds by default, with failed messages retried up to OUTBOX_MAX_ATTEMPTS times,
+	// 10 by default, before being dead lettered. Retries wait OUTBOX_RETRY_BACKOFF,
+	// 30 seconds by default, doubling with every attempt
+	ori, err := envInt("OUTBOX_RELAY_INTERVAL", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	oma, err := envInt("OUTBOX_MAX_ATTEMPTS", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	orb, err := envInt("OUTBOX_RETRY_BACKOFF", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	// webhook deliveries are queued as events are relayed
+	relayBroker := repository.NewFanoutEventsBroker(eventsBroker, webhookService)
+
+	outboxRelay := service.NewOutboxRelay(&service.ORConfig{
+		OutboxRepository: outboxRepository,
+		EventsBroker:     relayBroker,
+		Transactor:       transactor,
+		MaxAttempts:      int(oma),
+		RetryBackoff:     time.Duration(orb) * time.Second,
+		ImageRepository:  imageRepository,
+		ImageURLTTL:      d.Images.URLTTL,
+		ImagePublicURL:   d.Images.PublicURL,
+		BaseURL:          os.Getenv("ACCOUNT_API_URL"),
+	})
+
+	bg.every("relay outbox", time.Duration(ori)*time.Second, func(ctx context.Context) {
+		if _, err := outboxRelay.Relay(ctx); err != nil {
+			log.Printf("failed to relay outbox: %v\n", err)
+		}
+	})
+
+	adminService := service.NewAdminService(&service.ASConfig{
+		UserRepository:        userRepository,
+		RoleRepository:        roleRepository,
+		TokenRepository:       tokenRepository,
+		ImageRepository:       imageRepository,
+		AdminActionRepository: adminActionRepository,
+		OutboxRepository:      outboxRepository,
+		Transactor:            transactor,
+		ImageURLTTL:           d.Images.URLTTL,
+		ImagePublicURL:        d.Images.PublicURL,
+		BaseURL:               os.Getenv("ACCOUNT_API_URL"),
+	})
+
+	// grant the admin role to ADMIN_BOOTSTRAP_EMAIL if nobody holds it yet
+	// the user must already have signed up, otherwise restart after signing up
+	if adminEmail := os.Getenv("ADMIN_BOOTSTRAP_EMAIL"); adminEmail != "" {
+		if err := adminService.BootstrapAdmin(context.Background(), adminEmail); err != nil {
+			log.Printf("unable to bootstrap admin for email: %v: %v\n", adminEmail, err)
+		}
+	}
+
+	// exports and their download links expire after EXPORT_TTL, a day by default
+	et, err := envInt("EXPORT_TTL", 24*60*60)
+	if err != nil {
+		return nil, err
+	}
+
+	// download links are signed with EXPORT_SECRET, and could be forged without it
+	exportSecret := os.Getenv("EXPORT_SECRET")
+	if exportSecret == "" {
+		return nil, fmt.Errorf("EXPORT_SECRET is required")
+	}
+
+	exportService := service.NewExportService(&service.ESConfig{
+		UserRepository:       userRepository,
+		TokenRepository:      tokenRepository,
+		ImageRepository:      imageRepository,
+		ExportRepository:     exportRepository,
+		AuditEventRepository: auditEventRepository,
+		Secret:               exportSecret,
+		BaseURL:              os.Getenv("ACCOUNT_API_URL"),
+		ExportTTL:            time.Duration(et) * time.Second,
+	})
+
+	// exports whose generation never finished, for example because the service
+	// stopped, are failed every EXPORT_SWEEP_INTERVAL, a minute by default
+	esi, err := envInt("EXPORT_SWEEP_INTERVAL", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	bg.every("sweep exports", time.Duration(esi)*time.Second, func(ctx context.Context) {
+		swept, err := exportService.SweepExports(ctx)
+		if err != nil {
+			log.Printf("failed to sweep exports: %v\n", err)
+			return
+		}
+
+		if swept > 0 {
+			log.Printf("failed %d exports which did not finish\n", swept)
+		}
+	})
+
+	organizationService := service.NewOrganizationService(&service.OSConfig{
+		OrganizationRepository: organizationRepository,
+		UserRepository:         userRepository,
+		Transactor:             transactor,
+	})
+
+	// invitations can be accepted for INVITATION_TTL seconds after they are sent, a week by default
+	ittl, err := envInt("INVITATION_TTL", 7*24*60*60)
+	if err != nil {
+		return nil, err
+	}
+
+	invitationService := service.NewInvitationService(&service.ISConfig{
+		InvitationRepository:   invitationRepository,
+		OrganizationRepository: organizationRepository,
+		UserRepository:         userRepository,
+		UserService:            userService,
+		Transactor:             transactor,
+		InvitationTTL:          time.Duration(ittl) * time.Second,
+	})
+
+	auditService := service.NewAuditService(&service.AuditConfig{
+		AuditEventRepository: auditEventRepository,
 	})
 
 	// load rsa keys
@@ -83,18 +468,60 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse REFRESH_TOKEN_EXP as int: %w", err)
 	}
 
+	// impersonation tokens live for IMPERSONATION_TOKEN_EXP, capped at and defaulting to 15 minutes
+	impExp, err := envInt("IMPERSONATION_TOKEN_EXP", 15*60)
+	if err != nil {
+		return nil, err
+	}
+
 	tokenService := service.NewTokenService(&service.TSConfig{
-		TokenRepository:       tokenRepository,
-		PrivKey:               privKey,
-		PubKey:                pubKey,
-		RefreshSecret:         refreshSecret,
-		IDExpirationSecs:      idExp,
-		RefreshExpirationSecs: refreshExp,
+		TokenRepository:             tokenRepository,
+		AuditEventRepository:        auditEventRepository,
+		RealmService:                realmService,
+		PrivKey:                     privKey,
+		PubKey:                      pubKey,
+		RefreshSecret:               refreshSecret,
+		IDExpirationSecs:            idExp,
+		RefreshExpirationSecs:       refreshExp,
+		ImpersonationExpirationSecs: impExp,
+		ImageRepository:             imageRepository,
+		ImageURLTTL:                 d.Images.URLTTL,
+		ImagePublicURL:              d.Images.PublicURL,
+		BaseURL:                     os.Getenv("ACCOUNT_API_URL"),
 	})
 
 	// initialize gin.Engine
 	router := gin.Default()
 
+	// images stored on disk are served by the router, which checks
+	// the signature of links to private images
+	if d.Images.Dir != "" {
+		u, err := url.Parse(d.Images.URL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse FS_IMAGE_URL: %w", err)
+		}
+
+		// the route would collide with the one serving images from any storage
+		if path.Clean(u.Path) == path.Join(os.Getenv("ACCOUNT_API_URL"), "images") {
+			return nil, fmt.Errorf("FS_IMAGE_URL must not be served at ACCOUNT_API_URL/images")
+		}
+
+		images := router.Group(u.Path)
+		if d.Images.URLTTL > 0 {
+			images.Use(middleware.SignedURL(func(objName string, expires int64, signature string) error {
+				return repository.VerifyFSImageURL(d.Images.Secret, objName, expires, signature)
+			}))
+		}
+
+		images.Static("/", d.Images.Dir)
+
+		// files are uploaded straight to the directory
+		// the same way they would be to cloud storage
+		if d.Images.Secret != "" {
+			router.PUT(path.Join(u.Path, ":obj"), storeFSUpload(d.Images))
+		}
+	}
+
 	baseUrl := os.Getenv("ACCOUNT_API_URL")
 
 	// read in HANDLER_TIMEOUT
@@ -104,20 +531,23 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse HANDLER_TIMEOUT as int: %w", err)
 	}
 
-	maxBodyBytes := os.Getenv("MAX_BODY_BYTES")
-	mbb, err := strconv.ParseInt(maxBodyBytes, 0, 64)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse MAX_BODY_BYTES as int: %w", err)
-	}
-
 	handler.NewHandler(&handler.Config{
-		R:               router,
-		UserService:     userService,
-		TokenService:    tokenService,
-		BaseURL:         baseUrl,
-		TimeoutDuration: time.Duration(time.Duration(ht) * time.Second),
-		MaxBodyBytes:    mbb,
+		R:                   router,
+		UserService:         userService,
+		TokenService:        tokenService,
+		AdminService:        adminService,
+		ExportService:       exportService,
+		AuditService:        auditService,
+		WebhookService:      webhookService,
+		OrganizationService: organizationService,
+		InvitationService:   invitationService,
+		RealmService:        realmService,
+		ImageCollector:      imageCollector,
+		BaseURL:             baseUrl,
+		TimeoutDuration:     time.Duration(time.Duration(ht) * time.Second),
+		MaxBodyBytes:        mbb,
 	})
 
-	return router, nil
+	// requests are resolved to their realm before being routed
+	return middleware.Realm(realmService, baseUrl, router), nil
 }
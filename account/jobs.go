@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// jobs runs background work on an interval for as long as the server is up
+type jobs struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newJobs() *jobs {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &jobs{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// every runs fn each interval until stop is called
+func (j *jobs) every(name string, interval time.Duration, fn func(ctx context.Context)) {
+	j.wg.Add(1)
+
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		log.Printf("Starting background job %s every %v\n", name, interval)
+
+		for {
+			select {
+			case <-j.ctx.Done():
+				log.Printf("Stopping background job %s\n", name)
+				return
+			case <-ticker.C:
+				fn(j.ctx)
+			}
+		}
+	}()
+}
+
+// stop cancels all jobs and waits for any which are running to return
+func (j *jobs) stop() {
+	j.cancel()
+	j.wg.Wait()
+}
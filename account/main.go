@@ -19,7 +19,10 @@ func main() {
 		log.Fatalf("unable to initialize data sources: %v\n", err)
 	}
 
-	router, err := inject(ds)
+	// background jobs are registered while injecting
+	bg := newJobs()
+
+	router, err := inject(ds, bg)
 	if err != nil {
 		log.Fatalf("failure to inject data sources: %v\n", err)
 	}
@@ -39,7 +42,7 @@ func main() {
 	log.Printf("Listening on port %v\n", server.Addr)
 
 	// Wait for kill signal of channel
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -51,6 +54,9 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// stop background jobs before their data sources go away
+	bg.stop()
+
 	// shutdown data sources
 	if err := ds.close(); err != nil {
 		log.Fatalf("a problem occured gracefully shuting down data sources: %v\n", err)
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS roles (
+  name VARCHAR PRIMARY KEY,
+  description VARCHAR NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+  name VARCHAR PRIMARY KEY,
+  description VARCHAR NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role VARCHAR NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  permission VARCHAR NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+  PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  uid uuid NOT NULL REFERENCES users(uid) ON DELETE CASCADE,
+  role VARCHAR NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  granted_by uuid REFERENCES users(uid) ON DELETE SET NULL,
+  granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (uid, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('admin', 'Full administrative access'),
+  ('support', 'Read access to user accounts for support staff');
+
+INSERT INTO permissions (name, description) VALUES
+  ('roles:read', 'List roles and their permissions'),
+  ('roles:write', 'Grant and revoke user roles'),
+  ('users:read', 'View any user account'),
+  ('users:write', 'Modify any user account');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'roles:read'),
+  ('admin', 'roles:write'),
+  ('admin', 'users:read'),
+  ('admin', 'users:write'),
+  ('support', 'roles:read'),
+  ('support', 'users:read');
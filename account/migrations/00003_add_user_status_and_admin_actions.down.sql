@@ -0,0 +1,2 @@
+DROP TABLE admin_actions;
+ALTER TABLE users DROP COLUMN status;
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN status VARCHAR NOT NULL DEFAULT 'active';
+
+CREATE TABLE IF NOT EXISTS admin_actions (
+  id BIGSERIAL PRIMARY KEY,
+  actor_uid uuid NOT NULL,
+  target_uid uuid NOT NULL,
+  action VARCHAR NOT NULL,
+  detail VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS admin_actions_target_uid_idx ON admin_actions (target_uid, created_at);
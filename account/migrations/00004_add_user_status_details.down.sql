@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN status_reason;
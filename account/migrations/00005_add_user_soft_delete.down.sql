@@ -0,0 +1,6 @@
+DROP INDEX users_purge_after_idx;
+DROP INDEX users_email_key;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN purge_after;
+ALTER TABLE users DROP COLUMN deleted_at;
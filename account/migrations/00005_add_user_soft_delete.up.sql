@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN purge_after TIMESTAMPTZ;
+
+-- soft deleted users keep their row until purged, so only
+-- enforce unique emails amongst users who have not been deleted
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS users_purge_after_idx ON users (purge_after) WHERE deleted_at IS NOT NULL;
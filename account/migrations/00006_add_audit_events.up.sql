@@ -0,0 +1,22 @@
+-- append only, rows are never updated and are kept after the user is deleted
+CREATE TABLE IF NOT EXISTS audit_events (
+  id BIGSERIAL PRIMARY KEY,
+  uid uuid,
+  actor_uid uuid,
+  event VARCHAR NOT NULL,
+  outcome VARCHAR NOT NULL,
+  ip VARCHAR NOT NULL DEFAULT '',
+  user_agent VARCHAR NOT NULL DEFAULT '',
+  detail VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_uid_idx ON audit_events (uid, id);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+INSERT INTO permissions (name, description) VALUES
+  ('audit:read', 'View the security audit log of any user');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'audit:read'),
+  ('support', 'audit:read');
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox (
+  id BIGSERIAL PRIMARY KEY,
+  event_id uuid NOT NULL UNIQUE,
+  uid uuid NOT NULL,
+  event_type VARCHAR NOT NULL,
+  payload JSONB NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error VARCHAR NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  published_at TIMESTAMPTZ,
+  dead_lettered_at TIMESTAMPTZ
+);
+
+-- the relay only ever looks at messages which are still pending
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (uid, id) WHERE published_at IS NULL AND dead_lettered_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_published_at_idx ON outbox (published_at) WHERE published_at IS NOT NULL;
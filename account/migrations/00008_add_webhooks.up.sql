@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+  id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  url VARCHAR NOT NULL,
+  events VARCHAR[] NOT NULL DEFAULT '{}',
+  secret VARCHAR NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  webhook_id uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  event_id uuid NOT NULL,
+  event_type VARCHAR NOT NULL,
+  payload JSONB NOT NULL,
+  status VARCHAR NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  response_status INT NOT NULL DEFAULT 0,
+  last_error VARCHAR NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  delivered_at TIMESTAMPTZ,
+  -- events can be relayed more than once, but are only delivered once per webhook
+  UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+
+INSERT INTO permissions (name, description) VALUES
+  ('webhooks:manage', 'Manage webhook subscriptions and their deliveries');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'webhooks:manage');
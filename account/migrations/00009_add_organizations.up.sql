@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS organizations (
+  id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  name VARCHAR NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+  org_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  uid uuid NOT NULL REFERENCES users(uid) ON DELETE CASCADE,
+  role VARCHAR NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+  joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (org_id, uid)
+);
+
+CREATE INDEX IF NOT EXISTS org_members_uid_idx ON org_members (uid);
@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS invitations (
+  id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  org_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email VARCHAR NOT NULL,
+  role VARCHAR NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+  invited_by uuid REFERENCES users(uid) ON DELETE SET NULL,
+  -- only a hash of the token is stored, the token itself is sent to the invitee
+  token_hash VARCHAR NOT NULL UNIQUE,
+  status VARCHAR NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked')),
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  accepted_by uuid REFERENCES users(uid) ON DELETE SET NULL,
+  accepted_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS invitations_invited_by_idx ON invitations (invited_by);
+CREATE INDEX IF NOT EXISTS invitations_email_idx ON invitations (lower(email)) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+DROP INDEX users_realm_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email) WHERE deleted_at IS NULL;
+
+ALTER TABLE organizations DROP COLUMN realm_id;
+ALTER TABLE users DROP COLUMN realm_id;
+DROP TABLE realms;
@@ -0,0 +1,23 @@
+-- keys, secrets and token lifetimes left empty fall back to the deployment's
+CREATE TABLE IF NOT EXISTS realms (
+  id VARCHAR PRIMARY KEY,
+  name VARCHAR NOT NULL,
+  hosts VARCHAR[] NOT NULL DEFAULT '{}',
+  private_key VARCHAR NOT NULL DEFAULT '',
+  public_key VARCHAR NOT NULL DEFAULT '',
+  refresh_secret VARCHAR NOT NULL DEFAULT '',
+  id_token_exp BIGINT NOT NULL DEFAULT 0,
+  refresh_token_exp BIGINT NOT NULL DEFAULT 0,
+  branding JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO realms (id, name) VALUES ('default', 'Default');
+
+-- existing users and organizations belong to the default realm
+ALTER TABLE users ADD COLUMN realm_id VARCHAR NOT NULL DEFAULT 'default' REFERENCES realms(id);
+ALTER TABLE organizations ADD COLUMN realm_id VARCHAR NOT NULL DEFAULT 'default' REFERENCES realms(id);
+
+-- emails are only unique within a realm
+DROP INDEX users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_realm_email_key ON users (realm_id, email) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+INSERT INTO permissions (name, description) VALUES
+  ('users:impersonate', 'Sign in as any user without roles for a short time');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'users:impersonate');
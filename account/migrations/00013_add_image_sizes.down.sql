@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN image_url VARCHAR NOT NULL DEFAULT '';
+
+UPDATE users SET image_url = image_urls->>'512' WHERE image_urls ? '512';
+
+ALTER TABLE users DROP COLUMN image_urls;
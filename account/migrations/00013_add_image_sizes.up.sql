@@ -0,0 +1,9 @@
+-- profile images are stored in several sizes, keyed by their width
+ALTER TABLE users ADD COLUMN image_urls JSONB NOT NULL DEFAULT '{}';
+
+-- images uploaded before sizes were introduced serve every size until replaced
+UPDATE users
+SET image_urls = jsonb_build_object('64', image_url, '128', image_url, '512', image_url)
+WHERE image_url <> '';
+
+ALTER TABLE users DROP COLUMN image_url;
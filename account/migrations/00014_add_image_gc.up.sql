@@ -0,0 +1,5 @@
+INSERT INTO permissions (name, description) VALUES
+  ('images:manage', 'Review stored images and those no user refers to');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'images:manage');
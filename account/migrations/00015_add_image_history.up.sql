@@ -0,0 +1,2 @@
+-- previous profile images, from the most recently replaced
+ALTER TABLE users ADD COLUMN image_history JSONB NOT NULL DEFAULT '[]';
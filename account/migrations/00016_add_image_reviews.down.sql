@@ -0,0 +1 @@
+DROP TABLE image_reviews;
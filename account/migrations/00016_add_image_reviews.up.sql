@@ -0,0 +1,13 @@
+-- profile images held back from users until they are approved
+CREATE TABLE IF NOT EXISTS image_reviews (
+  id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  uid uuid NOT NULL REFERENCES users(uid) ON DELETE CASCADE,
+  image_urls JSONB NOT NULL,
+  status VARCHAR NOT NULL CHECK (status IN ('pending', 'quarantined', 'approved', 'rejected')),
+  reason VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  reviewed_by uuid REFERENCES users(uid) ON DELETE SET NULL,
+  reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS image_reviews_open_idx ON image_reviews (created_at) WHERE status IN ('pending', 'quarantined');
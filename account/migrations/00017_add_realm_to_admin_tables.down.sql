@@ -0,0 +1,3 @@
+ALTER TABLE audit_events DROP COLUMN realm_id;
+ALTER TABLE admin_actions DROP COLUMN realm_id;
+ALTER TABLE webhooks DROP COLUMN realm_id;
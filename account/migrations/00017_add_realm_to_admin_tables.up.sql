@@ -0,0 +1,11 @@
+-- webhooks and the logs admins read belong to a realm, like the users they are about
+ALTER TABLE webhooks ADD COLUMN realm_id VARCHAR NOT NULL DEFAULT 'default' REFERENCES realms(id);
+ALTER TABLE admin_actions ADD COLUMN realm_id VARCHAR NOT NULL DEFAULT 'default' REFERENCES realms(id);
+ALTER TABLE audit_events ADD COLUMN realm_id VARCHAR NOT NULL DEFAULT 'default' REFERENCES realms(id);
+
+-- users keep the audit events recorded before realms were tracked here
+UPDATE audit_events a SET realm_id = u.realm_id FROM users u WHERE u.uid = a.uid;
+UPDATE admin_actions a SET realm_id = u.realm_id FROM users u WHERE u.uid = a.target_uid;
+
+CREATE INDEX IF NOT EXISTS audit_events_realm_id_idx ON audit_events (realm_id, id);
+CREATE INDEX IF NOT EXISTS webhooks_realm_id_idx ON webhooks (realm_id);
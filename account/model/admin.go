@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded when an admin modifies a user
+const (
+	AdminActionAssignRole    = "assign_role"
+	AdminActionRevokeRole    = "revoke_role"
+	AdminActionUpdateUser    = "update_user"
+	AdminActionResetPassword = "reset_password"
+	AdminActionForceSignout  = "force_signout"
+	AdminActionSuspendUser   = "suspend_user"
+	AdminActionDisableUser   = "disable_user"
+	AdminActionEnableUser    = "enable_user"
+	AdminActionDeleteUser    = "delete_user"
+)
+
+// AdminAction attributes a change to a user account to the admin who made it
+// Actions are kept even after the target user is deleted
+type AdminAction struct {
+	ID        int64     `db:"id" json:"id"`
+	ActorUID  uuid.UUID `db:"actor_uid" json:"actorUid"`
+	TargetUID uuid.UUID `db:"target_uid" json:"targetUid"`
+	Action    string    `db:"action" json:"action"`
+	Detail    string    `db:"detail" json:"detail"`
+	RealmID   string    `db:"realm_id" json:"realm"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// UserPage is a single page of user search results. NextCursor
+// is empty when there are no further results
+type UserPage struct {
+	Users      []*User `json:"users"`
+	NextCursor string  `json:"nextCursor"`
+}
@@ -11,9 +11,11 @@ type Type string
 
 // "Set" of valid errorTypes
 const (
+	AccountInactive      Type = "ACCOUNT_INACTIVE"      // Valid credentials, but the account is suspended or disabled - 403
 	Authorization        Type = "AUTHORIZATION"         // Authentication Failures -
 	BadRequest           Type = "BAD_REQUEST"           // Validation errors / BadInput
 	Conflict             Type = "CONFLICT"              // Already exists (eg, create account with existent email) - 409
+	Forbidden            Type = "FORBIDDEN"             // Authenticated but lacking the required role or permission - 403
 	Internal             Type = "INTERNAL"              // Server (500) and fallback errors
 	NotFound             Type = "NOT_FOUND"             // For not finding resource
 	PayloadTooLarge      Type = "PAYLOAD_TOO_LARGE"     // for uploading tons of JSON, or an image over the limit - 413
@@ -41,12 +43,16 @@ func (e *Error) Error() string {
 // our errors already map http status codes
 func (e *Error) Status() int {
 	switch e.Type {
+	case AccountInactive:
+		return http.StatusForbidden
 	case Authorization:
 		return http.StatusUnauthorized
 	case BadRequest:
 		return http.StatusBadRequest
 	case Conflict:
 		return http.StatusConflict
+	case Forbidden:
+		return http.StatusForbidden
 	case Internal:
 		return http.StatusInternalServerError
 	case NotFound:
@@ -77,6 +83,14 @@ func Status(err error) int {
 * Error "Factories"
  */
 
+// NewAccountInactive to create a 403 for users who may not sign in
+func NewAccountInactive(reason string) *Error {
+	return &Error{
+		Type:    AccountInactive,
+		Message: reason,
+	}
+}
+
 // NewAuthorization to create a 401
 func NewAuthorization(reason string) *Error {
 	return &Error{
@@ -101,6 +115,14 @@ func NewConflict(name string, value string) *Error {
 	}
 }
 
+// NewForbidden to create an error for 403
+func NewForbidden(reason string) *Error {
+	return &Error{
+		Type:    Forbidden,
+		Message: reason,
+	}
+}
+
 // NewInternal for 500 errors and unknown errors
 func NewInternal() *Error {
 	return &Error{
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events recorded in the security audit log
+const (
+	AuditEventSignup        = "signup"
+	AuditEventSignin        = "signin"
+	AuditEventRefresh       = "refresh"
+	AuditEventSignout       = "signout"
+	AuditEventUpdateDetails = "update_details"
+	AuditEventUpdateImage   = "update_image"
+	AuditEventDeleteImage   = "delete_image"
+	AuditEventImpersonate   = "impersonate"
+	AuditEventReviewImage   = "review_image"
+)
+
+// Outcomes of an audited event
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent records the outcome of a security relevant action on an account
+// UID is nil when the account could not be identified, such as a signin
+// with an unknown email, and ActorUID is whoever performed the action
+type AuditEvent struct {
+	ID        int64      `db:"id" json:"id"`
+	UID       *uuid.UUID `db:"uid" json:"uid"`
+	ActorUID  *uuid.UUID `db:"actor_uid" json:"actorUid"`
+	Event     string     `db:"event" json:"event"`
+	Outcome   string     `db:"outcome" json:"outcome"`
+	IP        string     `db:"ip" json:"ip"`
+	UserAgent string     `db:"user_agent" json:"userAgent"`
+	Detail    string     `db:"detail" json:"detail"`
+	RealmID   string     `db:"realm_id" json:"realm"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// AuditFilter narrows down a query of the audit log. Zero values match
+// every event. Before only matches events older than the event with that id
+type AuditFilter struct {
+	UID      *uuid.UUID
+	ActorUID *uuid.UUID
+	Event    string
+	Outcome  string
+	IP       string
+	Since    *time.Time
+	Until    *time.Time
+	Before   int64
+	Limit    int
+}
+
+// AuditPage is a single page of audit events, newest first. NextCursor
+// is empty when there are no further results
+type AuditPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"nextCursor"`
+}
+
+// ClientInfo describes the client a request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo returns a copy of ctx carrying the client of the current request
+func WithClientInfo(ctx context.Context, ci ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, ci)
+}
+
+// ClientInfoFromContext returns the client set by WithClientInfo, if any
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	ci, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return ci
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of user lifecycle events published to other services
+const (
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+	EventImageChanged = "user.image_changed"
+	EventEmailChanged = "user.email_changed"
+)
+
+// UserEvent notifies other services of a change to a user so they can
+// keep their copies of user data fresh. User holds the state of the user
+// after the change and is nil for deletions. Its image URLs are the ones
+// clients are given: the default avatar when there is no image, and links
+// signed for IMAGE_URL_TTL when image storage is private, which consumers
+// should fetch from rather than keep. Consumers should use ID to ignore
+// events they have already handled. Events are only sent to the webhooks
+// of the user's realm
+type UserEvent struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	UID        uuid.UUID `json:"uid"`
+	RealmID    string    `json:"realm"`
+	User       *User     `json:"user,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// NewUserEvent creates an event of the given type about u
+func NewUserEvent(eventType string, u *User) *UserEvent {
+	e := &UserEvent{
+		ID:         uuid.New(),
+		Type:       eventType,
+		UID:        u.UID,
+		RealmID:    u.RealmID,
+		OccurredAt: time.Now(),
+	}
+
+	if e.RealmID == "" {
+		e.RealmID = DefaultRealmID
+	}
+
+	if eventType != EventUserDeleted {
+		e.User = u
+	}
+
+	return e
+}
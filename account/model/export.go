@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Formats a personal data export can be packaged as
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZIP  = "zip"
+)
+
+// Statuses of a personal data export as it is generated
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// DataExport tracks the asynchronous generation of a user's personal data export
+// DownloadURL is only set once the export is ready and expires along with it
+type DataExport struct {
+	ID          uuid.UUID `json:"id"`
+	UID         uuid.UUID `json:"uid"`
+	Format      string    `json:"format"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	DownloadURL string    `json:"downloadUrl,omitempty"`
+}
+
+// Session is an unexpired refresh token held by a user
+type Session struct {
+	TokenID   string    `json:"tokenId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ExportedImage describes a profile image included in an export
+// File is the path of the image within a zip export
+type ExportedImage struct {
+	URL        string `json:"url"`
+	ObjectName string `json:"objectName"`
+	File       string `json:"file,omitempty"`
+}
+
+// PersonalData is everything held about a user, as written to an export
+type PersonalData struct {
+	ExportedAt  time.Time        `json:"exportedAt"`
+	User        *User            `json:"user"`
+	Roles       []string         `json:"roles"`
+	Sessions    []*Session       `json:"sessions"`
+	Images      []*ExportedImage `json:"images"`
+	AuditEvents []*AuditEvent    `json:"auditEvents"`
+}
@@ -38,6 +38,29 @@ func NewMultipartImage(fileName string, contentType string) *MultipartImage {
 
 	defer f.Close()
 
+	body, formContentType := newMultipartBody(fileName, contentType, f)
+
+	return &MultipartImage{
+		imagePath:     imagePath,
+		ImageFile:     f,
+		MultipartBody: body,
+		ContentType:   formContentType,
+	}
+}
+
+// NewMultipartFile creates a Multipart Form with an imageFile
+// holding content, for uploads which are not a real image
+func NewMultipartFile(fileName string, contentType string, content []byte) *MultipartImage {
+	body, formContentType := newMultipartBody(fileName, contentType, bytes.NewReader(content))
+
+	return &MultipartImage{
+		MultipartBody: body,
+		ContentType:   formContentType,
+	}
+}
+
+// newMultipartBody writes content as a form's imageFile part
+func newMultipartBody(fileName string, contentType string, content io.Reader) (*bytes.Buffer, string) {
 	// create a multipart write onto which we
 	// will write the image file
 	body := &bytes.Buffer{}
@@ -53,15 +76,10 @@ func NewMultipartImage(fileName string, contentType string) *MultipartImage {
 	h.Set("Content-Type", contentType)
 	part, _ := writer.CreatePart(h)
 
-	io.Copy(part, f)
+	io.Copy(part, content)
 	writer.Close()
 
-	return &MultipartImage{
-		imagePath:     imagePath,
-		ImageFile:     f,
-		MultipartBody: body,
-		ContentType:   writer.FormDataContentType(),
-	}
+	return body, writer.FormDataContentType()
 }
 
 // GetFormFile extracts form file from multipart body
@@ -76,6 +94,10 @@ func (m *MultipartImage) GetFormFile() *multipart.FileHeader {
 
 // Close removes created file for test
 func (m *MultipartImage) Close() {
+	if m.ImageFile == nil {
+		return
+	}
+
 	m.ImageFile.Close()
 	os.Remove(m.imagePath)
 }
@@ -89,5 +111,8 @@ func createImage(imagePath string) *os.File {
 	f, _ := os.Create(imagePath)
 	png.Encode(f, img)
 
+	// rewind so the image can be copied into the multipart body
+	f.Seek(0, io.SeekStart)
+
 	return f
 }
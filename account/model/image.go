@@ -0,0 +1,132 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProfileImageSizes are the widths in pixels of the square versions
+// each profile image is stored in, from smallest to largest
+var ProfileImageSizes = []int{64, 128, 512}
+
+// ImageURLs maps the sizes of a profile image to the URL of that version.
+// It is stored as a JSON object and is empty when the user has no image.
+// With private image storage only the object keys are stored, and the
+// services swap them for signed URLs when a profile is read
+type ImageURLs map[int]string
+
+// Value encodes the URLs as JSON for the database
+func (m ImageURLs) Value() (driver.Value, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(m)
+}
+
+// Scan decodes the URLs from the JSON stored in the database
+func (m *ImageURLs) Scan(src interface{}) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*m = ImageURLs{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ImageURLs", src)
+	}
+
+	urls := ImageURLs{}
+	if err := json.Unmarshal(data, &urls); err != nil {
+		return err
+	}
+
+	*m = urls
+
+	return nil
+}
+
+// ImageVersion is a previous profile image of a user, kept so they can
+// revert to it. ReplacedAt is when it stopped being their profile image
+type ImageVersion struct {
+	ID         uuid.UUID `json:"id"`
+	ImageURLs  ImageURLs `json:"imageUrls"`
+	ReplacedAt time.Time `json:"replacedAt"`
+}
+
+// ImageHistory lists the previous profile images of a user from the most
+// recently replaced. It is stored as a JSON array, holding URLs or object
+// keys the same way as ImageURLs
+type ImageHistory []*ImageVersion
+
+// Value encodes the history as JSON for the database
+func (h ImageHistory) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(h)
+}
+
+// Scan decodes the history from the JSON stored in the database
+func (h *ImageHistory) Scan(src interface{}) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*h = ImageHistory{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ImageHistory", src)
+	}
+
+	history := ImageHistory{}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return err
+	}
+
+	*h = history
+
+	return nil
+}
+
+// ImageUpload is a pre-signed request with which a client sends an image
+// straight to storage, before committing it as their profile image.
+// The request must be made with Method and every one of Headers
+type ImageUpload struct {
+	ObjectName string            `json:"objectName"`
+	URL        string            `json:"url"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers"`
+	ExpiresAt  time.Time         `json:"expiresAt"`
+}
+
+// StoredImage is an object held in image storage
+type StoredImage struct {
+	ObjectName string    `json:"objectName"`
+	Size       int64     `json:"size"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ImageGCReport describes a pass of the collector of images no user refers to.
+// Orphans are every unreferenced image, and Deleted the names of those old
+// enough to be deleted. Nothing is actually deleted in a dry run
+type ImageGCReport struct {
+	Scanned    int            `json:"scanned"`
+	Referenced int            `json:"referenced"`
+	Orphans    []*StoredImage `json:"orphans"`
+	Deleted    []string       `json:"deleted"`
+	DryRun     bool           `json:"dryRun"`
+}
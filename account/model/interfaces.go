@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
 	"time"
 
@@ -16,17 +17,121 @@ SERVICES get request from the handler, it it needs a datasource, it sends it to
 // service it interact with to implement
 type UserService interface {
 	Get(ctx context.Context, uid uuid.UUID) (*User, error)
+	Profile(ctx context.Context, uid uuid.UUID) (*User, error)
 	Signup(ctx context.Context, u *User) error
 	Signin(ctx context.Context, u *User) error
 	UpdateDetails(ctx context.Context, u *User) error
 	SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*User, error)
+	NewImageUpload(ctx context.Context, uid uuid.UUID, contentType string, size int64) (*ImageUpload, error)
+	CommitImageUpload(ctx context.Context, uid uuid.UUID, objName string) (*User, error)
+	DefaultAvatar(ctx context.Context, uid uuid.UUID, size int) ([]byte, error)
+	OpenImage(ctx context.Context, objName string) (io.ReadCloser, error)
+	ImageHistory(ctx context.Context, uid uuid.UUID) (ImageHistory, error)
+	RevertProfileImage(ctx context.Context, uid uuid.UUID, versionID uuid.UUID) (*User, error)
+	ImageReviews(ctx context.Context, status string, limit int) ([]*ImageReview, error)
+	ReviewImage(ctx context.Context, actor *User, id uuid.UUID, approve bool, reason string) (*ImageReview, error)
+	SweepImageReviews(ctx context.Context) (int, error)
 	ClearProfileImage(ctx context.Context, uid uuid.UUID) error
+	Delete(ctx context.Context, uid uuid.UUID, password string) error
+	PurgeDeleted(ctx context.Context) (int, error)
+}
+
+// AdminService defines methods the handler layer expects
+// for administrative operations on users and their roles
+type AdminService interface {
+	ListRoles(ctx context.Context) ([]*Role, error)
+	AssignRole(ctx context.Context, actor *User, uid uuid.UUID, role string) error
+	RevokeRole(ctx context.Context, actor *User, uid uuid.UUID, role string) error
+	BootstrapAdmin(ctx context.Context, email string) error
+	SearchUsers(ctx context.Context, query string, cursor string, limit int) (*UserPage, error)
+	GetUser(ctx context.Context, uid uuid.UUID) (*User, error)
+	UpdateUser(ctx context.Context, actor *User, u *User, emailVerified *bool) error
+	ResetPassword(ctx context.Context, actor *User, uid uuid.UUID, password string) error
+	ForceSignout(ctx context.Context, actor *User, uid uuid.UUID) error
+	SuspendUser(ctx context.Context, actor *User, uid uuid.UUID, reason string, until *time.Time) error
+	DisableUser(ctx context.Context, actor *User, uid uuid.UUID, reason string) error
+	EnableUser(ctx context.Context, actor *User, uid uuid.UUID) error
+	DeleteUser(ctx context.Context, actor *User, uid uuid.UUID) error
+}
+
+// ExportService defines methods the handler layer expects
+// for producing personal data exports
+type ExportService interface {
+	RequestExport(ctx context.Context, uid uuid.UUID, format string) (*DataExport, error)
+	GetExport(ctx context.Context, uid uuid.UUID, id uuid.UUID) (*DataExport, error)
+	Download(ctx context.Context, id uuid.UUID, expires int64, signature string) (*DataExport, []byte, error)
+	SweepExports(ctx context.Context) (int, error)
+}
+
+// AuditService defines methods the handler layer expects
+// for querying the security audit log
+type AuditService interface {
+	ListEvents(ctx context.Context, f *AuditFilter, cursor string) (*AuditPage, error)
+}
+
+// ImageCollector defines methods for deleting
+// images which are no longer referred to by any user
+type ImageCollector interface {
+	Collect(ctx context.Context, dryRun bool) (*ImageGCReport, error)
+}
+
+// OutboxRelay defines methods for publishing the
+// events written to the outbox
+type OutboxRelay interface {
+	Relay(ctx context.Context) (int, error)
+}
+
+// WebhookService defines methods the handler layer expects for managing
+// webhooks, along with the methods used to queue and send their deliveries
+type WebhookService interface {
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	CreateWebhook(ctx context.Context, w *Webhook) error
+	UpdateWebhook(ctx context.Context, w *Webhook) error
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, status string, cursor string, limit int) (*WebhookDeliveryPage, error)
+	ReplayDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (*WebhookDelivery, error)
+	Publish(ctx context.Context, e *UserEvent) error
+	Deliver(ctx context.Context) (int, error)
+}
+
+// OrganizationService defines methods the handler layer expects for
+// managing organizations and their members on behalf of the acting user
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, actor *User, name string) (*Organization, error)
+	ListOrganizations(ctx context.Context, actor *User) ([]*Organization, error)
+	GetOrganization(ctx context.Context, actor *User, id uuid.UUID) (*Organization, error)
+	UpdateOrganization(ctx context.Context, actor *User, id uuid.UUID, name string) (*Organization, error)
+	DeleteOrganization(ctx context.Context, actor *User, id uuid.UUID) error
+	ListMembers(ctx context.Context, actor *User, orgID uuid.UUID) ([]*OrgMember, error)
+	AddMember(ctx context.Context, actor *User, orgID uuid.UUID, email string, role string) (*OrgMember, error)
+	UpdateMember(ctx context.Context, actor *User, orgID uuid.UUID, uid uuid.UUID, role string) (*OrgMember, error)
+	RemoveMember(ctx context.Context, actor *User, orgID uuid.UUID, uid uuid.UUID) error
+}
+
+// InvitationService defines methods the handler layer expects for inviting
+// people into organizations, whether or not they already have an account
+type InvitationService interface {
+	Invite(ctx context.Context, actor *User, orgID uuid.UUID, email string, role string) (*Invitation, string, error)
+	ListSent(ctx context.Context, actor *User) ([]*Invitation, error)
+	ListReceived(ctx context.Context, actor *User) ([]*Invitation, error)
+	Revoke(ctx context.Context, actor *User, id uuid.UUID) error
+	Accept(ctx context.Context, actor *User, token string) (*OrgMember, error)
+	Signup(ctx context.Context, u *User, token string) error
+}
+
+// RealmService defines methods used to resolve requests to realms and to
+// look up their settings. Realms are held in memory and reloaded periodically
+type RealmService interface {
+	Get(id string) (*Realm, error)
+	ResolveHost(host string) (*Realm, bool)
+	Reload(ctx context.Context) error
 }
 
 // TokenService defines methods the handler layer expect to interact with
 // in regards to producing jwt as string
 type TokenService interface {
 	NewPairFromUser(ctx context.Context, u *User, prevTokenID string) (*TokenPair, error)
+	NewImpersonationToken(ctx context.Context, actor *User, u *User) (*ImpersonationToken, error)
 	Signout(ctx context.Context, uid uuid.UUID) error
 	ValidateIDToken(tokenString string) (*User, error)
 	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)
@@ -42,7 +147,18 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	Create(ctx context.Context, u *User) error
 	Update(ctx context.Context, u *User) error
-	UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*User, error)
+	UpdateImage(ctx context.Context, uid uuid.UUID, imageURLs ImageURLs) (*User, error)
+	UpdateImageHistory(ctx context.Context, uid uuid.UUID, history ImageHistory) error
+	UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error
+	UpdateStatus(ctx context.Context, uid uuid.UUID, status string, reason string, until *time.Time) error
+	Search(ctx context.Context, query string, after uuid.UUID, limit int) ([]*User, error)
+	Delete(ctx context.Context, uid uuid.UUID) error
+	SoftDelete(ctx context.Context, uid uuid.UUID, purgeAfter time.Time) error
+	FindPurgeable(ctx context.Context, before time.Time, limit int) ([]*User, error)
+	ListImageURLs(ctx context.Context) ([]string, error)
+	// HasImage reports whether a user's profile image or one of their
+	// previous images is stored as objName
+	HasImage(ctx context.Context, objName string) (bool, error)
 }
 
 // TokenRepository defines methods that it expects a repository it
@@ -51,6 +167,116 @@ type TokenRepository interface {
 	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
 	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error
 	DeleteUserRefreshTokens(ctx context.Context, userID string) error
+	ListUserRefreshTokens(ctx context.Context, userID string) ([]*Session, error)
+}
+
+// ExportRepository defines methods the service layer expects
+// for storing personal data exports until they expire
+type ExportRepository interface {
+	Save(ctx context.Context, e *DataExport) error
+	Find(ctx context.Context, id uuid.UUID) (*DataExport, error)
+	SaveArchive(ctx context.Context, id uuid.UUID, archive []byte, expiresIn time.Duration) error
+	FindArchive(ctx context.Context, id uuid.UUID) ([]byte, error)
+	FindStalePending(ctx context.Context, before time.Time, limit int) ([]*DataExport, error)
+}
+
+// RoleRepository defines methods the service layer expects
+// for reading roles and granting them to users
+type RoleRepository interface {
+	List(ctx context.Context) ([]*Role, error)
+	Assign(ctx context.Context, uid uuid.UUID, role string, grantedBy *uuid.UUID) error
+	Revoke(ctx context.Context, uid uuid.UUID, role string) error
+	CountUsers(ctx context.Context, role string) (int, error)
+}
+
+// AdminActionRepository defines methods the service layer expects
+// for attributing account changes to the acting admin
+type AdminActionRepository interface {
+	Create(ctx context.Context, a *AdminAction) error
+}
+
+// AuditEventRepository defines methods the service layer expects
+// for appending to and querying the security audit log
+type AuditEventRepository interface {
+	Create(ctx context.Context, e *AuditEvent) error
+	List(ctx context.Context, f *AuditFilter) ([]*AuditEvent, error)
+}
+
+// Transactor defines methods the service layer expects for making
+// changes through several repositories atomically
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxRepository defines methods the service layer expects for
+// writing events alongside the changes they describe and relaying them
+type OutboxRepository interface {
+	Add(ctx context.Context, e *UserEvent) error
+	FetchPending(ctx context.Context, limit int) ([]*OutboxMessage, error)
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, reason string, nextAttemptAt time.Time) error
+	DeadLetter(ctx context.Context, id int64, reason string) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventsBroker defines methods the service layer expects
+// for publishing user events to other services
+type EventsBroker interface {
+	Publish(ctx context.Context, e *UserEvent) error
+}
+
+// OrganizationRepository defines methods the service layer expects
+// for storing organizations and their members
+type OrganizationRepository interface {
+	Create(ctx context.Context, o *Organization) error
+	Update(ctx context.Context, o *Organization) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	FindByID(ctx context.Context, id uuid.UUID) (*Organization, error)
+	ListByUser(ctx context.Context, uid uuid.UUID) ([]*Organization, error)
+	FindMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*OrgMember, error)
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]*OrgMember, error)
+	AddMember(ctx context.Context, m *OrgMember) error
+	UpdateMember(ctx context.Context, m *OrgMember) error
+	RemoveMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) error
+	CountOwners(ctx context.Context, orgID uuid.UUID) (int, error)
+}
+
+// InvitationRepository defines methods the service layer expects
+// for storing invitations
+type InvitationRepository interface {
+	Create(ctx context.Context, inv *Invitation) error
+	Update(ctx context.Context, inv *Invitation) error
+	FindByID(ctx context.Context, id uuid.UUID) (*Invitation, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (*Invitation, error)
+	ListByInviter(ctx context.Context, uid uuid.UUID) ([]*Invitation, error)
+	ListByEmail(ctx context.Context, email string) ([]*Invitation, error)
+}
+
+// RealmRepository defines methods the service layer expects
+// for loading realms
+type RealmRepository interface {
+	List(ctx context.Context) ([]*Realm, error)
+}
+
+// WebhookRepository defines methods the service layer expects
+// for storing webhook subscriptions
+type WebhookRepository interface {
+	Create(ctx context.Context, w *Webhook) error
+	Update(ctx context.Context, w *Webhook) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	FindByID(ctx context.Context, id uuid.UUID) (*Webhook, error)
+	List(ctx context.Context) ([]*Webhook, error)
+	ListSubscribed(ctx context.Context, realmID string, eventType string) ([]*Webhook, error)
+}
+
+// WebhookDeliveryRepository defines methods the service layer expects
+// for queueing webhook deliveries and keeping their log
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, d *WebhookDelivery) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	Update(ctx context.Context, d *WebhookDelivery) error
+	FindByID(ctx context.Context, id int64) (*WebhookDelivery, error)
+	List(ctx context.Context, webhookID uuid.UUID, status string, before int64, limit int) ([]*WebhookDelivery, error)
 }
 
 // ImageRepository defines methods it expects a repository it
@@ -58,4 +284,30 @@ type TokenRepository interface {
 type ImageRepository interface {
 	UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error)
 	DeleteProfile(ctx context.Context, objName string) error
+	GetProfile(ctx context.Context, objName string) (io.ReadCloser, error)
+	// SignURL returns a URL granting read access to a private object until ttl passes
+	SignURL(ctx context.Context, objName string, ttl time.Duration) (string, error)
+	// SignUpload returns a request which stores an object of contentType that
+	// is at most size bytes, without going through this service, until ttl passes
+	SignUpload(ctx context.Context, objName string, contentType string, size int64, ttl time.Duration) (*ImageUpload, error)
+	// List returns every object in storage
+	List(ctx context.Context) ([]*StoredImage, error)
+}
+
+// ImageModerator decides whether an uploaded profile image may be shown.
+// data is the image as it was uploaded, before it is processed
+type ImageModerator interface {
+	Moderate(ctx context.Context, uid uuid.UUID, data []byte) (*ModerationResult, error)
+}
+
+// ImageReviewRepository defines methods the service layer expects
+// for holding profile images back until they are approved
+type ImageReviewRepository interface {
+	Create(ctx context.Context, r *ImageReview) error
+	Update(ctx context.Context, r *ImageReview) error
+	FindByID(ctx context.Context, id uuid.UUID) (*ImageReview, error)
+	List(ctx context.Context, status string, limit int) ([]*ImageReview, error)
+	FindStalePending(ctx context.Context, before time.Time, limit int) ([]*ImageReview, error)
+	// HoldsImage reports whether an open review holds an image stored as objName
+	HoldsImage(ctx context.Context, objName string) (bool, error)
 }
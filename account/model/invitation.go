@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of an invitation. Pending invitations whose
+// expiry has passed are listed with the expired status
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation invites an email address to join an organization with a role.
+// It is accepted with a token sent to the invitee, of which only a hash is kept
+type Invitation struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	OrgID      uuid.UUID  `db:"org_id" json:"orgId"`
+	OrgName    string     `db:"org_name" json:"orgName"`
+	Email      string     `db:"email" json:"email"`
+	Role       string     `db:"role" json:"role"`
+	InvitedBy  *uuid.UUID `db:"invited_by" json:"invitedBy"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Status     string     `db:"status" json:"status"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expiresAt"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	AcceptedBy *uuid.UUID `db:"accepted_by" json:"acceptedBy"`
+	AcceptedAt *time.Time `db:"accepted_at" json:"acceptedAt"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revokedAt"`
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAdminActionRepository is a mock type for model.AdminActionRepository
+type MockAdminActionRepository struct {
+	mock.Mock
+}
+
+// Create is mock of AdminActionRepository Create
+func (m *MockAdminActionRepository) Create(ctx context.Context, a *model.AdminAction) error {
+	ret := m.Called(ctx, a)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
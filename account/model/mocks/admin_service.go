@@ -0,0 +1,186 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAdminService is a mock type for model.AdminService
+type MockAdminService struct {
+	mock.Mock
+}
+
+// ListRoles is a mock of AdminService ListRoles
+func (m *MockAdminService) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Role
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Role)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// AssignRole is a mock of AdminService AssignRole
+func (m *MockAdminService) AssignRole(ctx context.Context, actor *model.User, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, actor, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RevokeRole is a mock of AdminService RevokeRole
+func (m *MockAdminService) RevokeRole(ctx context.Context, actor *model.User, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, actor, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// BootstrapAdmin is a mock of AdminService BootstrapAdmin
+func (m *MockAdminService) BootstrapAdmin(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SearchUsers is a mock of AdminService SearchUsers
+func (m *MockAdminService) SearchUsers(ctx context.Context, query string, cursor string, limit int) (*model.UserPage, error) {
+	ret := m.Called(ctx, query, cursor, limit)
+
+	var r0 *model.UserPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetUser is a mock of AdminService GetUser
+func (m *MockAdminService) GetUser(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateUser is a mock of AdminService UpdateUser
+func (m *MockAdminService) UpdateUser(ctx context.Context, actor *model.User, u *model.User, emailVerified *bool) error {
+	ret := m.Called(ctx, actor, u, emailVerified)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ResetPassword is a mock of AdminService ResetPassword
+func (m *MockAdminService) ResetPassword(ctx context.Context, actor *model.User, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, actor, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ForceSignout is a mock of AdminService ForceSignout
+func (m *MockAdminService) ForceSignout(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+	ret := m.Called(ctx, actor, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SuspendUser is a mock of AdminService SuspendUser
+func (m *MockAdminService) SuspendUser(ctx context.Context, actor *model.User, uid uuid.UUID, reason string, until *time.Time) error {
+	ret := m.Called(ctx, actor, uid, reason, until)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DisableUser is a mock of AdminService DisableUser
+func (m *MockAdminService) DisableUser(ctx context.Context, actor *model.User, uid uuid.UUID, reason string) error {
+	ret := m.Called(ctx, actor, uid, reason)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// EnableUser is a mock of AdminService EnableUser
+func (m *MockAdminService) EnableUser(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+	ret := m.Called(ctx, actor, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DeleteUser is a mock of AdminService DeleteUser
+func (m *MockAdminService) DeleteUser(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+	ret := m.Called(ctx, actor, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditEventRepository is a mock type for model.AuditEventRepository
+type MockAuditEventRepository struct {
+	mock.Mock
+}
+
+// Create is mock of AuditEventRepository Create
+func (m *MockAuditEventRepository) Create(ctx context.Context, e *model.AuditEvent) error {
+	ret := m.Called(ctx, e)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// List is mock of AuditEventRepository List
+func (m *MockAuditEventRepository) List(ctx context.Context, f *model.AuditFilter) ([]*model.AuditEvent, error) {
+	ret := m.Called(ctx, f)
+
+	var r0 []*model.AuditEvent
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.AuditEvent)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditService is a mock type for model.AuditService
+type MockAuditService struct {
+	mock.Mock
+}
+
+// ListEvents is mock of AuditService ListEvents
+func (m *MockAuditService) ListEvents(ctx context.Context, f *model.AuditFilter, cursor string) (*model.AuditPage, error) {
+	ret := m.Called(ctx, f, cursor)
+
+	var r0 *model.AuditPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AuditPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockEventsBroker is a mock type for model.EventsBroker
+type MockEventsBroker struct {
+	mock.Mock
+}
+
+// Publish is mock of EventsBroker Publish
+func (m *MockEventsBroker) Publish(ctx context.Context, e *model.UserEvent) error {
+	ret := m.Called(ctx, e)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
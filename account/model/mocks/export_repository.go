@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportRepository is a mock type for model.ExportRepository
+type MockExportRepository struct {
+	mock.Mock
+}
+
+// Save is mock of ExportRepository Save
+func (m *MockExportRepository) Save(ctx context.Context, e *model.DataExport) error {
+	ret := m.Called(ctx, e)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Find is mock of ExportRepository Find
+func (m *MockExportRepository) Find(ctx context.Context, id uuid.UUID) (*model.DataExport, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.DataExport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.DataExport)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SaveArchive is mock of ExportRepository SaveArchive
+func (m *MockExportRepository) SaveArchive(ctx context.Context, id uuid.UUID, archive []byte, expiresIn time.Duration) error {
+	ret := m.Called(ctx, id, archive, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindArchive is mock of ExportRepository FindArchive
+func (m *MockExportRepository) FindArchive(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 []byte
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]byte)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindStalePending is mock of ExportRepository FindStalePending
+func (m *MockExportRepository) FindStalePending(ctx context.Context, before time.Time, limit int) ([]*model.DataExport, error) {
+	ret := m.Called(ctx, before, limit)
+
+	var r0 []*model.DataExport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.DataExport)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
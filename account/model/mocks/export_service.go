@@ -0,0 +1,87 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportService is a mock type for model.ExportService
+type MockExportService struct {
+	mock.Mock
+}
+
+// RequestExport is mock of ExportService RequestExport
+func (m *MockExportService) RequestExport(ctx context.Context, uid uuid.UUID, format string) (*model.DataExport, error) {
+	ret := m.Called(ctx, uid, format)
+
+	var r0 *model.DataExport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.DataExport)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetExport is mock of ExportService GetExport
+func (m *MockExportService) GetExport(ctx context.Context, uid uuid.UUID, id uuid.UUID) (*model.DataExport, error) {
+	ret := m.Called(ctx, uid, id)
+
+	var r0 *model.DataExport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.DataExport)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Download is mock of ExportService Download
+func (m *MockExportService) Download(ctx context.Context, id uuid.UUID, expires int64, signature string) (*model.DataExport, []byte, error) {
+	ret := m.Called(ctx, id, expires, signature)
+
+	var r0 *model.DataExport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.DataExport)
+	}
+
+	var r1 []byte
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).([]byte)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// SweepExports is mock of ExportService SweepExports
+func (m *MockExportService) SweepExports(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
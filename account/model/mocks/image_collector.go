@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockImageCollector is a mock type for model.ImageCollector
+type MockImageCollector struct {
+	mock.Mock
+}
+
+// Collect is mock of ImageCollector Collect
+func (m *MockImageCollector) Collect(ctx context.Context, dryRun bool) (*model.ImageGCReport, error) {
+	ret := m.Called(ctx, dryRun)
+
+	var r0 *model.ImageGCReport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ImageGCReport)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockImageModerator is a mock type for model.ImageModerator
+type MockImageModerator struct {
+	mock.Mock
+}
+
+// Moderate is mock of ImageModerator Moderate
+func (m *MockImageModerator) Moderate(ctx context.Context, uid uuid.UUID, data []byte) (*model.ModerationResult, error) {
+	ret := m.Called(ctx, uid, data)
+
+	var r0 *model.ModerationResult
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ModerationResult)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -2,8 +2,11 @@ package mocks
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
+	"time"
 
+	"github.com/ndenisj/go_mem/account/model"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -44,3 +47,72 @@ func (m *MockImageRepository) DeleteProfile(ctx context.Context, objName string)
 
 	return r0
 }
+
+// GetProfile is mock representation of ImageRepository GetProfile
+func (m *MockImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 io.ReadCloser
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(io.ReadCloser)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SignURL is mock representation of ImageRepository SignURL
+func (m *MockImageRepository) SignURL(ctx context.Context, objName string, ttl time.Duration) (string, error) {
+	ret := m.Called(ctx, objName, ttl)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SignUpload is mock representation of ImageRepository SignUpload
+func (m *MockImageRepository) SignUpload(ctx context.Context, objName string, contentType string, size int64, ttl time.Duration) (*model.ImageUpload, error) {
+	ret := m.Called(ctx, objName, contentType, size, ttl)
+
+	var r0 *model.ImageUpload
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ImageUpload)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is mock representation of ImageRepository List
+func (m *MockImageRepository) List(ctx context.Context) ([]*model.StoredImage, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.StoredImage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.StoredImage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,107 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockImageReviewRepository is a mock type for model.ImageReviewRepository
+type MockImageReviewRepository struct {
+	mock.Mock
+}
+
+// Create is mock of ImageReviewRepository Create
+func (m *MockImageReviewRepository) Create(ctx context.Context, r *model.ImageReview) error {
+	ret := m.Called(ctx, r)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Update is mock of ImageReviewRepository Update
+func (m *MockImageReviewRepository) Update(ctx context.Context, r *model.ImageReview) error {
+	ret := m.Called(ctx, r)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is mock of ImageReviewRepository FindByID
+func (m *MockImageReviewRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.ImageReview, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.ImageReview
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ImageReview)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is mock of ImageReviewRepository List
+func (m *MockImageReviewRepository) List(ctx context.Context, status string, limit int) ([]*model.ImageReview, error) {
+	ret := m.Called(ctx, status, limit)
+
+	var r0 []*model.ImageReview
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.ImageReview)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindStalePending is mock of ImageReviewRepository FindStalePending
+func (m *MockImageReviewRepository) FindStalePending(ctx context.Context, before time.Time, limit int) ([]*model.ImageReview, error) {
+	ret := m.Called(ctx, before, limit)
+
+	var r0 []*model.ImageReview
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.ImageReview)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// HoldsImage is mock of ImageReviewRepository HoldsImage
+func (m *MockImageReviewRepository) HoldsImage(ctx context.Context, objName string) (bool, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
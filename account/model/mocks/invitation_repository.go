@@ -0,0 +1,106 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInvitationRepository is a mock type for model.InvitationRepository
+type MockInvitationRepository struct {
+	mock.Mock
+}
+
+// Create is mock of InvitationRepository Create
+func (m *MockInvitationRepository) Create(ctx context.Context, inv *model.Invitation) error {
+	ret := m.Called(ctx, inv)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Update is mock of InvitationRepository Update
+func (m *MockInvitationRepository) Update(ctx context.Context, inv *model.Invitation) error {
+	ret := m.Called(ctx, inv)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is mock of InvitationRepository FindByID
+func (m *MockInvitationRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Invitation, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Invitation)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByTokenHash is mock of InvitationRepository FindByTokenHash
+func (m *MockInvitationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r0 *model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Invitation)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListByInviter is mock of InvitationRepository ListByInviter
+func (m *MockInvitationRepository) ListByInviter(ctx context.Context, uid uuid.UUID) ([]*model.Invitation, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Invitation)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListByEmail is mock of InvitationRepository ListByEmail
+func (m *MockInvitationRepository) ListByEmail(ctx context.Context, email string) ([]*model.Invitation, error) {
+	ret := m.Called(ctx, email)
+
+	var r0 []*model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Invitation)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,111 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInvitationService is a mock type for model.InvitationService
+type MockInvitationService struct {
+	mock.Mock
+}
+
+// Invite is mock of InvitationService Invite
+func (m *MockInvitationService) Invite(ctx context.Context, actor *model.User, orgID uuid.UUID, email string, role string) (*model.Invitation, string, error) {
+	ret := m.Called(ctx, actor, orgID, email, role)
+
+	var r0 *model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Invitation)
+	}
+
+	var r1 string
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// ListSent is mock of InvitationService ListSent
+func (m *MockInvitationService) ListSent(ctx context.Context, actor *model.User) ([]*model.Invitation, error) {
+	ret := m.Called(ctx, actor)
+
+	var r0 []*model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Invitation)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListReceived is mock of InvitationService ListReceived
+func (m *MockInvitationService) ListReceived(ctx context.Context, actor *model.User) ([]*model.Invitation, error) {
+	ret := m.Called(ctx, actor)
+
+	var r0 []*model.Invitation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Invitation)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Revoke is mock of InvitationService Revoke
+func (m *MockInvitationService) Revoke(ctx context.Context, actor *model.User, id uuid.UUID) error {
+	ret := m.Called(ctx, actor, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Accept is mock of InvitationService Accept
+func (m *MockInvitationService) Accept(ctx context.Context, actor *model.User, token string) (*model.OrgMember, error) {
+	ret := m.Called(ctx, actor, token)
+
+	var r0 *model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Signup is mock of InvitationService Signup
+func (m *MockInvitationService) Signup(ctx context.Context, u *model.User, token string) error {
+	ret := m.Called(ctx, u, token)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
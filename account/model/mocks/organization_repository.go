@@ -0,0 +1,171 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrganizationRepository is a mock type for model.OrganizationRepository
+type MockOrganizationRepository struct {
+	mock.Mock
+}
+
+// Create is mock of OrganizationRepository Create
+func (m *MockOrganizationRepository) Create(ctx context.Context, o *model.Organization) error {
+	ret := m.Called(ctx, o)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Update is mock of OrganizationRepository Update
+func (m *MockOrganizationRepository) Update(ctx context.Context, o *model.Organization) error {
+	ret := m.Called(ctx, o)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Delete is mock of OrganizationRepository Delete
+func (m *MockOrganizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is mock of OrganizationRepository FindByID
+func (m *MockOrganizationRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Organization, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListByUser is mock of OrganizationRepository ListByUser
+func (m *MockOrganizationRepository) ListByUser(ctx context.Context, uid uuid.UUID) ([]*model.Organization, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindMember is mock of OrganizationRepository FindMember
+func (m *MockOrganizationRepository) FindMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	ret := m.Called(ctx, orgID, uid)
+
+	var r0 *model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListMembers is mock of OrganizationRepository ListMembers
+func (m *MockOrganizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	ret := m.Called(ctx, orgID)
+
+	var r0 []*model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// AddMember is mock of OrganizationRepository AddMember
+func (m *MockOrganizationRepository) AddMember(ctx context.Context, member *model.OrgMember) error {
+	ret := m.Called(ctx, member)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdateMember is mock of OrganizationRepository UpdateMember
+func (m *MockOrganizationRepository) UpdateMember(ctx context.Context, member *model.OrgMember) error {
+	ret := m.Called(ctx, member)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RemoveMember is mock of OrganizationRepository RemoveMember
+func (m *MockOrganizationRepository) RemoveMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) error {
+	ret := m.Called(ctx, orgID, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// CountOwners is mock of OrganizationRepository CountOwners
+func (m *MockOrganizationRepository) CountOwners(ctx context.Context, orgID uuid.UUID) (int, error) {
+	ret := m.Called(ctx, orgID)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,157 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrganizationService is a mock type for model.OrganizationService
+type MockOrganizationService struct {
+	mock.Mock
+}
+
+// CreateOrganization is mock of OrganizationService CreateOrganization
+func (m *MockOrganizationService) CreateOrganization(ctx context.Context, actor *model.User, name string) (*model.Organization, error) {
+	ret := m.Called(ctx, actor, name)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListOrganizations is mock of OrganizationService ListOrganizations
+func (m *MockOrganizationService) ListOrganizations(ctx context.Context, actor *model.User) ([]*model.Organization, error) {
+	ret := m.Called(ctx, actor)
+
+	var r0 []*model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetOrganization is mock of OrganizationService GetOrganization
+func (m *MockOrganizationService) GetOrganization(ctx context.Context, actor *model.User, id uuid.UUID) (*model.Organization, error) {
+	ret := m.Called(ctx, actor, id)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateOrganization is mock of OrganizationService UpdateOrganization
+func (m *MockOrganizationService) UpdateOrganization(ctx context.Context, actor *model.User, id uuid.UUID, name string) (*model.Organization, error) {
+	ret := m.Called(ctx, actor, id, name)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteOrganization is mock of OrganizationService DeleteOrganization
+func (m *MockOrganizationService) DeleteOrganization(ctx context.Context, actor *model.User, id uuid.UUID) error {
+	ret := m.Called(ctx, actor, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ListMembers is mock of OrganizationService ListMembers
+func (m *MockOrganizationService) ListMembers(ctx context.Context, actor *model.User, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	ret := m.Called(ctx, actor, orgID)
+
+	var r0 []*model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// AddMember is mock of OrganizationService AddMember
+func (m *MockOrganizationService) AddMember(ctx context.Context, actor *model.User, orgID uuid.UUID, email string, role string) (*model.OrgMember, error) {
+	ret := m.Called(ctx, actor, orgID, email, role)
+
+	var r0 *model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateMember is mock of OrganizationService UpdateMember
+func (m *MockOrganizationService) UpdateMember(ctx context.Context, actor *model.User, orgID uuid.UUID, uid uuid.UUID, role string) (*model.OrgMember, error) {
+	ret := m.Called(ctx, actor, orgID, uid, role)
+
+	var r0 *model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// RemoveMember is mock of OrganizationService RemoveMember
+func (m *MockOrganizationService) RemoveMember(ctx context.Context, actor *model.User, orgID uuid.UUID, uid uuid.UUID) error {
+	ret := m.Called(ctx, actor, orgID, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,96 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOutboxRepository is a mock type for model.OutboxRepository
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+// Add is mock of OutboxRepository Add
+func (m *MockOutboxRepository) Add(ctx context.Context, e *model.UserEvent) error {
+	ret := m.Called(ctx, e)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FetchPending is mock of OutboxRepository FetchPending
+func (m *MockOutboxRepository) FetchPending(ctx context.Context, limit int) ([]*model.OutboxMessage, error) {
+	ret := m.Called(ctx, limit)
+
+	var r0 []*model.OutboxMessage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.OutboxMessage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// MarkPublished is mock of OutboxRepository MarkPublished
+func (m *MockOutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// MarkFailed is mock of OutboxRepository MarkFailed
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, nextAttemptAt time.Time) error {
+	ret := m.Called(ctx, id, reason, nextAttemptAt)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DeadLetter is mock of OutboxRepository DeadLetter
+func (m *MockOutboxRepository) DeadLetter(ctx context.Context, id int64, reason string) error {
+	ret := m.Called(ctx, id, reason)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DeletePublished is mock of OutboxRepository DeletePublished
+func (m *MockOutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	ret := m.Called(ctx, before)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
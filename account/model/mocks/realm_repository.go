@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRealmRepository is a mock type for model.RealmRepository
+type MockRealmRepository struct {
+	mock.Mock
+}
+
+// List is mock of RealmRepository List
+func (m *MockRealmRepository) List(ctx context.Context) ([]*model.Realm, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Realm
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Realm)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
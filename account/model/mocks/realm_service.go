@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRealmService is a mock type for model.RealmService
+type MockRealmService struct {
+	mock.Mock
+}
+
+// Get is mock of RealmService Get
+func (m *MockRealmService) Get(id string) (*model.Realm, error) {
+	ret := m.Called(id)
+
+	var r0 *model.Realm
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Realm)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ResolveHost is mock of RealmService ResolveHost
+func (m *MockRealmService) ResolveHost(host string) (*model.Realm, bool) {
+	ret := m.Called(host)
+
+	var r0 *model.Realm
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Realm)
+	}
+
+	var r1 bool
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// Reload is mock of RealmService Reload
+func (m *MockRealmService) Reload(ctx context.Context) error {
+	ret := m.Called(ctx)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
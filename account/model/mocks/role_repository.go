@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoleRepository is a mock type for model.RoleRepository
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+// List is mock of RoleRepository List
+func (m *MockRoleRepository) List(ctx context.Context) ([]*model.Role, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Role
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Role)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Assign is mock of RoleRepository Assign
+func (m *MockRoleRepository) Assign(ctx context.Context, uid uuid.UUID, role string, grantedBy *uuid.UUID) error {
+	ret := m.Called(ctx, uid, role, grantedBy)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Revoke is mock of RoleRepository Revoke
+func (m *MockRoleRepository) Revoke(ctx context.Context, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, uid, role)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// CountUsers is mock of RoleRepository CountUsers
+func (m *MockRoleRepository) CountUsers(ctx context.Context, role string) (int, error) {
+	ret := m.Called(ctx, role)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
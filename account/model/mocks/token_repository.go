@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/ndenisj/go_mem/account/model"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -50,3 +51,21 @@ func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userI
 
 	return r0
 }
+
+// ListUserRefreshTokens is a mock of model.TokenRepository ListUserRefreshTokens
+func (m *MockTokenRepository) ListUserRefreshTokens(ctx context.Context, userID string) ([]*model.Session, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
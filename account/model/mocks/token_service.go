@@ -33,6 +33,24 @@ func (m *MockTokenService) NewPairFromUser(ctx context.Context, u *model.User, p
 	return r0, r1
 }
 
+// NewImpersonationToken mocks concrete NewImpersonationToken
+func (m *MockTokenService) NewImpersonationToken(ctx context.Context, actor *model.User, u *model.User) (*model.ImpersonationToken, error) {
+	ret := m.Called(ctx, actor, u)
+
+	var r0 *model.ImpersonationToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ImpersonationToken)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 // Signout mocks concrete Signout
 func (m *MockTokenService) Signout(ctx context.Context, uid uuid.UUID) error {
 	ret := m.Called(ctx, uid)
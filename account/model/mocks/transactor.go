@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTransactor is a mock type for model.Transactor
+// fn is run with the same context unless an error is set to be returned
+type MockTransactor struct {
+	mock.Mock
+}
+
+// WithinTransaction is mock of Transactor WithinTransaction
+func (m *MockTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ret := m.Called(ctx)
+
+	if ret.Get(0) != nil {
+		return ret.Get(0).(error)
+	}
+
+	return fn(ctx)
+}
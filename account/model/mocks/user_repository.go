@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ndenisj/go_mem/account/model"
@@ -74,8 +75,8 @@ func (m *MockUserRepository) Update(ctx context.Context, u *model.User) error {
 	return r0
 }
 
-func (m *MockUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
-	ret := m.Called(ctx, uid, imageURL)
+func (m *MockUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURLs model.ImageURLs) (*model.User, error) {
+	ret := m.Called(ctx, uid, imageURLs)
 
 	var r0 *model.User
 
@@ -91,3 +92,144 @@ func (m *MockUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, ima
 
 	return r0, r1
 }
+
+// UpdateImageHistory is mock of UserRepository UpdateImageHistory
+func (m *MockUserRepository) UpdateImageHistory(ctx context.Context, uid uuid.UUID, history model.ImageHistory) error {
+	ret := m.Called(ctx, uid, history)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdatePassword is mock of UserRepository UpdatePassword
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdateStatus is mock of UserRepository UpdateStatus
+func (m *MockUserRepository) UpdateStatus(ctx context.Context, uid uuid.UUID, status string, reason string, until *time.Time) error {
+	ret := m.Called(ctx, uid, status, reason, until)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Search is mock of UserRepository Search
+func (m *MockUserRepository) Search(ctx context.Context, query string, after uuid.UUID, limit int) ([]*model.User, error) {
+	ret := m.Called(ctx, query, after, limit)
+
+	var r0 []*model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Delete is mock of UserRepository Delete
+func (m *MockUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SoftDelete is mock of UserRepository SoftDelete
+func (m *MockUserRepository) SoftDelete(ctx context.Context, uid uuid.UUID, purgeAfter time.Time) error {
+	ret := m.Called(ctx, uid, purgeAfter)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindPurgeable is mock of UserRepository FindPurgeable
+func (m *MockUserRepository) FindPurgeable(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	ret := m.Called(ctx, before, limit)
+
+	var r0 []*model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListImageURLs is mock of UserRepository ListImageURLs
+func (m *MockUserRepository) ListImageURLs(ctx context.Context) ([]string, error) {
+	ret := m.Called(ctx)
+
+	var r0 []string
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// HasImage is mock of UserRepository HasImage
+func (m *MockUserRepository) HasImage(ctx context.Context, objName string) (bool, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 bool
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
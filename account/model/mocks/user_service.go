@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
 
 	"github.com/google/uuid"
@@ -35,6 +36,23 @@ func (m *MockUserService) Get(ctx context.Context, uid uuid.UUID) (*model.User,
 	return r0, r1
 }
 
+// Profile is a mock of UserService.Profile
+func (m *MockUserService) Profile(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 // Signup is a mock of UserService.Signup
 func (m *MockUserService) Signup(ctx context.Context, u *model.User) error {
 	ret := m.Called(ctx, u)
@@ -86,6 +104,74 @@ func (m *MockUserService) SetProfileImage(ctx context.Context, uid uuid.UUID, im
 	return r0, r1
 }
 
+// NewImageUpload is a mock of UserService.NewImageUpload
+func (m *MockUserService) NewImageUpload(ctx context.Context, uid uuid.UUID, contentType string, size int64) (*model.ImageUpload, error) {
+	ret := m.Called(ctx, uid, contentType, size)
+
+	var r0 *model.ImageUpload
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ImageUpload)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CommitImageUpload is a mock of UserService.CommitImageUpload
+func (m *MockUserService) CommitImageUpload(ctx context.Context, uid uuid.UUID, objName string) (*model.User, error) {
+	ret := m.Called(ctx, uid, objName)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DefaultAvatar is a mock of UserService.DefaultAvatar
+func (m *MockUserService) DefaultAvatar(ctx context.Context, uid uuid.UUID, size int) ([]byte, error) {
+	ret := m.Called(ctx, uid, size)
+
+	var r0 []byte
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]byte)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// OpenImage is a mock of UserService.OpenImage
+func (m *MockUserService) OpenImage(ctx context.Context, objName string) (io.ReadCloser, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 io.ReadCloser
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(io.ReadCloser)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 func (m *MockUserService) ClearProfileImage(ctx context.Context, uid uuid.UUID) error {
 	ret := m.Called(ctx, uid)
 
@@ -96,3 +182,115 @@ func (m *MockUserService) ClearProfileImage(ctx context.Context, uid uuid.UUID)
 
 	return r0
 }
+
+func (m *MockUserService) Delete(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) PurgeDeleted(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SweepImageReviews is a mock of UserService.SweepImageReviews
+func (m *MockUserService) SweepImageReviews(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ImageHistory is a mock of UserService.ImageHistory
+func (m *MockUserService) ImageHistory(ctx context.Context, uid uuid.UUID) (model.ImageHistory, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 model.ImageHistory
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(model.ImageHistory)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// RevertProfileImage is a mock of UserService.RevertProfileImage
+func (m *MockUserService) RevertProfileImage(ctx context.Context, uid uuid.UUID, versionID uuid.UUID) (*model.User, error) {
+	ret := m.Called(ctx, uid, versionID)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ImageReviews is a mock of UserService.ImageReviews
+func (m *MockUserService) ImageReviews(ctx context.Context, status string, limit int) ([]*model.ImageReview, error) {
+	ret := m.Called(ctx, status, limit)
+
+	var r0 []*model.ImageReview
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.ImageReview)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ReviewImage is a mock of UserService.ReviewImage
+func (m *MockUserService) ReviewImage(ctx context.Context, actor *model.User, id uuid.UUID, approve bool, reason string) (*model.ImageReview, error) {
+	ret := m.Called(ctx, actor, id, approve, reason)
+
+	var r0 *model.ImageReview
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ImageReview)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
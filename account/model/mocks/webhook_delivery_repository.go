@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWebhookDeliveryRepository is a mock type for model.WebhookDeliveryRepository
+type MockWebhookDeliveryRepository struct {
+	mock.Mock
+}
+
+// Create is mock of WebhookDeliveryRepository Create
+func (m *MockWebhookDeliveryRepository) Create(ctx context.Context, d *model.WebhookDelivery) error {
+	ret := m.Called(ctx, d)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ClaimDue is mock of WebhookDeliveryRepository ClaimDue
+func (m *MockWebhookDeliveryRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	ret := m.Called(ctx, limit, lease)
+
+	var r0 []*model.WebhookDelivery
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.WebhookDelivery)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Update is mock of WebhookDeliveryRepository Update
+func (m *MockWebhookDeliveryRepository) Update(ctx context.Context, d *model.WebhookDelivery) error {
+	ret := m.Called(ctx, d)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is mock of WebhookDeliveryRepository FindByID
+func (m *MockWebhookDeliveryRepository) FindByID(ctx context.Context, id int64) (*model.WebhookDelivery, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.WebhookDelivery
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebhookDelivery)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is mock of WebhookDeliveryRepository List
+func (m *MockWebhookDeliveryRepository) List(ctx context.Context, webhookID uuid.UUID, status string, before int64, limit int) ([]*model.WebhookDelivery, error) {
+	ret := m.Called(ctx, webhookID, status, before, limit)
+
+	var r0 []*model.WebhookDelivery
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.WebhookDelivery)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,101 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWebhookRepository is a mock type for model.WebhookRepository
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+// Create is mock of WebhookRepository Create
+func (m *MockWebhookRepository) Create(ctx context.Context, w *model.Webhook) error {
+	ret := m.Called(ctx, w)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Update is mock of WebhookRepository Update
+func (m *MockWebhookRepository) Update(ctx context.Context, w *model.Webhook) error {
+	ret := m.Called(ctx, w)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Delete is mock of WebhookRepository Delete
+func (m *MockWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is mock of WebhookRepository FindByID
+func (m *MockWebhookRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.Webhook
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Webhook)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is mock of WebhookRepository List
+func (m *MockWebhookRepository) List(ctx context.Context) ([]*model.Webhook, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Webhook
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Webhook)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListSubscribed is mock of WebhookRepository ListSubscribed
+func (m *MockWebhookRepository) ListSubscribed(ctx context.Context, realmID string, eventType string) ([]*model.Webhook, error) {
+	ret := m.Called(ctx, realmID, eventType)
+
+	var r0 []*model.Webhook
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Webhook)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
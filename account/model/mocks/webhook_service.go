@@ -0,0 +1,130 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWebhookService is a mock type for model.WebhookService
+type MockWebhookService struct {
+	mock.Mock
+}
+
+// ListWebhooks is mock of WebhookService ListWebhooks
+func (m *MockWebhookService) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Webhook
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Webhook)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CreateWebhook is mock of WebhookService CreateWebhook
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	ret := m.Called(ctx, w)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdateWebhook is mock of WebhookService UpdateWebhook
+func (m *MockWebhookService) UpdateWebhook(ctx context.Context, w *model.Webhook) error {
+	ret := m.Called(ctx, w)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DeleteWebhook is mock of WebhookService DeleteWebhook
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ListDeliveries is mock of WebhookService ListDeliveries
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, webhookID uuid.UUID, status string, cursor string, limit int) (*model.WebhookDeliveryPage, error) {
+	ret := m.Called(ctx, webhookID, status, cursor, limit)
+
+	var r0 *model.WebhookDeliveryPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebhookDeliveryPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ReplayDelivery is mock of WebhookService ReplayDelivery
+func (m *MockWebhookService) ReplayDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (*model.WebhookDelivery, error) {
+	ret := m.Called(ctx, webhookID, id)
+
+	var r0 *model.WebhookDelivery
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebhookDelivery)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Publish is mock of WebhookService Publish
+func (m *MockWebhookService) Publish(ctx context.Context, e *model.UserEvent) error {
+	ret := m.Called(ctx, e)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Deliver is mock of WebhookService Deliver
+func (m *MockWebhookService) Deliver(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Decisions an ImageModerator makes about an uploaded profile image
+const (
+	ModerationAllow      = "allow"
+	ModerationReject     = "reject"
+	ModerationQuarantine = "quarantine"
+)
+
+// ModerationResult is the decision about an image and the reason for it
+type ModerationResult struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+}
+
+// Statuses of an image review. Pending images are waiting on an
+// asynchronous moderator, and quarantined images on an admin
+const (
+	ImageReviewPending     = "pending"
+	ImageReviewQuarantined = "quarantined"
+	ImageReviewApproved    = "approved"
+	ImageReviewRejected    = "rejected"
+)
+
+// ImageReview holds a profile image back from the user until it is
+// approved. The image is stored, but only becomes the user's profile
+// image once approved, and is deleted when rejected. ReviewedBy is
+// the admin who decided, and is nil when the moderator decided
+type ImageReview struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	UID        uuid.UUID  `db:"uid" json:"uid"`
+	ImageURLs  ImageURLs  `db:"image_urls" json:"imageUrls,omitempty"`
+	Status     string     `db:"status" json:"status"`
+	Reason     string     `db:"reason" json:"reason"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	ReviewedBy *uuid.UUID `db:"reviewed_by" json:"reviewedBy"`
+	ReviewedAt *time.Time `db:"reviewed_at" json:"reviewedAt"`
+}
+
+// IsOpen reports whether the image is still waiting to be approved or rejected
+func (r *ImageReview) IsOpen() bool {
+	return r.Status == ImageReviewPending || r.Status == ImageReviewQuarantined
+}
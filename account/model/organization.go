@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles a member can hold within an organization, from most to least privileged
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// orgRoleRanks orders organization roles by privilege
+var orgRoleRanks = map[string]int{
+	OrgRoleOwner:  3,
+	OrgRoleAdmin:  2,
+	OrgRoleMember: 1,
+}
+
+// ValidOrgRole reports whether role is one of the organization roles
+func ValidOrgRole(role string) bool {
+	return orgRoleRanks[role] > 0
+}
+
+// OrgRoleAtLeast reports whether role is as privileged as min
+func OrgRoleAtLeast(role string, min string) bool {
+	return orgRoleRanks[role] >= orgRoleRanks[min]
+}
+
+// Organization is a team account which users belong to as members.
+// Role is the role of the user the organization was listed for
+type Organization struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Role      string    `db:"role" json:"role,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// OrgMember is a user's membership of an organization
+// along with the details of the user needed to list members
+type OrgMember struct {
+	OrgID    uuid.UUID `db:"org_id" json:"orgId"`
+	UID      uuid.UUID `db:"uid" json:"uid"`
+	Role     string    `db:"role" json:"role"`
+	Name     string    `db:"name" json:"name"`
+	Email    string    `db:"email" json:"email"`
+	JoinedAt time.Time `db:"joined_at" json:"joinedAt"`
+}
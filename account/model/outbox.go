@@ -0,0 +1,36 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage is a user event waiting in the outbox to be published.
+// It is written in the same transaction as the change it describes,
+// so an event is only ever published for a committed change
+type OutboxMessage struct {
+	ID             int64      `db:"id"`
+	EventID        uuid.UUID  `db:"event_id"`
+	UID            uuid.UUID  `db:"uid"`
+	EventType      string     `db:"event_type"`
+	Payload        []byte     `db:"payload"`
+	Attempts       int        `db:"attempts"`
+	LastError      string     `db:"last_error"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+	PublishedAt    *time.Time `db:"published_at"`
+	DeadLetteredAt *time.Time `db:"dead_lettered_at"`
+}
+
+// Event decodes the event stored in the message's payload
+func (m *OutboxMessage) Event() (*UserEvent, error) {
+	e := &UserEvent{}
+
+	if err := json.Unmarshal(m.Payload, e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"context"
+	"crypto/rsa"
+	"time"
+)
+
+// DefaultRealmID is the realm of requests which don't resolve to another
+// realm, which holds every user from before realms were introduced
+const DefaultRealmID = "default"
+
+// RealmBranding is how a realm's apps present themselves to their users
+type RealmBranding struct {
+	DisplayName  string `json:"displayName"`
+	LogoURL      string `json:"logoUrl"`
+	PrimaryColor string `json:"primaryColor"`
+	SupportEmail string `json:"supportEmail"`
+}
+
+// Realm is a tenant of the account service with its own users. Requests
+// are resolved to a realm by their host or a /realms/:id path prefix.
+// Keys and token lifetimes which are not set fall back to the deployment's
+type Realm struct {
+	ID                    string          `json:"id"`
+	Name                  string          `json:"name"`
+	Hosts                 []string        `json:"-"`
+	Branding              RealmBranding   `json:"branding"`
+	PrivKey               *rsa.PrivateKey `json:"-"`
+	PubKey                *rsa.PublicKey  `json:"-"`
+	RefreshSecret         string          `json:"-"`
+	IDExpirationSecs      int64           `json:"-"`
+	RefreshExpirationSecs int64           `json:"-"`
+	CreatedAt             time.Time       `json:"createdAt"`
+}
+
+type realmKey struct{}
+
+// WithRealm returns a copy of ctx carrying the id of the realm of the current request
+func WithRealm(ctx context.Context, realmID string) context.Context {
+	return context.WithValue(ctx, realmKey{}, realmID)
+}
+
+// RealmIDFromContext returns the realm set by WithRealm, or the default realm
+func RealmIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(realmKey{}).(string); ok && id != "" {
+		return id
+	}
+
+	return DefaultRealmID
+}
+
+// RealmScope returns the realm set by WithRealm, or an empty string when ctx
+// is not tied to a realm, such as in background jobs which work across realms
+func RealmScope(ctx context.Context) string {
+	id, _ := ctx.Value(realmKey{}).(string)
+	return id
+}
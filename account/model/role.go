@@ -0,0 +1,26 @@
+package model
+
+// Role names seeded by the roles migration
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+// Permission names seeded by the roles migration
+const (
+	PermissionRolesRead        = "roles:read"
+	PermissionRolesWrite       = "roles:write"
+	PermissionUsersRead        = "users:read"
+	PermissionUsersWrite       = "users:write"
+	PermissionAuditRead        = "audit:read"
+	PermissionWebhooks         = "webhooks:manage"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionImages           = "images:manage"
+)
+
+// Role defines a named group of permissions which can be granted to users
+type Role struct {
+	Name        string   `db:"name" json:"name"`
+	Description string   `db:"description" json:"description"`
+	Permissions []string `db:"-" json:"permissions"`
+}
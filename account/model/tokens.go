@@ -1,6 +1,10 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // RefreshToken stores token properties that are accessed in
 // multiple application layers
@@ -21,3 +25,10 @@ type TokenPair struct {
 	IDToken
 	RefreshToken
 }
+
+// ImpersonationToken is an ID token minted for an admin to act as another
+// user. It can't be refreshed, so it is only usable until ExpiresAt
+type ImpersonationToken struct {
+	IDToken
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -1,13 +1,99 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// Account statuses stored in users.status
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDisabled  = "disabled"
+)
 
 // User defines domain model and its json and database representations
+// Roles, Permissions and Orgs are not columns of the users table. They are
+// loaded by the repository and travel in the ID token as their own claims.
+// Orgs maps the id of each organization the user belongs to to their role in it.
+// ActorUID is the admin impersonating the user, taken from the ID token's act claim.
+// ImageReview is set when a new profile image is held back for review
 type User struct {
-	UID      uuid.UUID `db:"uid" json:"uid"`
-	Email    string    `db:"email" json:"email"`
-	Password string    `db:"password" json:"-"`
-	Name     string    `db:"name" json:"name"`
-	ImageURL string    `db:"image_url" json:"image_url"`
-	Website  string    `db:"website" json:"website"`
+	UID            uuid.UUID         `db:"uid" json:"uid"`
+	RealmID        string            `db:"realm_id" json:"realm"`
+	Email          string            `db:"email" json:"email"`
+	EmailVerified  bool              `db:"email_verified" json:"email_verified"`
+	Password       string            `db:"password" json:"-"`
+	Name           string            `db:"name" json:"name"`
+	ImageURLs      ImageURLs         `db:"image_urls" json:"image_urls"`
+	ImageHistory   ImageHistory      `db:"image_history" json:"-"`
+	Website        string            `db:"website" json:"website"`
+	Status         string            `db:"status" json:"status"`
+	StatusReason   string            `db:"status_reason" json:"status_reason"`
+	SuspendedUntil *time.Time        `db:"suspended_until" json:"suspended_until"`
+	DeletedAt      *time.Time        `db:"deleted_at" json:"-"`
+	PurgeAfter     *time.Time        `db:"purge_after" json:"-"`
+	Roles          []string          `db:"-" json:"-"`
+	Permissions    []string          `db:"-" json:"-"`
+	Orgs           map[string]string `db:"-" json:"-"`
+	ActorUID       *uuid.UUID        `db:"-" json:"-"`
+	ImageReview    *ImageReview      `db:"-" json:"-"`
+}
+
+// IsActive reports whether the user may currently sign in or use
+// their tokens. A suspension whose end time has passed no longer applies
+func (u *User) IsActive(now time.Time) bool {
+	switch u.Status {
+	case UserStatusDisabled:
+		return false
+	case UserStatusSuspended:
+		return u.SuspendedUntil != nil && now.After(*u.SuspendedUntil)
+	default:
+		return true
+	}
+}
+
+// StatusError returns an AccountInactive error describing why
+// the user may not sign in, or nil if the user is active
+func (u *User) StatusError() error {
+	if u.IsActive(time.Now()) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Account has been %s", u.Status)
+
+	if u.Status == UserStatusSuspended && u.SuspendedUntil != nil {
+		msg = fmt.Sprintf("%s until %s", msg, u.SuspendedUntil.UTC().Format(time.RFC3339))
+	}
+
+	if u.StatusReason != "" {
+		msg = fmt.Sprintf("%s. Reason: %s", msg, u.StatusReason)
+	}
+
+	return apperrors.NewAccountInactive(msg)
+}
+
+// HasRole reports whether the user has been granted any of the given roles
+func (u *User) HasRole(roles ...string) bool {
+	return containsAny(u.Roles, roles)
+}
+
+// HasPermission reports whether any of the user's roles grant any of the given permissions
+func (u *User) HasPermission(permissions ...string) bool {
+	return containsAny(u.Permissions, permissions)
+}
+
+func containsAny(held []string, wanted []string) bool {
+	for _, h := range held {
+		for _, w := range wanted {
+			if h == w {
+				return true
+			}
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a webhook delivery
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is a partner's subscription to user events. An empty
+// Events filter subscribes to every event. The secret is used to sign
+// deliveries and is only revealed when the webhook is created.
+// Webhooks only receive events about users of their realm
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	Active    bool      `json:"active"`
+	RealmID   string    `json:"realm"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookDelivery is an attempt to send an event to a webhook,
+// kept as a log of what was sent and how the receiver responded
+type WebhookDelivery struct {
+	ID             int64      `db:"id" json:"id"`
+	WebhookID      uuid.UUID  `db:"webhook_id" json:"webhookId"`
+	EventID        uuid.UUID  `db:"event_id" json:"eventId"`
+	EventType      string     `db:"event_type" json:"eventType"`
+	Payload        []byte     `db:"payload" json:"-"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	ResponseStatus int        `db:"response_status" json:"responseStatus"`
+	LastError      string     `db:"last_error" json:"lastError"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at" json:"nextAttemptAt"`
+	CreatedAt      time.Time  `db:"created_at" json:"createdAt"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"deliveredAt"`
+}
+
+// WebhookDeliveryPage is a single page of a webhook's deliveries, newest
+// first. NextCursor is empty when there are no further results
+type WebhookDeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	NextCursor string             `json:"nextCursor"`
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// fanoutEventsBroker publishes each event to several brokers
+type fanoutEventsBroker struct {
+	Brokers []model.EventsBroker
+}
+
+// NewFanoutEventsBroker is a factory for initializing an EventsBroker
+// which publishes events to every one of the given brokers
+func NewFanoutEventsBroker(brokers ...model.EventsBroker) model.EventsBroker {
+	return &fanoutEventsBroker{
+		Brokers: brokers,
+	}
+}
+
+// Publish publishes the event to every broker, even when an earlier
+// one fails, returning the first error. As the outbox publishes failed
+// events again, brokers must tolerate duplicates
+func (b *fanoutEventsBroker) Publish(ctx context.Context, e *model.UserEvent) error {
+	var first error
+
+	for _, broker := range b.Brokers {
+		if err := broker.Publish(ctx, e); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// fsImageRepository stores images as files in a local directory, so the
+// service can run without cloud storage. The files are served by a static
+// route at BaseURL, giving URLs whose last segment is the object name.
+// Secret signs URLs to the files when the route is private
+type fsImageRepository struct {
+	Dir     string
+	BaseURL string
+	Secret  string
+}
+
+// NewFSImageRepository is a factory for initializing an image repository
+// storing objects in dir and serving them from baseURL. secret is only
+// needed when the files are served privately, through signed URLs
+func NewFSImageRepository(dir string, baseURL string, secret string) model.ImageRepository {
+	return &fsImageRepository{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Secret:  secret,
+	}
+}
+
+func (r *fsImageRepository) UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error) {
+	p, err := r.path(objName)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		log.Printf("unable to create image directory: %v: %v\n", r.Dir, err)
+		return "", apperrors.NewInternal()
+	}
+
+	// write to a temporary file first so a failed upload never
+	// leaves a partially written image in place of the old one
+	tmp, err := os.CreateTemp(r.Dir, "."+objName+"-*")
+	if err != nil {
+		log.Printf("unable to create image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, imageFile); err != nil {
+		tmp.Close()
+		log.Printf("unable to write image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := tmp.Close(); err != nil {
+		log.Printf("unable to write image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	// temporary files are created readable by the owner only
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		log.Printf("unable to set image file mode: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		log.Printf("unable to move image file into place: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return r.BaseURL + "/" + objName, nil
+}
+
+func (r *fsImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	p, err := r.path(objName)
+	if err != nil {
+		return err
+	}
+
+	// deleting an object that is already gone is not a failure
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to delete image object with ID: %s from disk: %v\n", objName, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetProfile opens a reader for an image object. The caller must close it
+func (r *fsImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	p, err := r.path(objName)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		log.Printf("Failed to read image object with ID: %s from disk: %v\n", objName, err)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, apperrors.NewNotFound("image", objName)
+		}
+		return nil, apperrors.NewInternal()
+	}
+
+	return f, nil
+}
+
+// SignURL links to an object with an expiry time and a signature of both,
+// which the route serving the files checks with VerifyFSImageURL
+func (r *fsImageRepository) SignURL(ctx context.Context, objName string, ttl time.Duration) (string, error) {
+	if _, err := r.path(objName); err != nil {
+		return "", err
+	}
+
+	if r.Secret == "" {
+		log.Printf("Failed to sign URL for image object with ID: %s: no secret configured\n", objName)
+		return "", apperrors.NewInternal()
+	}
+
+	expires := time.Now().Add(ttl).Unix()
+
+	return fmt.Sprintf("%s/%s?expires=%d&signature=%s", r.BaseURL, url.PathEscape(objName), expires, signFSImage(r.Secret, objName, expires)), nil
+}
+
+// VerifyFSImageURL checks the expiry time and signature of a URL created
+// by the SignURL method of a filesystem image repository using secret
+func VerifyFSImageURL(secret string, objName string, expires int64, signature string) error {
+	if time.Now().Unix() > expires {
+		return apperrors.NewAuthorization("Image link has expired")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(signFSImage(secret, objName, expires))) {
+		return apperrors.NewAuthorization("Invalid image link")
+	}
+
+	return nil
+}
+
+// SignUpload links to an object with the type and size of the file to be
+// put there, an expiry time and a signature of them all. The route storing
+// uploaded files checks them with VerifyFSImageUpload
+func (r *fsImageRepository) SignUpload(ctx context.Context, objName string, contentType string, size int64, ttl time.Duration) (*model.ImageUpload, error) {
+	if _, err := r.path(objName); err != nil {
+		return nil, err
+	}
+
+	if r.Secret == "" {
+		log.Printf("Failed to sign upload for image object with ID: %s: no secret configured\n", objName)
+		return nil, apperrors.NewInternal()
+	}
+
+	expires := time.Now().Add(ttl).Unix()
+	signature := signFSImage(r.Secret, fsUploadSubject(objName, contentType, size), expires)
+
+	return &model.ImageUpload{
+		ObjectName: objName,
+		URL:        fmt.Sprintf("%s/%s?expires=%d&signature=%s", r.BaseURL, url.PathEscape(objName), expires, signature),
+		Method:     http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":   contentType,
+			"Content-Length": strconv.FormatInt(size, 10),
+		},
+	}, nil
+}
+
+// VerifyFSImageUpload checks the expiry time and signature of an upload
+// created by the SignUpload method of a filesystem image repository using
+// secret, along with the type and size of the file being uploaded
+func VerifyFSImageUpload(secret string, objName string, contentType string, size int64, expires int64, signature string) error {
+	return VerifyFSImageURL(secret, fsUploadSubject(objName, contentType, size), expires, signature)
+}
+
+// fsUploadSubject is what is signed for an upload, which must not be
+// mistaken for the object name signed in links to read an object
+func fsUploadSubject(objName string, contentType string, size int64) string {
+	return fmt.Sprintf("PUT:%s:%s:%d", objName, contentType, size)
+}
+
+// signFSImage creates the signature of a link to an object
+func signFSImage(secret string, objName string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%d", objName, expires)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// List reads the image directory. Temporary files of uploads in progress
+// are hidden, as are any directories
+func (r *fsImageRepository) List(ctx context.Context) ([]*model.StoredImage, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*model.StoredImage{}, nil
+		}
+		log.Printf("Failed to list image directory: %v: %v\n", r.Dir, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	images := []*model.StoredImage{}
+
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			// removed since the directory was read
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			log.Printf("Failed to stat image object with ID: %s: %v\n", e.Name(), err)
+			return nil, apperrors.NewInternal()
+		}
+
+		images = append(images, &model.StoredImage{
+			ObjectName: e.Name(),
+			Size:       info.Size(),
+			UpdatedAt:  info.ModTime(),
+		})
+	}
+
+	return images, nil
+}
+
+// path returns where an object is stored, refusing names which
+// would resolve outside of the image directory
+func (r *fsImageRepository) path(objName string) (string, error) {
+	if objName == "" || objName == "." || objName == ".." || strings.ContainsAny(objName, `/\`) {
+		return "", apperrors.NewBadRequest("invalid image name")
+	}
+
+	return filepath.Join(r.Dir, objName), nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSImageRepository(t *testing.T) {
+	dir := t.TempDir()
+	r := NewFSImageRepository(dir, "http://localhost:8080/images/", "secret")
+	ctx := context.Background()
+
+	t.Run("Store and read", func(t *testing.T) {
+		imageURL, err := r.UpdateProfile(ctx, "avatar", imageFile{bytes.NewReader([]byte("image"))})
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/images/avatar", imageURL)
+
+		stored, err := os.ReadFile(filepath.Join(dir, "avatar"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("image"), stored)
+
+		assert.NoError(t, r.DeleteProfile(ctx, "avatar"))
+		_, err = r.GetProfile(ctx, "avatar")
+		assert.Equal(t, apperrors.NewNotFound("image", "avatar"), err)
+	})
+
+	t.Run("Invalid name", func(t *testing.T) {
+		_, err := r.UpdateProfile(ctx, "../avatar", imageFile{bytes.NewReader([]byte("image"))})
+		assert.Equal(t, apperrors.NewBadRequest("invalid image name"), err)
+	})
+
+	t.Run("Signed URL", func(t *testing.T) {
+		signedURL, err := r.SignURL(ctx, "avatar", time.Minute)
+		assert.NoError(t, err)
+
+		u, err := url.Parse(signedURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "/images/avatar", u.Path)
+
+		expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+		assert.NoError(t, err)
+		signature := u.Query().Get("signature")
+
+		assert.NoError(t, VerifyFSImageURL("secret", "avatar", expires, signature))
+
+		// the signature only holds for its object, expiry and secret
+		assert.Equal(t, apperrors.NewAuthorization("Invalid image link"), VerifyFSImageURL("secret", "other", expires, signature))
+		assert.Equal(t, apperrors.NewAuthorization("Invalid image link"), VerifyFSImageURL("secret", "avatar", expires+60, signature))
+		assert.Equal(t, apperrors.NewAuthorization("Invalid image link"), VerifyFSImageURL("other", "avatar", expires, signature))
+	})
+
+	t.Run("Expired URL", func(t *testing.T) {
+		signedURL, err := r.SignURL(ctx, "avatar", -time.Minute)
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(signedURL)
+		expires, _ := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+
+		assert.Equal(t, apperrors.NewAuthorization("Image link has expired"), VerifyFSImageURL("secret", "avatar", expires, u.Query().Get("signature")))
+	})
+
+	t.Run("Signed upload", func(t *testing.T) {
+		upload, err := r.SignUpload(ctx, "upload", "image/png", 100, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "PUT", upload.Method)
+		assert.Equal(t, map[string]string{"Content-Type": "image/png", "Content-Length": "100"}, upload.Headers)
+
+		u, err := url.Parse(upload.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "/images/upload", u.Path)
+
+		expires, _ := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+		signature := u.Query().Get("signature")
+
+		assert.NoError(t, VerifyFSImageUpload("secret", "upload", "image/png", 100, expires, signature))
+
+		// the type and size of the upload are signed
+		assert.Equal(t, apperrors.NewAuthorization("Invalid image link"), VerifyFSImageUpload("secret", "upload", "image/gif", 100, expires, signature))
+		assert.Equal(t, apperrors.NewAuthorization("Invalid image link"), VerifyFSImageUpload("secret", "upload", "image/png", 101, expires, signature))
+
+		// and an upload can't be used to read the object
+		assert.Equal(t, apperrors.NewAuthorization("Invalid image link"), VerifyFSImageURL("secret", "upload", expires, signature))
+	})
+
+	t.Run("No secret", func(t *testing.T) {
+		r := NewFSImageRepository(dir, "http://localhost:8080/images", "")
+
+		_, err := r.SignURL(ctx, "avatar", time.Minute)
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		dir := t.TempDir()
+		r := NewFSImageRepository(dir, "http://localhost:8080/images", "")
+
+		_, err := r.UpdateProfile(ctx, "avatar-64", imageFile{bytes.NewReader([]byte("image"))})
+		assert.NoError(t, err)
+
+		// uploads in progress and directories are not images
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, ".avatar-128-123"), []byte("partial"), 0600))
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0755))
+
+		images, err := r.List(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, images, 1) {
+			assert.Equal(t, "avatar-64", images[0].ObjectName)
+			assert.Equal(t, int64(5), images[0].Size)
+			assert.WithinDuration(t, time.Now(), images[0].UpdatedAt, time.Minute)
+		}
+	})
+
+	t.Run("List missing directory", func(t *testing.T) {
+		r := NewFSImageRepository(filepath.Join(dir, "missing"), "http://localhost:8080/images", "")
+
+		images, err := r.List(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, images)
+	})
+}
@@ -2,14 +2,18 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
+	"net/http"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/ndenisj/go_mem/account/model"
 	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"google.golang.org/api/iterator"
 )
 
 type gcImageRepository struct {
@@ -54,10 +58,101 @@ func (r *gcImageRepository) DeleteProfile(ctx context.Context, objName string) e
 
 	object := bckt.Object(objName)
 
-	if err := object.Delete(ctx); err != nil {
+	// deleting an object that is already gone is not a failure
+	if err := object.Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
 		log.Printf("Failed to delete image object with ID: %s from GC storage\n", objName)
 		return apperrors.NewInternal()
 	}
 
 	return nil
 }
+
+// GetProfile opens a reader for an image object. The caller must close it
+func (r *gcImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	bckt := r.Storage.Bucket(r.BucketName)
+
+	rc, err := bckt.Object(objName).NewReader(ctx)
+	if err != nil {
+		log.Printf("Failed to read image object with ID: %s from GC storage: %v\n", objName, err)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, apperrors.NewNotFound("image", objName)
+		}
+		return nil, apperrors.NewInternal()
+	}
+
+	return rc, nil
+}
+
+// SignURL creates a V4 signed URL for an object, using the service
+// account the client was created with to sign it
+func (r *gcImageRepository) SignURL(ctx context.Context, objName string, ttl time.Duration) (string, error) {
+	bckt := r.Storage.Bucket(r.BucketName)
+
+	signedURL, err := bckt.SignedURL(objName, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(ttl),
+	})
+	if err != nil {
+		log.Printf("Failed to sign URL for image object with ID: %s: %v\n", objName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return signedURL, nil
+}
+
+// SignUpload creates a V4 signed URL to put an object. Cloud storage refuses
+// bodies outside of the signed x-goog-content-length-range header
+func (r *gcImageRepository) SignUpload(ctx context.Context, objName string, contentType string, size int64, ttl time.Duration) (*model.ImageUpload, error) {
+	bckt := r.Storage.Bucket(r.BucketName)
+	lengthRange := fmt.Sprintf("0,%d", size)
+
+	signedURL, err := bckt.SignedURL(objName, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      http.MethodPut,
+		ContentType: contentType,
+		Headers:     []string{"x-goog-content-length-range:" + lengthRange},
+		Expires:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		log.Printf("Failed to sign upload for image object with ID: %s: %v\n", objName, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return &model.ImageUpload{
+		ObjectName: objName,
+		URL:        signedURL,
+		Method:     http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":                contentType,
+			"x-goog-content-length-range": lengthRange,
+		},
+	}, nil
+}
+
+// List pages through every object in the bucket
+func (r *gcImageRepository) List(ctx context.Context) ([]*model.StoredImage, error) {
+	bckt := r.Storage.Bucket(r.BucketName)
+	it := bckt.Objects(ctx, nil)
+
+	images := []*model.StoredImage{}
+
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Printf("Failed to list image objects in GC storage bucket: %s: %v\n", r.BucketName, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		images = append(images, &model.StoredImage{
+			ObjectName: attrs.Name,
+			Size:       attrs.Size,
+			UpdatedAt:  attrs.Updated,
+		})
+	}
+
+	return images, nil
+}
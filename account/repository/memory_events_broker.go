@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// MemoryEventsBroker is an in-process implementation of service layer
+// EventsBroker, for running without redis and for checking published
+// events. Events are kept for the lifetime of the broker
+type MemoryEventsBroker struct {
+	mu          sync.Mutex
+	events      []*model.UserEvent
+	subscribers []chan *model.UserEvent
+}
+
+// NewMemoryEventsBroker is a factory for initializing an in-memory EventsBroker
+func NewMemoryEventsBroker() *MemoryEventsBroker {
+	return &MemoryEventsBroker{}
+}
+
+// Publish stores the event and hands it to every subscriber. Subscribers
+// which are not keeping up miss the event rather than blocking the publisher
+func (b *MemoryEventsBroker) Publish(ctx context.Context, e *model.UserEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = append(b.events, e)
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("dropped event: %v for slow subscriber\n", e.ID)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe returns a channel receiving every event published from now on
+func (b *MemoryEventsBroker) Subscribe(buffer int) <-chan *model.UserEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan *model.UserEvent, buffer)
+	b.subscribers = append(b.subscribers, ch)
+
+	return ch
+}
+
+// Events returns every event published so far, oldest first
+func (b *MemoryEventsBroker) Events() []*model.UserEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make([]*model.UserEvent, len(b.events))
+	copy(events, b.events)
+
+	return events
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgAdminActionRepository is data/repository implementation
+// of service layer AdminActionRepository
+type pgAdminActionRepository struct {
+	DB *sqlx.DB
+}
+
+// NewAdminActionRepository is a factory for initializing admin action repositories
+func NewAdminActionRepository(db *sqlx.DB) model.AdminActionRepository {
+	return &pgAdminActionRepository{
+		DB: db,
+	}
+}
+
+// Create records an admin action in the request's realm, filling in its id and creation time
+func (r *pgAdminActionRepository) Create(ctx context.Context, a *model.AdminAction) error {
+	query := `
+		INSERT INTO admin_actions (actor_uid, target_uid, action, detail, realm_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, a, query, a.ActorUID, a.TargetUID, a.Action, a.Detail, model.RealmIDFromContext(ctx)); err != nil {
+		log.Printf("Could not record admin action: %+v. Reason: %v\n", a, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgAuditEventRepository is data/repository implementation
+// of service layer AuditEventRepository
+type pgAuditEventRepository struct {
+	DB *sqlx.DB
+}
+
+// NewAuditEventRepository is a factory for initializing audit event repositories
+func NewAuditEventRepository(db *sqlx.DB) model.AuditEventRepository {
+	return &pgAuditEventRepository{
+		DB: db,
+	}
+}
+
+// Create appends an event to the audit log, filling in its id and creation time.
+// It belongs to the request's realm, or to the user's when ctx is not tied to one
+func (r *pgAuditEventRepository) Create(ctx context.Context, e *model.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (uid, actor_uid, event, outcome, ip, user_agent, detail, realm_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), (SELECT realm_id FROM users WHERE uid = $1), 'default'))
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, e, query, e.UID, e.ActorUID, e.Event, e.Outcome, e.IP, e.UserAgent, e.Detail, model.RealmScope(ctx)); err != nil {
+		log.Printf("Could not record audit event: %+v. Reason: %v\n", e, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// List returns the events matching the filter, newest first. Only events of
+// the request's realm are returned, or of any realm when ctx is not tied to one
+func (r *pgAuditEventRepository) List(ctx context.Context, f *model.AuditFilter) ([]*model.AuditEvent, error) {
+	query := `
+		SELECT * FROM audit_events
+		WHERE ($1::uuid IS NULL OR uid = $1)
+		AND ($2::uuid IS NULL OR actor_uid = $2)
+		AND ($3 = '' OR event = $3)
+		AND ($4 = '' OR outcome = $4)
+		AND ($5 = '' OR ip = $5)
+		AND ($6::timestamptz IS NULL OR created_at >= $6)
+		AND ($7::timestamptz IS NULL OR created_at < $7)
+		AND ($8 = 0 OR id < $8)
+		AND ($10 = '' OR realm_id = $10)
+		ORDER BY id DESC
+		LIMIT $9;
+	`
+
+	events := []*model.AuditEvent{}
+
+	if err := r.DB.SelectContext(ctx, &events, query, f.UID, f.ActorUID, f.Event, f.Outcome, f.IP, f.Since, f.Until, f.Before, f.Limit, model.RealmScope(ctx)); err != nil {
+		log.Printf("error listing audit events for filter: %+v: %v\n", f, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return events, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgImageReviewRepository is data/repository implementation
+// of service layer ImageReviewRepository
+type pgImageReviewRepository struct {
+	DB *sqlx.DB
+}
+
+// NewImageReviewRepository is a factory for initializing image review repositories
+func NewImageReviewRepository(db *sqlx.DB) model.ImageReviewRepository {
+	return &pgImageReviewRepository{
+		DB: db,
+	}
+}
+
+// Create stores a review, filling in its id and creation time
+func (r *pgImageReviewRepository) Create(ctx context.Context, rv *model.ImageReview) error {
+	query := `
+		INSERT INTO image_reviews (uid, image_urls, status, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at;
+	`
+
+	if err := conn(ctx, r.DB).GetContext(ctx, rv, query, rv.UID, rv.ImageURLs, rv.Status, rv.Reason); err != nil {
+		log.Printf("Could not create image review for uid: %v. Reason: %v\n", rv.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Update saves a review's status, the reason for it and who decided
+func (r *pgImageReviewRepository) Update(ctx context.Context, rv *model.ImageReview) error {
+	query := `
+		UPDATE image_reviews
+		SET status=$2, reason=$3, reviewed_by=$4, reviewed_at=$5
+		WHERE id=$1;
+	`
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, rv.ID, rv.Status, rv.Reason, rv.ReviewedBy, rv.ReviewedAt); err != nil {
+		log.Printf("Could not update image review: %v. Reason: %v\n", rv.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByID fetches a review by its id, locking it
+// until the end of the caller's transaction
+func (r *pgImageReviewRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.ImageReview, error) {
+	rv := &model.ImageReview{}
+
+	if err := conn(ctx, r.DB).GetContext(ctx, rv, "SELECT * FROM image_reviews WHERE id=$1 FOR UPDATE", id); err != nil {
+		return nil, apperrors.NewNotFound("image review", id.String())
+	}
+
+	return rv, nil
+}
+
+// List returns up to limit reviews with a status for users of the
+// request's realm, oldest first so they are reviewed in order
+func (r *pgImageReviewRepository) List(ctx context.Context, status string, limit int) ([]*model.ImageReview, error) {
+	query := `
+		SELECT rv.* FROM image_reviews rv
+		JOIN users u ON u.uid = rv.uid
+		WHERE rv.status = $1
+		AND u.realm_id = $2
+		ORDER BY rv.created_at
+		LIMIT $3;
+	`
+
+	reviews := []*model.ImageReview{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &reviews, query, status, model.RealmIDFromContext(ctx), limit); err != nil {
+		log.Printf("error listing image reviews with status: %v: %v\n", status, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return reviews, nil
+}
+
+// FindStalePending returns up to limit reviews of users of every realm
+// which were still pending at the given time, oldest first
+func (r *pgImageReviewRepository) FindStalePending(ctx context.Context, before time.Time, limit int) ([]*model.ImageReview, error) {
+	query := `
+		SELECT * FROM image_reviews
+		WHERE status = $1 AND created_at < $2
+		ORDER BY created_at
+		LIMIT $3;
+	`
+
+	reviews := []*model.ImageReview{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &reviews, query, model.ImageReviewPending, before, limit); err != nil {
+		log.Printf("error finding stale pending image reviews: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return reviews, nil
+}
+
+// HoldsImage reports whether a pending or quarantined review of a user of
+// any realm holds an image stored as objName. Reviews hold URLs, or object
+// names when image storage is private, so either is matched
+func (r *pgImageReviewRepository) HoldsImage(ctx context.Context, objName string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM image_reviews rv, jsonb_each_text(rv.image_urls) i
+			WHERE rv.status IN ($2, $3)
+			AND (i.value = $1 OR right(i.value, length($1) + 1) = '/' || $1)
+		);
+	`
+
+	var held bool
+
+	if err := conn(ctx, r.DB).GetContext(ctx, &held, query, objName, model.ImageReviewPending, model.ImageReviewQuarantined); err != nil {
+		log.Printf("error checking image reviews for object: %v: %v\n", objName, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return held, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgInvitationRepository is data/repository implementation
+// of service layer InvitationRepository
+type pgInvitationRepository struct {
+	DB *sqlx.DB
+}
+
+// NewInvitationRepository is a factory for initializing invitation repositories
+func NewInvitationRepository(db *sqlx.DB) model.InvitationRepository {
+	return &pgInvitationRepository{
+		DB: db,
+	}
+}
+
+// invitationColumns selects invitations along with the name of their
+// organization, reporting pending invitations which have expired as expired
+const invitationColumns = `
+	SELECT i.id, i.org_id, o.name AS org_name, i.email, i.role, i.invited_by, i.token_hash,
+		CASE WHEN i.status = 'pending' AND i.expires_at <= now() THEN 'expired' ELSE i.status END AS status,
+		i.expires_at, i.created_at, i.accepted_by, i.accepted_at, i.revoked_at
+	FROM invitations i
+	JOIN organizations o ON o.id = i.org_id
+`
+
+// Create stores an invitation, filling in its id and creation time
+func (r *pgInvitationRepository) Create(ctx context.Context, inv *model.Invitation) error {
+	query := `
+		INSERT INTO invitations (org_id, email, role, invited_by, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at;
+	`
+
+	if err := conn(ctx, r.DB).GetContext(ctx, inv, query, inv.OrgID, inv.Email, inv.Role, inv.InvitedBy, inv.TokenHash, inv.ExpiresAt); err != nil {
+		log.Printf("Could not invite email: %v to organization: %v. Reason: %v\n", inv.Email, inv.OrgID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Update saves an invitation's status and when it was accepted or revoked
+func (r *pgInvitationRepository) Update(ctx context.Context, inv *model.Invitation) error {
+	query := `
+		UPDATE invitations
+		SET status=$2, accepted_by=$3, accepted_at=$4, revoked_at=$5
+		WHERE id=$1;
+	`
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, inv.ID, inv.Status, inv.AcceptedBy, inv.AcceptedAt, inv.RevokedAt); err != nil {
+		log.Printf("Could not update invitation: %v. Reason: %v\n", inv.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByID fetches an invitation by its id, locking it
+// until the end of the caller's transaction
+func (r *pgInvitationRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Invitation, error) {
+	inv := &model.Invitation{}
+
+	if err := conn(ctx, r.DB).GetContext(ctx, inv, invitationColumns+"WHERE i.id=$1 FOR UPDATE OF i", id); err != nil {
+		return nil, apperrors.NewNotFound("invitation", id.String())
+	}
+
+	return inv, nil
+}
+
+// FindByTokenHash fetches the invitation with the given token hash to an
+// organization of the request's realm, locking it until the end of the
+// caller's transaction
+func (r *pgInvitationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	inv := &model.Invitation{}
+
+	query := invitationColumns + "WHERE i.token_hash=$1 AND o.realm_id=$2 FOR UPDATE OF i"
+
+	if err := conn(ctx, r.DB).GetContext(ctx, inv, query, tokenHash, model.RealmIDFromContext(ctx)); err != nil {
+		return nil, apperrors.NewNotFound("invitation", "token")
+	}
+
+	return inv, nil
+}
+
+// ListByInviter returns the invitations a user has sent, newest first
+func (r *pgInvitationRepository) ListByInviter(ctx context.Context, uid uuid.UUID) ([]*model.Invitation, error) {
+	return r.list(ctx, invitationColumns+"WHERE i.invited_by=$1 ORDER BY i.created_at DESC", uid)
+}
+
+// ListByEmail returns the pending invitations sent to an email by
+// organizations of the request's realm, newest first
+func (r *pgInvitationRepository) ListByEmail(ctx context.Context, email string) ([]*model.Invitation, error) {
+	query := invitationColumns + `
+		WHERE lower(i.email) = lower($1)
+		AND o.realm_id = $2
+		AND i.status = 'pending'
+		AND i.expires_at > now()
+		ORDER BY i.created_at DESC
+	`
+
+	return r.list(ctx, query, email, model.RealmIDFromContext(ctx))
+}
+
+func (r *pgInvitationRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.Invitation, error) {
+	invitations := []*model.Invitation{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &invitations, query, args...); err != nil {
+		log.Printf("error listing invitations: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return invitations, nil
+}
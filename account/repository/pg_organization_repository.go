@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgOrganizationRepository is data/repository implementation
+// of service layer OrganizationRepository
+type pgOrganizationRepository struct {
+	DB *sqlx.DB
+}
+
+// NewOrganizationRepository is a factory for initializing organization repositories
+func NewOrganizationRepository(db *sqlx.DB) model.OrganizationRepository {
+	return &pgOrganizationRepository{
+		DB: db,
+	}
+}
+
+// Create stores an organization in the realm of the request, filling in its id and timestamps
+func (r *pgOrganizationRepository) Create(ctx context.Context, o *model.Organization) error {
+	query := `
+		INSERT INTO organizations (name, realm_id)
+		VALUES ($1, $2)
+		RETURNING id, name, created_at, updated_at;
+	`
+
+	if err := conn(ctx, r.DB).GetContext(ctx, o, query, o.Name, model.RealmIDFromContext(ctx)); err != nil {
+		log.Printf("Could not create organization: %v. Reason: %v\n", o.Name, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Update saves an organization's name
+func (r *pgOrganizationRepository) Update(ctx context.Context, o *model.Organization) error {
+	query := `
+		UPDATE organizations
+		SET name=$2, updated_at=now()
+		WHERE id=$1
+		RETURNING id, name, created_at, updated_at;
+	`
+
+	if err := conn(ctx, r.DB).GetContext(ctx, o, query, o.ID, o.Name); err != nil {
+		log.Printf("Could not update organization: %v. Reason: %v\n", o.ID, err)
+		return apperrors.NewNotFound("organization", o.ID.String())
+	}
+
+	return nil
+}
+
+// Delete removes an organization along with its memberships
+func (r *pgOrganizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.DB).ExecContext(ctx, "DELETE FROM organizations WHERE id=$1", id)
+	if err != nil {
+		log.Printf("Could not delete organization: %v. Reason: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NewNotFound("organization", id.String())
+	}
+
+	return nil
+}
+
+// FindByID fetches an organization by its id
+func (r *pgOrganizationRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Organization, error) {
+	o := &model.Organization{}
+
+	query := "SELECT id, name, created_at, updated_at FROM organizations WHERE id=$1"
+
+	if err := conn(ctx, r.DB).GetContext(ctx, o, query, id); err != nil {
+		return nil, apperrors.NewNotFound("organization", id.String())
+	}
+
+	return o, nil
+}
+
+// ListByUser returns the organizations a user belongs to
+// along with their role in each, ordered by name
+func (r *pgOrganizationRepository) ListByUser(ctx context.Context, uid uuid.UUID) ([]*model.Organization, error) {
+	query := `
+		SELECT o.id, o.name, m.role, o.created_at, o.updated_at
+		FROM organizations o
+		JOIN org_members m ON m.org_id = o.id
+		WHERE m.uid = $1
+		ORDER BY o.name, o.id;
+	`
+
+	orgs := []*model.Organization{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &orgs, query, uid); err != nil {
+		log.Printf("Unable to list organizations of uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return orgs, nil
+}
+
+// memberColumns selects members joined with the details of their users
+const memberColumns = `
+	SELECT m.org_id, m.uid, m.role, u.name, u.email, m.joined_at
+	FROM org_members m
+	JOIN users u ON u.uid = m.uid AND u.deleted_at IS NULL
+`
+
+// FindMember fetches a user's membership of an organization
+func (r *pgOrganizationRepository) FindMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	m := &model.OrgMember{}
+
+	query := memberColumns + "WHERE m.org_id=$1 AND m.uid=$2"
+
+	if err := conn(ctx, r.DB).GetContext(ctx, m, query, orgID, uid); err != nil {
+		return nil, apperrors.NewNotFound("member", uid.String())
+	}
+
+	return m, nil
+}
+
+// ListMembers returns every member of an organization, earliest to join first
+func (r *pgOrganizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	members := []*model.OrgMember{}
+
+	query := memberColumns + "WHERE m.org_id=$1 ORDER BY m.joined_at, m.uid"
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &members, query, orgID); err != nil {
+		log.Printf("Unable to list members of organization: %v. Err: %v\n", orgID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return members, nil
+}
+
+// AddMember adds a user to an organization, filling in when they joined
+func (r *pgOrganizationRepository) AddMember(ctx context.Context, m *model.OrgMember) error {
+	query := `
+		INSERT INTO org_members (org_id, uid, role)
+		VALUES ($1, $2, $3)
+		RETURNING joined_at;
+	`
+
+	if err := conn(ctx, r.DB).GetContext(ctx, &m.JoinedAt, query, m.OrgID, m.UID, m.Role); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			return apperrors.NewConflict("member", m.UID.String())
+		}
+
+		log.Printf("Could not add uid: %v to organization: %v. Reason: %v\n", m.UID, m.OrgID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateMember saves a member's role
+func (r *pgOrganizationRepository) UpdateMember(ctx context.Context, m *model.OrgMember) error {
+	query := "UPDATE org_members SET role=$3 WHERE org_id=$1 AND uid=$2"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, m.OrgID, m.UID, m.Role)
+	if err != nil {
+		log.Printf("Could not update role of uid: %v in organization: %v. Reason: %v\n", m.UID, m.OrgID, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NewNotFound("member", m.UID.String())
+	}
+
+	return nil
+}
+
+// RemoveMember removes a user from an organization
+func (r *pgOrganizationRepository) RemoveMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) error {
+	result, err := conn(ctx, r.DB).ExecContext(ctx, "DELETE FROM org_members WHERE org_id=$1 AND uid=$2", orgID, uid)
+	if err != nil {
+		log.Printf("Could not remove uid: %v from organization: %v. Reason: %v\n", uid, orgID, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NewNotFound("member", uid.String())
+	}
+
+	return nil
+}
+
+// CountOwners returns how many owners an organization has, locking
+// the owners' memberships until the end of the caller's transaction
+// so concurrent changes cannot leave the organization without an owner
+func (r *pgOrganizationRepository) CountOwners(ctx context.Context, orgID uuid.UUID) (int, error) {
+	query := `
+		SELECT count(*) FROM (
+			SELECT 1 FROM org_members
+			WHERE org_id=$1 AND role='owner'
+			FOR UPDATE
+		) owners;
+	`
+
+	var count int
+
+	if err := conn(ctx, r.DB).GetContext(ctx, &count, query, orgID); err != nil {
+		log.Printf("Unable to count owners of organization: %v. Err: %v\n", orgID, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgOutboxRepository is data/repository implementation
+// of service layer OutboxRepository
+type pgOutboxRepository struct {
+	DB *sqlx.DB
+}
+
+// NewOutboxRepository is a factory for initializing outbox repositories
+func NewOutboxRepository(db *sqlx.DB) model.OutboxRepository {
+	return &pgOutboxRepository{
+		DB: db,
+	}
+}
+
+// Add writes an event to the outbox. It should be called within the
+// same transaction as the change the event describes
+func (r *pgOutboxRepository) Add(ctx context.Context, e *model.UserEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("could not marshal event: %v: %v\n", e.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	query := `
+		INSERT INTO outbox (event_id, uid, event_type, payload)
+		VALUES ($1, $2, $3, $4);
+	`
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, e.ID, e.UID, e.Type, payload); err != nil {
+		log.Printf("could not add event: %v to outbox: %v\n", e.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FetchPending locks up to limit messages which are due to be published.
+// Only the oldest pending message of each user is returned, so a user's
+// events are published in order. Messages locked by another relay are skipped
+func (r *pgOutboxRepository) FetchPending(ctx context.Context, limit int) ([]*model.OutboxMessage, error) {
+	query := `
+		SELECT * FROM outbox o
+		WHERE o.published_at IS NULL
+		AND o.dead_lettered_at IS NULL
+		AND o.next_attempt_at <= now()
+		AND NOT EXISTS (
+			SELECT 1 FROM outbox p
+			WHERE p.uid = o.uid
+			AND p.id < o.id
+			AND p.published_at IS NULL
+			AND p.dead_lettered_at IS NULL
+		)
+		ORDER BY o.id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED;
+	`
+
+	msgs := []*model.OutboxMessage{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &msgs, query, limit); err != nil {
+		log.Printf("could not fetch pending outbox messages: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return msgs, nil
+}
+
+// MarkPublished records that a message has been delivered to the broker
+func (r *pgOutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	query := "UPDATE outbox SET published_at=now(), attempts=attempts+1 WHERE id=$1"
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, id); err != nil {
+		log.Printf("could not mark outbox message: %v published: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt to publish a message and when to try again
+func (r *pgOutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, nextAttemptAt time.Time) error {
+	query := "UPDATE outbox SET attempts=attempts+1, last_error=$2, next_attempt_at=$3 WHERE id=$1"
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, id, reason, nextAttemptAt); err != nil {
+		log.Printf("could not mark outbox message: %v failed: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// DeadLetter gives up on publishing a message, letting later
+// messages of the same user be published
+func (r *pgOutboxRepository) DeadLetter(ctx context.Context, id int64, reason string) error {
+	query := "UPDATE outbox SET attempts=attempts+1, last_error=$2, dead_lettered_at=now() WHERE id=$1"
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, id, reason); err != nil {
+		log.Printf("could not dead letter outbox message: %v: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// DeletePublished removes messages published before the given time,
+// returning how many were removed. Dead lettered messages are kept
+func (r *pgOutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := "DELETE FROM outbox WHERE published_at < $1"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, before)
+	if err != nil {
+		log.Printf("could not delete published outbox messages: %v\n", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	n, _ := result.RowsAffected()
+
+	return n, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgRealmRepository is data/repository implementation
+// of service layer RealmRepository
+type pgRealmRepository struct {
+	DB *sqlx.DB
+}
+
+// NewRealmRepository is a factory for initializing realm repositories
+func NewRealmRepository(db *sqlx.DB) model.RealmRepository {
+	return &pgRealmRepository{
+		DB: db,
+	}
+}
+
+// realmRow is a row of realms, with its keys as PEM and branding as JSON
+type realmRow struct {
+	ID              string         `db:"id"`
+	Name            string         `db:"name"`
+	Hosts           pq.StringArray `db:"hosts"`
+	PrivateKey      string         `db:"private_key"`
+	PublicKey       string         `db:"public_key"`
+	RefreshSecret   string         `db:"refresh_secret"`
+	IDTokenExp      int64          `db:"id_token_exp"`
+	RefreshTokenExp int64          `db:"refresh_token_exp"`
+	Branding        []byte         `db:"branding"`
+	CreatedAt       time.Time      `db:"created_at"`
+}
+
+func (row *realmRow) realm() (*model.Realm, error) {
+	r := &model.Realm{
+		ID:                    row.ID,
+		Name:                  row.Name,
+		Hosts:                 []string(row.Hosts),
+		RefreshSecret:         row.RefreshSecret,
+		IDExpirationSecs:      row.IDTokenExp,
+		RefreshExpirationSecs: row.RefreshTokenExp,
+		CreatedAt:             row.CreatedAt,
+	}
+
+	if err := json.Unmarshal(row.Branding, &r.Branding); err != nil {
+		return nil, fmt.Errorf("invalid branding: %w", err)
+	}
+
+	// a realm signs with its own keys only when it has both of them
+	if row.PrivateKey != "" && row.PublicKey != "" {
+		var err error
+
+		if r.PrivKey, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(row.PrivateKey)); err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+
+		if r.PubKey, err = jwt.ParseRSAPublicKeyFromPEM([]byte(row.PublicKey)); err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// List returns every realm. Realms with invalid settings are logged and
+// left out, so one misconfigured realm does not take down the others
+func (r *pgRealmRepository) List(ctx context.Context) ([]*model.Realm, error) {
+	rows := []*realmRow{}
+
+	if err := r.DB.SelectContext(ctx, &rows, "SELECT * FROM realms ORDER BY id"); err != nil {
+		log.Printf("Unable to list realms. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	realms := make([]*model.Realm, 0, len(rows))
+
+	for _, row := range rows {
+		realm, err := row.realm()
+		if err != nil {
+			log.Printf("Skipping realm: %v. Err: %v\n", row.ID, err)
+			continue
+		}
+
+		realms = append(realms, realm)
+	}
+
+	return realms, nil
+}
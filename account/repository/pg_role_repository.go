@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgRoleRepository is data/repository implementation
+// of service layer RoleRepository
+type pgRoleRepository struct {
+	DB *sqlx.DB
+}
+
+// NewRoleRepository is a factory for initializing role repositories
+func NewRoleRepository(db *sqlx.DB) model.RoleRepository {
+	return &pgRoleRepository{
+		DB: db,
+	}
+}
+
+// rolePermission is a row of roles left joined with their permissions
+type rolePermission struct {
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Permission  sql.NullString `db:"permission"`
+}
+
+// List returns every role along with the permissions it grants
+func (r *pgRoleRepository) List(ctx context.Context) ([]*model.Role, error) {
+	query := `
+		SELECT r.name, r.description, rp.permission
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		ORDER BY r.name, rp.permission;
+	`
+
+	rows := []rolePermission{}
+	if err := conn(ctx, r.DB).SelectContext(ctx, &rows, query); err != nil {
+		log.Printf("unable to list roles: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	roles := []*model.Role{}
+	for _, row := range rows {
+		if len(roles) == 0 || roles[len(roles)-1].Name != row.Name {
+			roles = append(roles, &model.Role{
+				Name:        row.Name,
+				Description: row.Description,
+				Permissions: []string{},
+			})
+		}
+
+		if row.Permission.Valid {
+			role := roles[len(roles)-1]
+			role.Permissions = append(role.Permissions, row.Permission.String)
+		}
+	}
+
+	return roles, nil
+}
+
+// Assign grants a role to a user. Granting a role the user already holds is a no-op
+func (r *pgRoleRepository) Assign(ctx context.Context, uid uuid.UUID, role string, grantedBy *uuid.UUID) error {
+	query := `
+		INSERT INTO user_roles (uid, role, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (uid, role) DO NOTHING;
+	`
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, role, grantedBy); err != nil {
+		// either the user or the role does not exist
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "foreign_key_violation" {
+			log.Printf("Could not assign role: %v to uid: %v. Reason: %v\n", role, uid, err.Code.Name())
+			if err.Constraint == "user_roles_role_fkey" {
+				return apperrors.NewNotFound("role", role)
+			}
+			return apperrors.NewNotFound("uid", uid.String())
+		}
+
+		log.Printf("Could not assign role: %v to uid: %v. Reason: %v\n", role, uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Revoke removes a role from a user
+func (r *pgRoleRepository) Revoke(ctx context.Context, uid uuid.UUID, role string) error {
+	query := "DELETE FROM user_roles WHERE uid=$1 AND role=$2"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, role)
+	if err != nil {
+		log.Printf("Could not revoke role: %v from uid: %v. Reason: %v\n", role, uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n < 1 {
+		return apperrors.NewNotFound("role", role)
+	}
+
+	return nil
+}
+
+// CountUsers returns the number of users of the request's realm holding a
+// role. Their grants are locked until the end of the caller's transaction,
+// so none of them can be revoked before the caller is done
+func (r *pgRoleRepository) CountUsers(ctx context.Context, role string) (int, error) {
+	query := `
+		SELECT ur.uid
+		FROM user_roles ur
+		JOIN users u ON u.uid = ur.uid
+		WHERE ur.role = $1 AND u.realm_id = $2 AND u.deleted_at IS NULL
+		FOR UPDATE OF ur;
+	`
+
+	uids := []uuid.UUID{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &uids, query, role, model.RealmIDFromContext(ctx)); err != nil {
+		log.Printf("Could not count users with role: %v. Reason: %v\n", role, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return len(uids), nil
+}
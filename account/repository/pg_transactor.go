@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// dbtx is implemented by both *sqlx.DB and *sqlx.Tx
+type dbtx interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+}
+
+type txKey struct{}
+
+// conn returns the transaction started by pgTransactor for ctx,
+// or db when the caller is not within a transaction
+func conn(ctx context.Context, db *sqlx.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// pgTransactor is a postgres implementation of service layer Transactor
+type pgTransactor struct {
+	DB *sqlx.DB
+}
+
+// NewTransactor is a factory for initializing a Transactor
+// shared by all of the postgres repositories
+func NewTransactor(db *sqlx.DB) model.Transactor {
+	return &pgTransactor{
+		DB: db,
+	}
+}
+
+// WithinTransaction runs fn in a transaction which postgres repositories
+// called with the ctx passed to fn take part in. The transaction is committed
+// if fn returns nil and rolled back otherwise. Nested calls join the outer transaction
+func (t *pgTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("could not begin transaction: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("could not rollback transaction: %v\n", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("could not commit transaction: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -25,9 +27,11 @@ func NewUserRepository(db *sqlx.DB) model.UserRepository {
 
 // Create reaches out to database SQLX api
 func (r *pgUserRepository) Create(ctx context.Context, u *model.User) error {
-	query := "INSERT INTO users (email, password) VALUES ($1, $2) RETURNING *"
+	query := "INSERT INTO users (realm_id, email, password, email_verified) VALUES ($1, $2, $3, $4) RETURNING *"
 
-	if err := r.DB.GetContext(ctx, u, query, u.Email, u.Password); err != nil {
+	realmID := model.RealmIDFromContext(ctx)
+
+	if err := conn(ctx, r.DB).GetContext(ctx, u, query, realmID, u.Email, u.Password, u.EmailVerified); err != nil {
 		// check unique constraint
 		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
 			log.Printf("Could not create a user with email: %v. Reason: %v\n", u.Email, err.Code.Name())
@@ -40,48 +44,74 @@ func (r *pgUserRepository) Create(ctx context.Context, u *model.User) error {
 	return nil
 }
 
+// FindByID finds a user in the realm of the request, or in any realm
+// when ctx is not tied to one
 func (r *pgUserRepository) FindByID(ctx context.Context, uid uuid.UUID) (*model.User, error) {
 	user := &model.User{}
 
-	query := "SELECT * FROM users WHERE uid=$1"
+	query := "SELECT * FROM users WHERE uid=$1 AND ($2 = '' OR realm_id=$2) AND deleted_at IS NULL"
 
 	// we need to actually check errors as it could be something other than not found
-	if err := r.DB.GetContext(ctx, user, query, uid); err != nil {
+	if err := conn(ctx, r.DB).GetContext(ctx, user, query, uid, model.RealmScope(ctx)); err != nil {
 		return user, apperrors.NewNotFound("uid", uid.String())
 	}
 
+	if err := r.loadRoles(ctx, user); err != nil {
+		return user, err
+	}
+
+	if err := r.loadOrgs(ctx, user); err != nil {
+		return user, err
+	}
+
 	return user, nil
 }
 
+// FindByEmail finds a user in the realm of the request, as emails
+// are only unique within a realm
 func (r *pgUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	user := &model.User{}
 
-	query := "SELECT * FROM users WHERE email=$1"
+	query := "SELECT * FROM users WHERE email=$1 AND realm_id=$2 AND deleted_at IS NULL"
 
 	// we need to actually check errors as it could be something other than not found
-	if err := r.DB.GetContext(ctx, user, query, email); err != nil {
+	if err := conn(ctx, r.DB).GetContext(ctx, user, query, email, model.RealmIDFromContext(ctx)); err != nil {
 		log.Printf("Unable to get user with email address: %v. Err: %v\n", email, err)
 		return user, apperrors.NewNotFound("email", email)
 	}
 
+	if err := r.loadRoles(ctx, user); err != nil {
+		return user, err
+	}
+
+	if err := r.loadOrgs(ctx, user); err != nil {
+		return user, err
+	}
+
 	return user, nil
 }
 
 func (r *pgUserRepository) Update(ctx context.Context, u *model.User) error {
 	query := `
 		UPDATE users
-		SET name=:name, email=:email, website=:website
-		WHERE uid=:uid
+		SET name=:name, email=:email, website=:website, email_verified=:email_verified
+		WHERE uid=:uid AND deleted_at IS NULL
 		RETURNING *;
 	`
 
-	nstmt, err := r.DB.PrepareNamedContext(ctx, query)
+	nstmt, err := conn(ctx, r.DB).PrepareNamedContext(ctx, query)
 	if err != nil {
 		log.Printf("unable to prepare user update query: %v\n", err)
 		return apperrors.NewInternal()
 	}
 
 	if err := nstmt.GetContext(ctx, u, u); err != nil {
+		// check unique constraint
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not update email to: %v. Reason: %v\n", u.Email, err.Code.Name())
+			return apperrors.NewConflict("email", u.Email)
+		}
+
 		log.Printf("failed to update detaile for user: %v\n", u)
 		return apperrors.NewInternal()
 	}
@@ -89,18 +119,19 @@ func (r *pgUserRepository) Update(ctx context.Context, u *model.User) error {
 	return nil
 }
 
-func (r *pgUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
+// UpdateImage replaces the URLs of every size of the user's profile image
+func (r *pgUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURLs model.ImageURLs) (*model.User, error) {
 	query := `
 		UPDATE users
-		SET image_url=$2
-		WHERE uid=$1
+		SET image_urls=$2
+		WHERE uid=$1 AND deleted_at IS NULL
 		RETURNING *;
 	`
 
 	// must be instantiated to scan into ref using 'GetContext'
 	u := &model.User{}
 
-	err := r.DB.GetContext(ctx, u, query, uid, imageURL)
+	err := conn(ctx, r.DB).GetContext(ctx, u, query, uid, imageURLs)
 	if err != nil {
 		log.Printf("error updating image url in database: %v\n", err)
 		return nil, apperrors.NewInternal()
@@ -108,3 +139,253 @@ func (r *pgUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, image
 
 	return u, nil
 }
+
+// UpdateImageHistory replaces the previous profile images kept for the user
+func (r *pgUserRepository) UpdateImageHistory(ctx context.Context, uid uuid.UUID, history model.ImageHistory) error {
+	query := "UPDATE users SET image_history=$2 WHERE uid=$1 AND deleted_at IS NULL"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, history)
+	if err != nil {
+		log.Printf("error updating image history for uid: %v: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n < 1 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// loadRoles populates the user's roles and the union of the permissions they grant
+func (r *pgUserRepository) loadRoles(ctx context.Context, u *model.User) error {
+	query := `
+		SELECT ur.role, rp.permission
+		FROM user_roles ur
+		LEFT JOIN role_permissions rp ON rp.role = ur.role
+		WHERE ur.uid=$1;
+	`
+
+	rows := []struct {
+		Role       string         `db:"role"`
+		Permission sql.NullString `db:"permission"`
+	}{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &rows, query, u.UID); err != nil {
+		log.Printf("Unable to load roles for uid: %v. Err: %v\n", u.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	u.Roles = []string{}
+	u.Permissions = []string{}
+	roleSeen := map[string]bool{}
+	permSeen := map[string]bool{}
+
+	for _, row := range rows {
+		if !roleSeen[row.Role] {
+			roleSeen[row.Role] = true
+			u.Roles = append(u.Roles, row.Role)
+		}
+
+		if row.Permission.Valid && !permSeen[row.Permission.String] {
+			permSeen[row.Permission.String] = true
+			u.Permissions = append(u.Permissions, row.Permission.String)
+		}
+	}
+
+	return nil
+}
+
+// loadOrgs populates the user's role in each organization they belong to
+func (r *pgUserRepository) loadOrgs(ctx context.Context, u *model.User) error {
+	rows := []struct {
+		OrgID uuid.UUID `db:"org_id"`
+		Role  string    `db:"role"`
+	}{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &rows, "SELECT org_id, role FROM org_members WHERE uid=$1", u.UID); err != nil {
+		log.Printf("Unable to load organizations for uid: %v. Err: %v\n", u.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	u.Orgs = map[string]string{}
+
+	for _, row := range rows {
+		u.Orgs[row.OrgID.String()] = row.Role
+	}
+
+	return nil
+}
+
+// UpdatePassword sets the user's password hash, only finding users
+// in the realm of the request when ctx is tied to one
+func (r *pgUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := "UPDATE users SET password=$2 WHERE uid=$1 AND ($3 = '' OR realm_id=$3) AND deleted_at IS NULL"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, password, model.RealmScope(ctx))
+	if err != nil {
+		log.Printf("error updating password for uid: %v: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n < 1 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// UpdateStatus sets the account status along with the reason for it
+// until is only meaningful for suspensions and may be nil.
+// Only users of the request's realm are found when ctx is tied to one
+func (r *pgUserRepository) UpdateStatus(ctx context.Context, uid uuid.UUID, status string, reason string, until *time.Time) error {
+	query := "UPDATE users SET status=$2, status_reason=$3, suspended_until=$4 WHERE uid=$1 AND ($5 = '' OR realm_id=$5) AND deleted_at IS NULL"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, status, reason, until, model.RealmScope(ctx))
+	if err != nil {
+		log.Printf("error updating status for uid: %v: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n < 1 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// Search finds users whose email or name contains query, or whose uid equals it.
+// Results are ordered by uid, and only users with a uid greater than after
+// are returned so the last uid of a page can be used as a cursor.
+// Only users of the request's realm are searched
+func (r *pgUserRepository) Search(ctx context.Context, query string, after uuid.UUID, limit int) ([]*model.User, error) {
+	q := `
+		SELECT * FROM users
+		WHERE ($1 = '' OR email ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%' OR uid::text = $1)
+		AND uid > $2
+		AND realm_id = $4
+		AND deleted_at IS NULL
+		ORDER BY uid
+		LIMIT $3;
+	`
+
+	users := []*model.User{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &users, q, query, after, limit, model.RealmIDFromContext(ctx)); err != nil {
+		log.Printf("error searching users for query: %v: %v\n", query, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// Delete permanently removes a user, whether or not they have been soft deleted.
+// Only users of the request's realm are found when ctx is tied to one
+func (r *pgUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	query := "DELETE FROM users WHERE uid=$1 AND ($2 = '' OR realm_id=$2)"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, model.RealmScope(ctx))
+	if err != nil {
+		log.Printf("error deleting uid: %v: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n < 1 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// SoftDelete hides a user from every other query until they
+// are permanently removed by a purge after purgeAfter
+func (r *pgUserRepository) SoftDelete(ctx context.Context, uid uuid.UUID, purgeAfter time.Time) error {
+	query := "UPDATE users SET deleted_at=now(), purge_after=$2 WHERE uid=$1 AND deleted_at IS NULL"
+
+	result, err := conn(ctx, r.DB).ExecContext(ctx, query, uid, purgeAfter)
+	if err != nil {
+		log.Printf("error soft deleting uid: %v: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n < 1 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// FindPurgeable returns up to limit soft deleted users whose grace period ended before the given time
+func (r *pgUserRepository) FindPurgeable(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	query := `
+		SELECT * FROM users
+		WHERE deleted_at IS NOT NULL AND purge_after < $1
+		ORDER BY purge_after
+		LIMIT $2;
+	`
+
+	users := []*model.User{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &users, query, before, limit); err != nil {
+		log.Printf("error finding users to purge: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// ListImageURLs returns every distinct image URL, or object name when image
+// storage is private, stored for any user, including their previous images
+// and those waiting to be reviewed. Soft deleted users and users of every
+// realm are included, as their images must be kept until they are purged
+func (r *pgUserRepository) ListImageURLs(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT i.value
+		FROM users u, jsonb_each_text(u.image_urls) i
+		UNION
+		SELECT i.value
+		FROM users u, jsonb_array_elements(u.image_history) v, jsonb_each_text(v->'imageUrls') i
+		UNION
+		SELECT i.value
+		FROM image_reviews rv, jsonb_each_text(rv.image_urls) i
+		WHERE rv.status IN ('pending', 'quarantined');
+	`
+
+	urls := []string{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &urls, query); err != nil {
+		log.Printf("error listing image urls: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return urls, nil
+}
+
+// HasImage reports whether the profile image or a previous image of a user
+// of any realm is stored as objName. Soft deleted users are left out, as
+// their images are no longer shown. Images are stored as URLs, or object
+// names when image storage is private, so either is matched
+func (r *pgUserRepository) HasImage(ctx context.Context, objName string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM users u, jsonb_each_text(u.image_urls) i
+			WHERE u.deleted_at IS NULL
+			AND (i.value = $1 OR right(i.value, length($1) + 1) = '/' || $1)
+			UNION ALL
+			SELECT 1
+			FROM users u, jsonb_array_elements(u.image_history) v, jsonb_each_text(v->'imageUrls') i
+			WHERE u.deleted_at IS NULL
+			AND (i.value = $1 OR right(i.value, length($1) + 1) = '/' || $1)
+		);
+	`
+
+	var found bool
+
+	if err := conn(ctx, r.DB).GetContext(ctx, &found, query, objName); err != nil {
+		log.Printf("error checking users for image object: %v: %v\n", objName, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return found, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgWebhookDeliveryRepository is data/repository implementation
+// of service layer WebhookDeliveryRepository
+type pgWebhookDeliveryRepository struct {
+	DB *sqlx.DB
+}
+
+// NewWebhookDeliveryRepository is a factory for initializing webhook delivery repositories
+func NewWebhookDeliveryRepository(db *sqlx.DB) model.WebhookDeliveryRepository {
+	return &pgWebhookDeliveryRepository{
+		DB: db,
+	}
+}
+
+// Create queues a delivery. Events relayed more than once are
+// only queued once for each webhook
+func (r *pgWebhookDeliveryRepository) Create(ctx context.Context, d *model.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING;
+	`
+
+	if _, err := conn(ctx, r.DB).ExecContext(ctx, query, d.WebhookID, d.EventID, d.EventType, d.Payload); err != nil {
+		log.Printf("could not queue event: %v for webhook: %v: %v\n", d.EventID, d.WebhookID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ClaimDue returns up to limit pending deliveries which are due, pushing their
+// next attempt back by lease so other workers leave them alone while they are sent
+func (r *pgWebhookDeliveryRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = now() + $2 * interval '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending'
+			AND next_attempt_at <= now()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;
+	`
+
+	deliveries := []*model.WebhookDelivery{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &deliveries, query, limit, lease.Milliseconds()); err != nil {
+		log.Printf("could not claim due webhook deliveries: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return deliveries, nil
+}
+
+// Update saves the outcome of an attempt to send a delivery
+func (r *pgWebhookDeliveryRepository) Update(ctx context.Context, d *model.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status=:status, attempts=:attempts, response_status=:response_status,
+			last_error=:last_error, next_attempt_at=:next_attempt_at, delivered_at=:delivered_at
+		WHERE id=:id;
+	`
+
+	nstmt, err := conn(ctx, r.DB).PrepareNamedContext(ctx, query)
+	if err != nil {
+		log.Printf("unable to prepare webhook delivery update query: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if _, err := nstmt.ExecContext(ctx, d); err != nil {
+		log.Printf("could not update webhook delivery: %v: %v\n", d.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByID fetches a delivery by its id
+func (r *pgWebhookDeliveryRepository) FindByID(ctx context.Context, id int64) (*model.WebhookDelivery, error) {
+	d := &model.WebhookDelivery{}
+
+	if err := conn(ctx, r.DB).GetContext(ctx, d, "SELECT * FROM webhook_deliveries WHERE id=$1", id); err != nil {
+		return nil, apperrors.NewNotFound("delivery", fmt.Sprint(id))
+	}
+
+	return d, nil
+}
+
+// List returns a webhook's deliveries with ids below before, newest first.
+// Empty status matches deliveries of any status and zero before matches every id
+func (r *pgWebhookDeliveryRepository) List(ctx context.Context, webhookID uuid.UUID, status string, before int64, limit int) ([]*model.WebhookDelivery, error) {
+	query := `
+		SELECT * FROM webhook_deliveries
+		WHERE webhook_id = $1
+		AND ($2 = '' OR status = $2)
+		AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4;
+	`
+
+	deliveries := []*model.WebhookDelivery{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &deliveries, query, webhookID, status, before, limit); err != nil {
+		log.Printf("error listing deliveries of webhook: %v: %v\n", webhookID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return deliveries, nil
+}
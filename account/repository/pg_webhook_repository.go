@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// pgWebhookRepository is data/repository implementation
+// of service layer WebhookRepository
+type pgWebhookRepository struct {
+	DB *sqlx.DB
+}
+
+// NewWebhookRepository is a factory for initializing webhook repositories
+func NewWebhookRepository(db *sqlx.DB) model.WebhookRepository {
+	return &pgWebhookRepository{
+		DB: db,
+	}
+}
+
+// webhookRow is a row of webhooks, scanning its events array
+type webhookRow struct {
+	ID        uuid.UUID      `db:"id"`
+	URL       string         `db:"url"`
+	Events    pq.StringArray `db:"events"`
+	Secret    string         `db:"secret"`
+	Active    bool           `db:"active"`
+	RealmID   string         `db:"realm_id"`
+	CreatedAt time.Time      `db:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at"`
+}
+
+func (row *webhookRow) webhook() *model.Webhook {
+	return &model.Webhook{
+		ID:        row.ID,
+		URL:       row.URL,
+		Events:    []string(row.Events),
+		Secret:    row.Secret,
+		Active:    row.Active,
+		RealmID:   row.RealmID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+// Create stores a webhook in the request's realm, filling in its id and timestamps
+func (r *pgWebhookRepository) Create(ctx context.Context, w *model.Webhook) error {
+	query := `
+		INSERT INTO webhooks (url, events, secret, active, realm_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;
+	`
+
+	row := &webhookRow{}
+
+	if err := conn(ctx, r.DB).GetContext(ctx, row, query, w.URL, pq.StringArray(w.Events), w.Secret, w.Active, model.RealmIDFromContext(ctx)); err != nil {
+		log.Printf("Could not create webhook for url: %v. Reason: %v\n", w.URL, err)
+		return apperrors.NewInternal()
+	}
+
+	*w = *row.webhook()
+
+	return nil
+}
+
+// Update saves a webhook's url, events and whether it is active
+// Like the other lookups by id, only webhooks of the request's
+// realm are found, or of any realm when ctx is not tied to one
+func (r *pgWebhookRepository) Update(ctx context.Context, w *model.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url=$2, events=$3, active=$4, updated_at=now()
+		WHERE id=$1 AND ($5 = '' OR realm_id=$5)
+		RETURNING *;
+	`
+
+	row := &webhookRow{}
+
+	if err := conn(ctx, r.DB).GetContext(ctx, row, query, w.ID, w.URL, pq.StringArray(w.Events), w.Active, model.RealmScope(ctx)); err != nil {
+		log.Printf("Could not update webhook: %v. Reason: %v\n", w.ID, err)
+		return apperrors.NewNotFound("webhook", w.ID.String())
+	}
+
+	*w = *row.webhook()
+
+	return nil
+}
+
+// Delete removes a webhook along with its delivery log
+func (r *pgWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.DB).ExecContext(ctx, "DELETE FROM webhooks WHERE id=$1 AND ($2 = '' OR realm_id=$2)", id, model.RealmScope(ctx))
+	if err != nil {
+		log.Printf("Could not delete webhook: %v. Reason: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NewNotFound("webhook", id.String())
+	}
+
+	return nil
+}
+
+// FindByID fetches a webhook by its id
+func (r *pgWebhookRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	row := &webhookRow{}
+
+	if err := conn(ctx, r.DB).GetContext(ctx, row, "SELECT * FROM webhooks WHERE id=$1 AND ($2 = '' OR realm_id=$2)", id, model.RealmScope(ctx)); err != nil {
+		return nil, apperrors.NewNotFound("webhook", id.String())
+	}
+
+	return row.webhook(), nil
+}
+
+// List returns every webhook of the request's realm, oldest first
+func (r *pgWebhookRepository) List(ctx context.Context) ([]*model.Webhook, error) {
+	return r.list(ctx, "SELECT * FROM webhooks WHERE realm_id=$1 ORDER BY created_at", model.RealmIDFromContext(ctx))
+}
+
+// ListSubscribed returns the active webhooks of a realm which want events of the given type
+func (r *pgWebhookRepository) ListSubscribed(ctx context.Context, realmID string, eventType string) ([]*model.Webhook, error) {
+	query := `
+		SELECT * FROM webhooks
+		WHERE active
+		AND realm_id = $1
+		AND (cardinality(events) = 0 OR $2 = ANY(events))
+		ORDER BY created_at;
+	`
+
+	return r.list(ctx, query, realmID, eventType)
+}
+
+func (r *pgWebhookRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.Webhook, error) {
+	rows := []*webhookRow{}
+
+	if err := conn(ctx, r.DB).SelectContext(ctx, &rows, query, args...); err != nil {
+		log.Printf("error listing webhooks: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	webhooks := make([]*model.Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = row.webhook()
+	}
+
+	return webhooks, nil
+}
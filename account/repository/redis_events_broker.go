@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// redisEventsBroker is a redis streams implementation
+// of service layer EventsBroker
+type redisEventsBroker struct {
+	Redis  *redis.Client
+	Stream string
+	MaxLen int64
+}
+
+// NewEventsBroker is a factory for initializing an EventsBroker which
+// appends events to a redis stream. The stream is trimmed to roughly
+// maxLen entries, or left to grow if maxLen is 0
+func NewEventsBroker(redisClient *redis.Client, stream string, maxLen int64) model.EventsBroker {
+	return &redisEventsBroker{
+		Redis:  redisClient,
+		Stream: stream,
+		MaxLen: maxLen,
+	}
+}
+
+// Publish appends the event to the stream. The type and uid are stored
+// alongside the payload so consumers can filter without decoding it
+func (r *redisEventsBroker) Publish(ctx context.Context, e *model.UserEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("could not marshal event: %v: %v\n", e.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	args := &redis.XAddArgs{
+		Stream: r.Stream,
+		MaxLen: r.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":      e.ID.String(),
+			"type":    e.Type,
+			"uid":     e.UID.String(),
+			"payload": string(payload),
+		},
+	}
+
+	if err := r.Redis.XAdd(ctx, args).Err(); err != nil {
+		log.Printf("could not publish event: %v to stream: %v: %v\n", e.ID, r.Stream, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// redisExportRepository is data/repository implementation
+// of service layer ExportRepository. Exports are kept in
+// redis so they disappear by themselves when they expire
+type redisExportRepository struct {
+	Redis *redis.Client
+}
+
+// NewExportRepository is a factory for initializing export repositories
+func NewExportRepository(redisClient *redis.Client) model.ExportRepository {
+	return &redisExportRepository{
+		Redis: redisClient,
+	}
+}
+
+func exportKey(id uuid.UUID) string {
+	return fmt.Sprintf("export:%s", id)
+}
+
+func exportArchiveKey(id uuid.UUID) string {
+	return fmt.Sprintf("export:%s:archive", id)
+}
+
+// pendingExportsKey holds the ids of pending exports, scored by when they were created
+const pendingExportsKey = "exports:pending"
+
+// Save stores an export until its ExpiresAt, keeping track of
+// it among the pending exports for as long as it is pending
+func (r *redisExportRepository) Save(ctx context.Context, e *model.DataExport) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("could not marshal export: %v: %v\n", e.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	_, err = r.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, exportKey(e.ID), b, time.Until(e.ExpiresAt))
+
+		if e.Status == model.ExportStatusPending {
+			pipe.ZAdd(ctx, pendingExportsKey, &redis.Z{Score: float64(e.CreatedAt.Unix()), Member: e.ID.String()})
+		} else {
+			pipe.ZRem(ctx, pendingExportsKey, e.ID.String())
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("could not SET export: %v to redis: %v\n", e.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *redisExportRepository) Find(ctx context.Context, id uuid.UUID) (*model.DataExport, error) {
+	b, err := r.Redis.Get(ctx, exportKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("export", id.String())
+	}
+
+	if err != nil {
+		log.Printf("could not GET export: %v from redis: %v\n", id, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	e := &model.DataExport{}
+	if err := json.Unmarshal(b, e); err != nil {
+		log.Printf("could not unmarshal export: %v: %v\n", id, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return e, nil
+}
+
+// SaveArchive stores the generated file of an export
+func (r *redisExportRepository) SaveArchive(ctx context.Context, id uuid.UUID, archive []byte, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, exportArchiveKey(id), archive, expiresIn).Err(); err != nil {
+		log.Printf("could not SET archive of export: %v to redis: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *redisExportRepository) FindArchive(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	b, err := r.Redis.Get(ctx, exportArchiveKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("export", id.String())
+	}
+
+	if err != nil {
+		log.Printf("could not GET archive of export: %v from redis: %v\n", id, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return b, nil
+}
+
+// FindStalePending returns up to limit exports which were still pending
+// at the given time, oldest first. Exports which have expired in the
+// meantime are no longer tracked
+func (r *redisExportRepository) FindStalePending(ctx context.Context, before time.Time, limit int) ([]*model.DataExport, error) {
+	ids, err := r.Redis.ZRangeByScore(ctx, pendingExportsKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprintf("(%d", before.Unix()),
+		Count: int64(limit),
+	}).Result()
+
+	if err != nil {
+		log.Printf("could not ZRANGEBYSCORE pending exports from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	exports := []*model.DataExport{}
+
+	for _, member := range ids {
+		id, err := uuid.Parse(member)
+		if err != nil {
+			log.Printf("invalid id of pending export: %v: %v\n", member, err)
+			r.Redis.ZRem(ctx, pendingExportsKey, member)
+			continue
+		}
+
+		e, err := r.Find(ctx, id)
+		if apperrors.Status(err) == http.StatusNotFound {
+			r.Redis.ZRem(ctx, pendingExportsKey, member)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Status == model.ExportStatusPending {
+			exports = append(exports, e)
+		}
+	}
+
+	return exports, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -83,3 +84,38 @@ func (r *redisTokenRepository) DeleteUserRefreshTokens(ctx context.Context, user
 
 	return nil
 }
+
+// ListUserRefreshTokens scans for all unexpired refresh tokens of a user
+func (r *redisTokenRepository) ListUserRefreshTokens(ctx context.Context, userID string) ([]*model.Session, error) {
+	pattern := fmt.Sprintf("%s:*", userID)
+
+	iter := r.Redis.Scan(ctx, 0, pattern, 5).Iterator()
+	sessions := []*model.Session{}
+
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		ttl, err := r.Redis.TTL(ctx, key).Result()
+		if err != nil {
+			log.Printf("failed to get ttl of refresh token: %s: %v\n", key, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		// the token expired between scanning and reading its ttl
+		if ttl < 0 {
+			continue
+		}
+
+		sessions = append(sessions, &model.Session{
+			TokenID:   strings.TrimPrefix(key, userID+":"),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Printf("failed to list refresh tokens for userID: %s: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"strings"
+
+	// decoders for every format profile images are accepted in
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// rulesImageModerator decides on images with local rules, so images are
+// never sent anywhere. Images on the blocklist, which holds the hex SHA-256
+// of uploaded files, and images smaller than MinDimension are rejected.
+// Images more than MaxAspectRatio times as wide as they are tall, or the
+// other way around, lose most of themselves when cropped to a square, so
+// are quarantined for an admin to look at. Zero limits are not checked
+type rulesImageModerator struct {
+	MinDimension   int
+	MaxAspectRatio float64
+	Blocklist      map[string]bool
+}
+
+// NewRulesImageModerator is a factory for initializing an ImageModerator
+// which applies local rules. blocklist holds hex SHA-256 hashes of images
+func NewRulesImageModerator(minDimension int, maxAspectRatio float64, blocklist []string) model.ImageModerator {
+	blocked := make(map[string]bool, len(blocklist))
+	for _, hash := range blocklist {
+		blocked[strings.ToLower(hash)] = true
+	}
+
+	return &rulesImageModerator{
+		MinDimension:   minDimension,
+		MaxAspectRatio: maxAspectRatio,
+		Blocklist:      blocked,
+	}
+}
+
+func (m *rulesImageModerator) Moderate(ctx context.Context, uid uuid.UUID, data []byte) (*model.ModerationResult, error) {
+	hash := sha256.Sum256(data)
+	if m.Blocklist[hex.EncodeToString(hash[:])] {
+		return &model.ModerationResult{Decision: model.ModerationReject, Reason: "image is blocked"}, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return &model.ModerationResult{Decision: model.ModerationReject, Reason: "image could not be read"}, nil
+	}
+
+	short, long := cfg.Width, cfg.Height
+	if short > long {
+		short, long = long, short
+	}
+
+	if short < m.MinDimension {
+		return &model.ModerationResult{
+			Decision: model.ModerationReject,
+			Reason:   fmt.Sprintf("image must be at least %d by %d pixels", m.MinDimension, m.MinDimension),
+		}, nil
+	}
+
+	if m.MaxAspectRatio > 0 && float64(long) > m.MaxAspectRatio*float64(short) {
+		return &model.ModerationResult{
+			Decision: model.ModerationQuarantine,
+			Reason:   fmt.Sprintf("image is more than %g times as long as it is wide", m.MaxAspectRatio),
+		}, nil
+	}
+
+	return &model.ModerationResult{Decision: model.ModerationAllow}, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRulesImageModerator(t *testing.T) {
+	encode := func(width, height int) []byte {
+		buf := &bytes.Buffer{}
+		png.Encode(buf, image.NewGray(image.Rect(0, 0, width, height)))
+		return buf.Bytes()
+	}
+
+	blocked := encode(100, 101)
+	hash := sha256.Sum256(blocked)
+
+	m := NewRulesImageModerator(64, 3, []string{hex.EncodeToString(hash[:])})
+	uid := uuid.New()
+
+	cases := []struct {
+		name     string
+		data     []byte
+		decision string
+		reason   string
+	}{
+		{"Allowed", encode(100, 200), model.ModerationAllow, ""},
+		{"Blocked", blocked, model.ModerationReject, "image is blocked"},
+		{"Too small", encode(63, 100), model.ModerationReject, "image must be at least 64 by 64 pixels"},
+		{"Too long", encode(400, 100), model.ModerationQuarantine, "image is more than 3 times as long as it is wide"},
+		{"Too tall", encode(100, 301), model.ModerationQuarantine, "image is more than 3 times as long as it is wide"},
+		{"Not an image", []byte("text"), model.ModerationReject, "image could not be read"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := m.Moderate(context.Background(), uid, tc.data)
+
+			assert.NoError(t, err)
+			assert.Equal(t, &model.ModerationResult{Decision: tc.decision, Reason: tc.reason}, result)
+		})
+	}
+
+	t.Run("No limits", func(t *testing.T) {
+		result, err := NewRulesImageModerator(0, 0, nil).Moderate(context.Background(), uid, encode(1, 1000))
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ModerationAllow, result.Decision)
+	})
+}
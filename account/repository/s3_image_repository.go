@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// s3ImageRepository stores images in a bucket of any S3 compatible
+// object storage, such as AWS, MinIO or Ceph
+type s3ImageRepository struct {
+	Client     *s3.Client
+	BucketName string
+	BaseURL    string
+}
+
+// NewS3ImageRepository is a factory for initializing an image repository storing
+// objects in bucketName. baseURL is where the bucket's objects are publicly served
+func NewS3ImageRepository(client *s3.Client, bucketName string, baseURL string) model.ImageRepository {
+	return &s3ImageRepository{
+		Client:     client,
+		BucketName: bucketName,
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (r *s3ImageRepository) UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error) {
+	// unlike cloud storage, S3 does not detect the content type of objects
+	head := make([]byte, 512)
+	n, err := io.ReadFull(imageFile, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		log.Printf("unable to read image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if _, err := imageFile.Seek(0, io.SeekStart); err != nil {
+		log.Printf("unable to rewind image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	_, err = r.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.BucketName),
+		Key:         aws.String(objName),
+		Body:        imageFile,
+		ContentType: aws.String(http.DetectContentType(head[:n])),
+		// set cache control so profile image will be served fresh by browsers
+		CacheControl: aws.String("no-cache, max-age=0"),
+	})
+	if err != nil {
+		log.Printf("unable to write file to S3 bucket: %v: %v\n", r.BucketName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return r.BaseURL + "/" + objName, nil
+}
+
+func (r *s3ImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	// S3 reports success when deleting an object which is already gone
+	_, err := r.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.BucketName),
+		Key:    aws.String(objName),
+	})
+	if err != nil {
+		log.Printf("Failed to delete image object with ID: %s from S3: %v\n", objName, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetProfile opens a reader for an image object. The caller must close it
+func (r *s3ImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	out, err := r.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.BucketName),
+		Key:    aws.String(objName),
+	})
+	if err != nil {
+		log.Printf("Failed to read image object with ID: %s from S3: %v\n", objName, err)
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, apperrors.NewNotFound("image", objName)
+		}
+		return nil, apperrors.NewInternal()
+	}
+
+	return out.Body, nil
+}
+
+// SignURL presigns a GetObject request for an object. Signing happens
+// locally with the client's credentials, so the bucket is not contacted
+func (r *s3ImageRepository) SignURL(ctx context.Context, objName string, ttl time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(r.Client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.BucketName),
+		Key:    aws.String(objName),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		log.Printf("Failed to sign URL for image object with ID: %s: %v\n", objName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return req.URL, nil
+}
+
+// SignUpload presigns a PutObject request for an object. S3 has no signed
+// length ranges for PUT requests, so the exact size is signed instead
+func (r *s3ImageRepository) SignUpload(ctx context.Context, objName string, contentType string, size int64, ttl time.Duration) (*model.ImageUpload, error) {
+	req, err := s3.NewPresignClient(r.Client).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(r.BucketName),
+		Key:           aws.String(objName),
+		ContentType:   aws.String(contentType),
+		ContentLength: size,
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		log.Printf("Failed to sign upload for image object with ID: %s: %v\n", objName, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	// the host is set by the client from the URL
+	headers := map[string]string{}
+	for name := range req.SignedHeader {
+		if name != "Host" {
+			headers[name] = req.SignedHeader.Get(name)
+		}
+	}
+
+	return &model.ImageUpload{
+		ObjectName: objName,
+		URL:        req.URL,
+		Method:     req.Method,
+		Headers:    headers,
+	}, nil
+}
+
+// List pages through every object in the bucket
+func (r *s3ImageRepository) List(ctx context.Context) ([]*model.StoredImage, error) {
+	paginator := s3.NewListObjectsV2Paginator(r.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.BucketName),
+	})
+
+	images := []*model.StoredImage{}
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("Failed to list image objects in S3 bucket: %s: %v\n", r.BucketName, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		for _, obj := range page.Contents {
+			images = append(images, &model.StoredImage{
+				ObjectName: aws.ToString(obj.Key),
+				Size:       obj.Size,
+				UpdatedAt:  aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+
+	return images, nil
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is an in-process S3 server holding objects in memory. It supports
+// both path style and virtual hosted style addressing of buckets
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]*fakeS3Object
+}
+
+type fakeS3Object struct {
+	body     []byte
+	header   http.Header
+	modified time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// requests are signed in a header, or in the query when presigned
+	signed := strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") ||
+		(r.URL.Query().Get("X-Amz-Algorithm") == "AWS4-HMAC-SHA256" && r.URL.Query().Get("X-Amz-Signature") != "")
+
+	if !signed {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if host, _, _ := net.SplitHostPort(r.Host); strings.HasSuffix(host, ".s3.test") {
+		key = strings.TrimSuffix(host, ".s3.test") + "/" + key
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = &fakeS3Object{
+			body: body,
+			header: http.Header{
+				"Content-Type":  {r.Header.Get("Content-Type")},
+				"Cache-Control": {r.Header.Get("Cache-Control")},
+			},
+			modified: time.Now(),
+		}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			f.list(w, strings.TrimSuffix(key, "/"))
+			return
+		}
+
+		obj, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Write(obj.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list responds with every object of a bucket in a single page
+func (f *fakeS3) list(w http.ResponseWriter, bucket string) {
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprintf(w, `<ListBucketResult><Name>%s</Name><IsTruncated>false</IsTruncated>`, bucket)
+
+	for key, obj := range f.objects {
+		if name := strings.TrimPrefix(key, bucket+"/"); name != key {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>%d</Size></Contents>`,
+				name, obj.modified.UTC().Format(time.RFC3339), len(obj.body))
+		}
+	}
+
+	fmt.Fprint(w, `</ListBucketResult>`)
+}
+
+// imageFile is an in memory multipart.File
+type imageFile struct {
+	*bytes.Reader
+}
+
+func (imageFile) Close() error { return nil }
+
+func TestS3ImageRepository(t *testing.T) {
+	fake := &fakeS3{objects: map[string]*fakeS3Object{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+
+	// virtual hosted buckets are subdomains of the endpoint, so
+	// every host is dialed to the fake server
+	httpClient := func(pathStyle bool) *http.Client {
+		if pathStyle {
+			return server.Client()
+		}
+
+		return &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+				},
+			},
+		}
+	}
+
+	newRepository := func(pathStyle bool) *s3ImageRepository {
+		endpoint := server.URL
+		if !pathStyle {
+			_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+			endpoint = "http://s3.test:" + port
+		}
+
+		client := s3.New(s3.Options{
+			Region:           "us-east-1",
+			UsePathStyle:     pathStyle,
+			Credentials:      credentials.NewStaticCredentialsProvider("key", "secret", ""),
+			EndpointResolver: s3.EndpointResolverFromURL(endpoint),
+			HTTPClient:       httpClient(pathStyle),
+		})
+
+		return NewS3ImageRepository(client, "images", "https://cdn.test/images/").(*s3ImageRepository)
+	}
+
+	for _, pathStyle := range []bool{true, false} {
+		t.Run(fmt.Sprintf("Path style %v", pathStyle), func(t *testing.T) {
+			r := newRepository(pathStyle)
+			ctx := context.Background()
+
+			imageURL, err := r.UpdateProfile(ctx, "avatar", imageFile{bytes.NewReader(png)})
+			assert.NoError(t, err)
+			assert.Equal(t, "https://cdn.test/images/avatar", imageURL)
+
+			stored := fake.objects["images/avatar"]
+			if assert.NotNil(t, stored) {
+				assert.Equal(t, png, stored.body)
+				assert.Equal(t, "image/png", stored.header.Get("Content-Type"))
+				assert.Equal(t, "no-cache, max-age=0", stored.header.Get("Cache-Control"))
+			}
+
+			rc, err := r.GetProfile(ctx, "avatar")
+			assert.NoError(t, err)
+			body, _ := io.ReadAll(rc)
+			rc.Close()
+			assert.Equal(t, png, body)
+
+			assert.NoError(t, r.DeleteProfile(ctx, "avatar"))
+			// deleting again is not a failure
+			assert.NoError(t, r.DeleteProfile(ctx, "avatar"))
+
+			_, err = r.GetProfile(ctx, "avatar")
+			assert.Equal(t, apperrors.NewNotFound("image", "avatar"), err)
+
+			// signed URLs point at the bucket itself, not at the public URL
+			signedURL, err := r.SignURL(ctx, "avatar", 5*time.Minute)
+			assert.NoError(t, err)
+
+			u, err := url.Parse(signedURL)
+			if assert.NoError(t, err) {
+				assert.NotEqual(t, "cdn.test", u.Host)
+				assert.True(t, strings.HasSuffix(u.Path, "/avatar"))
+				assert.Equal(t, "300", u.Query().Get("X-Amz-Expires"))
+				assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+			}
+
+			// uploads are put straight to the bucket with the signed headers
+			upload, err := r.SignUpload(ctx, "upload", "image/png", int64(len(png)), 5*time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, http.MethodPut, upload.Method)
+			assert.Equal(t, "image/png", upload.Headers["Content-Type"])
+			assert.Equal(t, strconv.Itoa(len(png)), upload.Headers["Content-Length"])
+
+			signedHeaders, _ := url.Parse(upload.URL)
+			assert.Contains(t, signedHeaders.Query().Get("X-Amz-SignedHeaders"), "content-length")
+			assert.Contains(t, signedHeaders.Query().Get("X-Amz-SignedHeaders"), "content-type")
+
+			req, _ := http.NewRequest(upload.Method, upload.URL, bytes.NewReader(png))
+			for name, value := range upload.Headers {
+				req.Header.Set(name, value)
+			}
+
+			res, err := httpClient(pathStyle).Do(req)
+			if assert.NoError(t, err) {
+				res.Body.Close()
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+			}
+
+			rc, err = r.GetProfile(ctx, "upload")
+			assert.NoError(t, err)
+			body, _ = io.ReadAll(rc)
+			rc.Close()
+			assert.Equal(t, png, body)
+
+			images, err := r.List(ctx)
+			assert.NoError(t, err)
+			if assert.Len(t, images, 1) {
+				assert.Equal(t, "upload", images[0].ObjectName)
+				assert.Equal(t, int64(len(png)), images[0].Size)
+				assert.WithinDuration(t, time.Now(), images[0].UpdatedAt, time.Minute)
+			}
+
+			assert.NoError(t, r.DeleteProfile(ctx, "upload"))
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// adminService acts as a struct for injecting the repositories
+// used for administrative operations on users
+type adminService struct {
+	UserRepository        model.UserRepository
+	RoleRepository        model.RoleRepository
+	TokenRepository       model.TokenRepository
+	ImageRepository       model.ImageRepository
+	AdminActionRepository model.AdminActionRepository
+	OutboxRepository      model.OutboxRepository
+	Transactor            model.Transactor
+	ImageURLTTL           time.Duration
+	ImagePublicURL        string
+	BaseURL               string
+}
+
+// ASConfig will hold repositories that will eventually be injected
+// into this service layer
+type ASConfig struct {
+	UserRepository        model.UserRepository
+	RoleRepository        model.RoleRepository
+	TokenRepository       model.TokenRepository
+	ImageRepository       model.ImageRepository
+	AdminActionRepository model.AdminActionRepository
+	OutboxRepository      model.OutboxRepository
+	Transactor            model.Transactor
+	// ImageURLTTL is how long signed image URLs last when image storage is private
+	ImageURLTTL time.Duration
+	// ImagePublicURL is where public images are linked to under their object names
+	ImagePublicURL string
+	// BaseURL is where this service is served, which default avatars are linked to under
+	BaseURL string
+}
+
+// NewAdminService is a factory function for initializing
+// an AdminService with its repository layer dependencies
+func NewAdminService(c *ASConfig) model.AdminService {
+	return &adminService{
+		UserRepository:        c.UserRepository,
+		RoleRepository:        c.RoleRepository,
+		TokenRepository:       c.TokenRepository,
+		ImageRepository:       c.ImageRepository,
+		AdminActionRepository: c.AdminActionRepository,
+		OutboxRepository:      c.OutboxRepository,
+		Transactor:            c.Transactor,
+		ImageURLTTL:           c.ImageURLTTL,
+		ImagePublicURL:        strings.TrimSuffix(c.ImagePublicURL, "/"),
+		BaseURL:               c.BaseURL,
+	}
+}
+
+// ListRoles returns all roles with their permissions
+func (s *adminService) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	return s.RoleRepository.List(ctx)
+}
+
+// AssignRole grants role to the user with uid on behalf of actor
+func (s *adminService) AssignRole(ctx context.Context, actor *model.User, uid uuid.UUID, role string) error {
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := s.RoleRepository.Assign(ctx, uid, role, &actor.UID); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionAssignRole, role)
+
+	return nil
+}
+
+// RevokeRole removes role from the user with uid on behalf of actor
+// The last remaining admin of the realm cannot be revoked, otherwise
+// nobody would be left to grant roles. Admins are counted and revoked
+// in a single transaction, so concurrent revokes can't both pass the check
+func (s *adminService) RevokeRole(ctx context.Context, actor *model.User, uid uuid.UUID, role string) error {
+	// only users of the request's realm can be acted on
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return err
+	}
+
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if role == model.RoleAdmin {
+			count, err := s.RoleRepository.CountUsers(ctx, model.RoleAdmin)
+			if err != nil {
+				return err
+			}
+
+			if count <= 1 {
+				return apperrors.NewBadRequest("cannot revoke the last remaining admin")
+			}
+		}
+
+		return s.RoleRepository.Revoke(ctx, uid, role)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionRevokeRole, role)
+
+	return nil
+}
+
+// BootstrapAdmin grants the admin role to the user with the given email
+// only if no user of the realm holds the admin role yet. It is used on startup so the
+// first admin can be created without touching the database
+func (s *adminService) BootstrapAdmin(ctx context.Context, email string) error {
+	count, err := s.RoleRepository.CountUsers(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if err := s.RoleRepository.Assign(ctx, u.UID, model.RoleAdmin, nil); err != nil {
+		return err
+	}
+
+	log.Printf("bootstrapped admin role for uid: %v\n", u.UID)
+
+	return nil
+}
+
+// SearchUsers returns a page of users matching query. cursor is the
+// NextCursor of the previous page, or empty for the first page
+func (s *adminService) SearchUsers(ctx context.Context, query string, cursor string, limit int) (*model.UserPage, error) {
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	after := uuid.Nil
+	if cursor != "" {
+		c, err := uuid.Parse(cursor)
+		if err != nil {
+			return nil, apperrors.NewBadRequest("invalid cursor")
+		}
+		after = c
+	}
+
+	// fetch one extra user to find out if there is another page
+	users, err := s.UserRepository.Search(ctx, query, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.UserPage{
+		Users: users,
+	}
+
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = page.Users[limit-1].UID.String()
+	}
+
+	for _, u := range page.Users {
+		if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, u); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// GetUser retrieves any user along with their roles
+func (s *adminService) GetUser(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// UpdateUser overwrites the editable fields of u on behalf of actor, and
+// marks their email as verified or not unless emailVerified is nil
+func (s *adminService) UpdateUser(ctx context.Context, actor *model.User, u *model.User, emailVerified *bool) error {
+	err := updateWithEvents(ctx, s.Transactor, s.OutboxRepository, s.UserRepository, u, emailVerified)
+
+	if err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, u.UID, model.AdminActionUpdateUser, fmt.Sprintf("name=%q email=%q website=%q email_verified=%t", u.Name, u.Email, u.Website, u.EmailVerified))
+
+	// u is returned to the admin, holding the image as stored
+	return resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, u)
+}
+
+// ResetPassword sets a new password for the user and signs them out everywhere
+func (s *adminService) ResetPassword(ctx context.Context, actor *model.User, uid uuid.UUID, password string) error {
+	// only users of the request's realm can be acted on
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return err
+	}
+
+	pw, err := hashPassword(password)
+	if err != nil {
+		log.Printf("unable to hash password for uid: %v\n", uid)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, uid, pw); err != nil {
+		return err
+	}
+
+	if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String()); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionResetPassword, "")
+
+	return nil
+}
+
+// ForceSignout revokes all of the user's refresh tokens
+func (s *adminService) ForceSignout(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+	// only users of the request's realm can be acted on
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String()); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionForceSignout, "")
+
+	return nil
+}
+
+// SuspendUser blocks the user from signing in until the given time, or
+// indefinitely if until is nil, and revokes their refresh tokens
+func (s *adminService) SuspendUser(ctx context.Context, actor *model.User, uid uuid.UUID, reason string, until *time.Time) error {
+	if actor.UID == uid {
+		return apperrors.NewBadRequest("admins cannot suspend their own account")
+	}
+
+	if until != nil && !until.After(time.Now()) {
+		return apperrors.NewBadRequest("suspension end time must be in the future")
+	}
+
+	if err := s.deactivate(ctx, uid, model.UserStatusSuspended, reason, until); err != nil {
+		return err
+	}
+
+	detail := reason
+	if until != nil {
+		detail = fmt.Sprintf("until %s: %s", until.UTC().Format(time.RFC3339), reason)
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionSuspendUser, detail)
+
+	return nil
+}
+
+// DisableUser blocks the user from signing in and revokes their refresh tokens
+func (s *adminService) DisableUser(ctx context.Context, actor *model.User, uid uuid.UUID, reason string) error {
+	if actor.UID == uid {
+		return apperrors.NewBadRequest("admins cannot disable their own account")
+	}
+
+	if err := s.deactivate(ctx, uid, model.UserStatusDisabled, reason, nil); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionDisableUser, reason)
+
+	return nil
+}
+
+// deactivate stores a non-active status and revokes all refresh tokens so
+// the user is signed out as soon as their ID token needs refreshing
+func (s *adminService) deactivate(ctx context.Context, uid uuid.UUID, status string, reason string, until *time.Time) error {
+	// only users of the request's realm can be acted on
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := s.UserRepository.UpdateStatus(ctx, uid, status, reason, until); err != nil {
+		return err
+	}
+
+	return s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String())
+}
+
+// EnableUser allows a suspended or disabled user to sign in again
+func (s *adminService) EnableUser(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+	// only users of the request's realm can be acted on
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := s.UserRepository.UpdateStatus(ctx, uid, model.UserStatusActive, "", nil); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionEnableUser, "")
+
+	return nil
+}
+
+// DeleteUser permanently removes the user, their profile and previous images and their refresh tokens
+func (s *adminService) DeleteUser(ctx context.Context, actor *model.User, uid uuid.UUID) error {
+	if actor.UID == uid {
+		return apperrors.NewBadRequest("admins cannot delete their own account")
+	}
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String()); err != nil {
+		return err
+	}
+
+	if err := deleteAllImages(ctx, s.ImageRepository, u); err != nil {
+		return err
+	}
+
+	err = withEvent(ctx, s.Transactor, s.OutboxRepository, model.EventUserDeleted, func(ctx context.Context) (*model.User, error) {
+		return u, s.UserRepository.Delete(ctx, uid)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, uid, model.AdminActionDeleteUser, u.Email)
+
+	return nil
+}
+
+// record attributes an action to the acting admin. The action has already
+// happened by the time it is recorded, so failures are logged rather than returned
+func (s *adminService) record(ctx context.Context, actor *model.User, target uuid.UUID, action string, detail string) {
+	a := &model.AdminAction{
+		ActorUID:  actor.UID,
+		TargetUID: target,
+		Action:    action,
+		Detail:    detail,
+	}
+
+	if err := s.AdminActionRepository.Create(ctx, a); err != nil {
+		log.Printf("failed to record admin action: %v by %v on uid: %v\n", action, actor.UID, target)
+		return
+	}
+
+	log.Printf("admin %v performed %v on uid: %v\n", actor.UID, action, target)
+}
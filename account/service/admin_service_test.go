@@ -0,0 +1,534 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAssignRole(t *testing.T) {
+	actorUID, _ := uuid.NewRandom()
+	actor := &model.User{UID: actorUID}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			RoleRepository:        mockRoleRepository,
+			AdminActionRepository: mockAdminActionRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockRoleRepository.On("Assign", mock.Anything, uid, model.RoleSupport, &actor.UID).Return(nil)
+		mockAdminActionRepository.On("Create", mock.Anything, &model.AdminAction{
+			ActorUID:  actor.UID,
+			TargetUID: uid,
+			Action:    model.AdminActionAssignRole,
+			Detail:    model.RoleSupport,
+		}).Return(nil)
+
+		err := as.AssignRole(context.TODO(), actor, uid, model.RoleSupport)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockRoleRepository.AssertExpectations(t)
+		mockAdminActionRepository.AssertExpectations(t)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockErr := apperrors.NewNotFound("uid", uid.String())
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(nil, mockErr)
+
+		err := as.AssignRole(context.TODO(), actor, uid, model.RoleSupport)
+
+		assert.Equal(t, mockErr, err)
+		mockRoleRepository.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeRole(t *testing.T) {
+	actorUID, _ := uuid.NewRandom()
+	actor := &model.User{UID: actorUID}
+
+	t.Run("Last admin", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+			RoleRepository: mockRoleRepository,
+			Transactor:     mockTransactor,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, actor.UID).Return(actor, nil)
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(1, nil)
+
+		err := as.RevokeRole(context.TODO(), actor, actor.UID, model.RoleAdmin)
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockRoleRepository.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		as := NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			RoleRepository:        mockRoleRepository,
+			AdminActionRepository: mockAdminActionRepository,
+			Transactor:            mockTransactor,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(2, nil)
+		mockRoleRepository.On("Revoke", mock.Anything, uid, model.RoleAdmin).Return(nil)
+		mockAdminActionRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.AdminAction")).Return(nil)
+
+		err := as.RevokeRole(context.TODO(), actor, uid, model.RoleAdmin)
+
+		assert.NoError(t, err)
+		mockRoleRepository.AssertExpectations(t)
+		mockTransactor.AssertExpectations(t)
+	})
+
+	t.Run("Transaction fails", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(apperrors.NewInternal())
+		as := NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			RoleRepository:        mockRoleRepository,
+			AdminActionRepository: mockAdminActionRepository,
+			Transactor:            mockTransactor,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+
+		err := as.RevokeRole(context.TODO(), actor, uid, model.RoleAdmin)
+
+		assert.Error(t, err)
+		mockAdminActionRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	email := "first@admin.com"
+
+	t.Run("Grants admin when none exists", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(0, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+		mockRoleRepository.On("Assign", mock.Anything, uid, model.RoleAdmin, (*uuid.UUID)(nil)).Return(nil)
+
+		err := as.BootstrapAdmin(context.TODO(), email)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockRoleRepository.AssertExpectations(t)
+	})
+
+	t.Run("No-op when an admin exists", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(1, nil)
+
+		err := as.BootstrapAdmin(context.TODO(), email)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSearchUsers(t *testing.T) {
+	t.Run("Returns next cursor when more results exist", func(t *testing.T) {
+		users := []*model.User{}
+		for i := 0; i < 3; i++ {
+			uid, _ := uuid.NewRandom()
+			users = append(users, &model.User{UID: uid})
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		// a limit of 2 should ask the repository for 3
+		mockUserRepository.On("Search", mock.Anything, "bob", uuid.Nil, 3).Return(users, nil)
+
+		page, err := as.SearchUsers(context.TODO(), "bob", "", 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, users[:2], page.Users)
+		assert.Equal(t, users[1].UID.String(), page.NextCursor)
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		after, _ := uuid.NewRandom()
+		uid, _ := uuid.NewRandom()
+		users := []*model.User{{UID: uid}}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("Search", mock.Anything, "", after, defaultSearchLimit+1).Return(users, nil)
+
+		page, err := as.SearchUsers(context.TODO(), "", after.String(), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, users, page.Users)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		_, err := as.SearchUsers(context.TODO(), "", "notacursor", 10)
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminUpdateUser(t *testing.T) {
+	actor := &model.User{UID: uuid.New()}
+
+	newService := func(prev *model.User) (model.AdminService, *mocks.MockUserRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByID", mock.Anything, prev.UID).Return(prev, nil)
+		mockUserRepository.On("Update", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		mockAdminActionRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		return NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			OutboxRepository:      mockOutboxRepository,
+			Transactor:            mockTransactor,
+			AdminActionRepository: mockAdminActionRepository,
+		}), mockUserRepository
+	}
+
+	t.Run("Marks email verified", func(t *testing.T) {
+		uid := uuid.New()
+		as, mockUserRepository := newService(&model.User{UID: uid, Email: "bob@bob.com"})
+		verified := true
+
+		err := as.UpdateUser(context.TODO(), actor, &model.User{UID: uid, Email: "bob@bob.com"}, &verified)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.EmailVerified
+		}))
+	})
+
+	t.Run("Keeps email verified when omitted", func(t *testing.T) {
+		uid := uuid.New()
+		as, mockUserRepository := newService(&model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true})
+
+		err := as.UpdateUser(context.TODO(), actor, &model.User{UID: uid, Name: "Bob", Email: "bob@bob.com"}, nil)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Name == "Bob" && u.EmailVerified
+		}))
+	})
+
+	t.Run("New email unverified when omitted", func(t *testing.T) {
+		uid := uuid.New()
+		as, mockUserRepository := newService(&model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true})
+
+		err := as.UpdateUser(context.TODO(), actor, &model.User{UID: uid, Email: "new@bob.com"}, nil)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Email == "new@bob.com" && !u.EmailVerified
+		}))
+	})
+
+	t.Run("New email marked verified", func(t *testing.T) {
+		uid := uuid.New()
+		as, mockUserRepository := newService(&model.User{UID: uid, Email: "bob@bob.com"})
+		verified := true
+
+		err := as.UpdateUser(context.TODO(), actor, &model.User{UID: uid, Email: "new@bob.com"}, &verified)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Email == "new@bob.com" && u.EmailVerified
+		}))
+	})
+}
+
+func TestDisableUser(t *testing.T) {
+	actorUID, _ := uuid.NewRandom()
+	actor := &model.User{UID: actorUID}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			TokenRepository:       mockTokenRepository,
+			AdminActionRepository: mockAdminActionRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockUserRepository.On("UpdateStatus", mock.Anything, uid, model.UserStatusDisabled, "spam", (*time.Time)(nil)).Return(nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockAdminActionRepository.On("Create", mock.Anything, mock.MatchedBy(func(a *model.AdminAction) bool {
+			return a.ActorUID == actor.UID && a.TargetUID == uid && a.Action == model.AdminActionDisableUser
+		})).Return(nil)
+
+		err := as.DisableUser(context.TODO(), actor, uid, "spam")
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockAdminActionRepository.AssertExpectations(t)
+	})
+
+	t.Run("Cannot disable self", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		err := as.DisableUser(context.TODO(), actor, actor.UID, "spam")
+
+		assert.Error(t, err)
+		mockUserRepository.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSuspendUser(t *testing.T) {
+	actorUID, _ := uuid.NewRandom()
+	actor := &model.User{UID: actorUID}
+
+	t.Run("Revokes refresh tokens", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		until := time.Now().Add(24 * time.Hour)
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			TokenRepository:       mockTokenRepository,
+			AdminActionRepository: mockAdminActionRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockUserRepository.On("UpdateStatus", mock.Anything, uid, model.UserStatusSuspended, "abuse", &until).Return(nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockAdminActionRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.AdminAction")).Return(nil)
+
+		err := as.SuspendUser(context.TODO(), actor, uid, "abuse", &until)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("End time in the past", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		until := time.Now().Add(-time.Hour)
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		err := as.SuspendUser(context.TODO(), actor, uid, "abuse", &until)
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	actorUID, _ := uuid.NewRandom()
+	actor := &model.User{UID: actorUID}
+
+	t.Run("Removes image, tokens and user", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{
+			UID:   uid,
+			Email: "bob@bob.com",
+			ImageURLs: model.ImageURLs{
+				64:  "https://storage.googleapis.com/bucket/imageobject-64",
+				512: "https://storage.googleapis.com/bucket/imageobject-512",
+			},
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		as := NewAdminService(&ASConfig{
+			UserRepository:        mockUserRepository,
+			TokenRepository:       mockTokenRepository,
+			ImageRepository:       mockImageRepository,
+			AdminActionRepository: mockAdminActionRepository,
+			OutboxRepository:      mockOutboxRepository,
+			Transactor:            mockTransactor,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject-64").Return(nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject-512").Return(nil)
+		mockUserRepository.On("Delete", mock.Anything, uid).Return(nil)
+		mockAdminActionRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.AdminAction")).Return(nil)
+		mockOutboxRepository.On("Add", mock.Anything, mock.MatchedBy(func(e *model.UserEvent) bool {
+			return e.Type == model.EventUserDeleted && e.UID == uid && e.User == nil
+		})).Return(nil)
+
+		err := as.DeleteUser(context.TODO(), actor, uid)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+		mockAdminActionRepository.AssertExpectations(t)
+		mockOutboxRepository.AssertExpectations(t)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		mockErr := apperrors.NewNotFound("uid", uid.String())
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(nil, mockErr)
+
+		err := as.DeleteUser(context.TODO(), actor, uid)
+
+		assert.Equal(t, mockErr, err)
+		mockTokenRepository.AssertNotCalled(t, "DeleteUserRefreshTokens", mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+// users of other realms are not found by FindByID, so no admin action may reach them
+func TestAdminActionsOtherRealm(t *testing.T) {
+	actor := &model.User{UID: uuid.New()}
+	uid := uuid.New()
+	until := time.Now().Add(time.Hour)
+
+	actions := map[string]func(as model.AdminService) error{
+		"RevokeRole": func(as model.AdminService) error {
+			return as.RevokeRole(context.TODO(), actor, uid, model.RoleSupport)
+		},
+		"ResetPassword": func(as model.AdminService) error {
+			return as.ResetPassword(context.TODO(), actor, uid, "avalidpassword123")
+		},
+		"ForceSignout": func(as model.AdminService) error {
+			return as.ForceSignout(context.TODO(), actor, uid)
+		},
+		"SuspendUser": func(as model.AdminService) error {
+			return as.SuspendUser(context.TODO(), actor, uid, "abuse", &until)
+		},
+		"DisableUser": func(as model.AdminService) error {
+			return as.DisableUser(context.TODO(), actor, uid, "spam")
+		},
+		"EnableUser": func(as model.AdminService) error {
+			return as.EnableUser(context.TODO(), actor, uid)
+		},
+		"DeleteUser": func(as model.AdminService) error {
+			return as.DeleteUser(context.TODO(), actor, uid)
+		},
+	}
+
+	for name, action := range actions {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepository := new(mocks.MockUserRepository)
+			mockRoleRepository := new(mocks.MockRoleRepository)
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			mockAdminActionRepository := new(mocks.MockAdminActionRepository)
+			as := NewAdminService(&ASConfig{
+				UserRepository:        mockUserRepository,
+				RoleRepository:        mockRoleRepository,
+				TokenRepository:       mockTokenRepository,
+				AdminActionRepository: mockAdminActionRepository,
+			})
+
+			mockErr := apperrors.NewNotFound("uid", uid.String())
+			mockUserRepository.On("FindByID", mock.Anything, uid).Return(nil, mockErr)
+
+			err := action(as)
+
+			assert.Equal(t, mockErr, err)
+			mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+			mockUserRepository.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mockUserRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+			mockRoleRepository.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+			mockTokenRepository.AssertNotCalled(t, "DeleteUserRefreshTokens", mock.Anything, mock.Anything)
+			mockAdminActionRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// auditService acts as a struct for injecting an implementation of
+// AuditEventRepository for use in service methods
+type auditService struct {
+	AuditEventRepository model.AuditEventRepository
+}
+
+// AuditConfig will hold repositories that will eventually be injected
+// into this service layer
+type AuditConfig struct {
+	AuditEventRepository model.AuditEventRepository
+}
+
+// NewAuditService is a factory function for initializing
+// an AuditService with its repository layer dependencies
+func NewAuditService(c *AuditConfig) model.AuditService {
+	return &auditService{
+		AuditEventRepository: c.AuditEventRepository,
+	}
+}
+
+// ListEvents returns a page of the events matching the filter, newest first
+// The cursor is the id of the last event of the previous page
+func (s *auditService) ListEvents(ctx context.Context, f *model.AuditFilter, cursor string) (*model.AuditPage, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	if cursor != "" {
+		before, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || before <= 0 {
+			return nil, apperrors.NewBadRequest("invalid cursor")
+		}
+		f.Before = before
+	}
+
+	// fetch one extra event to find out if there is another page
+	f.Limit = limit + 1
+
+	events, err := s.AuditEventRepository.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.AuditPage{
+		Events: events,
+	}
+
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = strconv.FormatInt(page.Events[limit-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// recordAudit appends an event about uid, performed by actor, to the audit log
+// along with the client of the current request. Either uid may be nil when unknown.
+// A failure to record is logged rather than failing the action being audited
+func recordAudit(ctx context.Context, r model.AuditEventRepository, uid *uuid.UUID, actor *uuid.UUID, event string, outcome string, detail string) {
+	ci := model.ClientInfoFromContext(ctx)
+
+	e := &model.AuditEvent{
+		UID:       uid,
+		ActorUID:  actor,
+		Event:     event,
+		Outcome:   outcome,
+		IP:        ci.IP,
+		UserAgent: ci.UserAgent,
+		Detail:    detail,
+	}
+
+	if err := r.Create(ctx, e); err != nil {
+		log.Printf("failed to record audit event: %v for uid: %v: %v\n", event, uid, err)
+	}
+}
+
+// auditOutcome maps the error returned by an audited action to its outcome
+func auditOutcome(err error) string {
+	if err != nil {
+		return model.AuditOutcomeFailure
+	}
+
+	return model.AuditOutcomeSuccess
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListEvents(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Next cursor when more events", func(t *testing.T) {
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		as := NewAuditService(&AuditConfig{
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		events := []*model.AuditEvent{{ID: 30}, {ID: 29}, {ID: 28}}
+
+		mockAuditEventRepository.On("List", mock.Anything, mock.MatchedBy(func(f *model.AuditFilter) bool {
+			return *f.UID == uid && f.Before == 31 && f.Limit == 3
+		})).Return(events, nil)
+
+		page, err := as.ListEvents(context.TODO(), &model.AuditFilter{UID: &uid, Limit: 2}, "31")
+
+		assert.NoError(t, err)
+		assert.Equal(t, events[:2], page.Events)
+		assert.Equal(t, "29", page.NextCursor)
+		mockAuditEventRepository.AssertExpectations(t)
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		as := NewAuditService(&AuditConfig{
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		events := []*model.AuditEvent{{ID: 2}, {ID: 1}}
+
+		mockAuditEventRepository.On("List", mock.Anything, mock.MatchedBy(func(f *model.AuditFilter) bool {
+			return f.Before == 0 && f.Limit == defaultSearchLimit+1
+		})).Return(events, nil)
+
+		page, err := as.ListEvents(context.TODO(), &model.AuditFilter{}, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, events, page.Events)
+		assert.Equal(t, "", page.NextCursor)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		as := NewAuditService(&AuditConfig{
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		page, err := as.ListEvents(context.TODO(), &model.AuditFilter{}, "notanumber")
+
+		assert.Nil(t, page)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockAuditEventRepository.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// avatarCells is the number of cells across an identicon
+const avatarCells = 5
+
+// avatarBackground is the color of the cells left empty in an identicon
+var avatarBackground = color.RGBA{240, 240, 240, 255}
+
+// DefaultAvatar renders the avatar shown for a user without a profile image.
+// It only depends on the uid, so it is the same every time and nothing is stored
+func (s *userService) DefaultAvatar(ctx context.Context, uid uuid.UUID, size int) ([]byte, error) {
+	valid := false
+	for _, s := range model.ProfileImageSizes {
+		valid = valid || s == size
+	}
+
+	if !valid {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("size must be one of %v", model.ProfileImageSizes))
+	}
+
+	return renderIdenticon(uid, size)
+}
+
+// renderIdenticon draws a horizontally symmetric pattern of cells, with the
+// pattern and its color taken from a hash of the uid, as a square PNG
+func renderIdenticon(uid uuid.UUID, size int) ([]byte, error) {
+	hash := sha256.Sum256(uid[:])
+
+	fg := hslColor(float64(uint16(hash[0])<<8|uint16(hash[1]))/65536, 0.55, 0.5)
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{avatarBackground, fg})
+
+	// leave half a cell of margin on every side
+	cell := size / (avatarCells + 1)
+	margin := (size - cell*avatarCells) / 2
+
+	// only the left half and middle column are picked, the rest mirrors them
+	half := (avatarCells + 1) / 2
+
+	for row := 0; row < avatarCells; row++ {
+		for col := 0; col < half; col++ {
+			bit := row*half + col
+			if hash[2+bit/8]&(1<<(bit%8)) == 0 {
+				continue
+			}
+
+			for _, c := range []int{col, avatarCells - 1 - col} {
+				fill(img, image.Rect(margin+c*cell, margin+row*cell, margin+(c+1)*cell, margin+(row+1)*cell))
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fill sets every pixel of r to the foreground color of img
+func fill(img *image.Paletted, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+}
+
+// hslColor converts a hue, saturation and lightness, each from 0 to 1, to RGB
+func hslColor(h, s, l float64) color.RGBA {
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+
+	channel := func(t float64) uint8 {
+		if t < 0 {
+			t++
+		}
+		if t > 1 {
+			t--
+		}
+
+		v := p
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 1.0/2:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		}
+
+		return uint8(v*255 + 0.5)
+	}
+
+	return color.RGBA{channel(h + 1.0/3), channel(h), channel(h - 1.0/3), 255}
+}
+
+// defaultAvatarURLs links to every size of the default avatar of a user
+func defaultAvatarURLs(baseURL string, uid uuid.UUID) model.ImageURLs {
+	urls := make(model.ImageURLs, len(model.ProfileImageSizes))
+
+	for _, size := range model.ProfileImageSizes {
+		urls[size] = fmt.Sprintf("%s/avatars/%s?size=%d", baseURL, uid, size)
+	}
+
+	return urls
+}
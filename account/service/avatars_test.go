@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultAvatar(t *testing.T) {
+	us := NewUserService(&USConfig{})
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Every size", func(t *testing.T) {
+		for _, size := range model.ProfileImageSizes {
+			data, err := us.DefaultAvatar(context.TODO(), uid, size)
+			assert.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(data))
+			assert.NoError(t, err)
+			assert.Equal(t, size, img.Bounds().Dx())
+			assert.Equal(t, size, img.Bounds().Dy())
+		}
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		first, err := us.DefaultAvatar(context.TODO(), uid, 128)
+		assert.NoError(t, err)
+
+		second, err := us.DefaultAvatar(context.TODO(), uid, 128)
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+
+		other, err := us.DefaultAvatar(context.TODO(), uuid.New(), 128)
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, other)
+	})
+
+	t.Run("Symmetric", func(t *testing.T) {
+		data, err := us.DefaultAvatar(context.TODO(), uid, 64)
+		assert.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(data))
+		assert.NoError(t, err)
+
+		// the cells are centered, so every row reads the same from both ends
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				assert.Equal(t, img.At(x, y), img.At(b.Max.X-1-x, y))
+			}
+		}
+	})
+
+	t.Run("Unsupported size", func(t *testing.T) {
+		data, err := us.DefaultAvatar(context.TODO(), uid, 100)
+
+		assert.Nil(t, data)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+	})
+}
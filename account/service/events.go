@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// withEvent makes a change to a user and writes an event about the changed
+// user to the outbox in a single transaction, so the event is published
+// if and only if the change is committed
+func withEvent(ctx context.Context, t model.Transactor, o model.OutboxRepository, eventType string, change func(ctx context.Context) (*model.User, error)) error {
+	return t.WithinTransaction(ctx, func(ctx context.Context) error {
+		u, err := change(ctx)
+		if err != nil {
+			return err
+		}
+
+		return o.Add(ctx, model.NewUserEvent(eventType, u))
+	})
+}
+
+// updateWithEvents updates a user's details and writes an updated event to
+// the outbox in a single transaction, along with an email changed event
+// when the update gives the user a different email. Unless emailVerified
+// says otherwise, the email stays verified or not, and a new email is
+// unverified until it is verified
+func updateWithEvents(ctx context.Context, t model.Transactor, o model.OutboxRepository, r model.UserRepository, u *model.User, emailVerified *bool) error {
+	return t.WithinTransaction(ctx, func(ctx context.Context) error {
+		prev, err := r.FindByID(ctx, u.UID)
+		if err != nil {
+			return err
+		}
+
+		u.EmailVerified = prev.EmailVerified && prev.Email == u.Email
+		if emailVerified != nil {
+			u.EmailVerified = *emailVerified
+		}
+
+		if err := r.Update(ctx, u); err != nil {
+			return err
+		}
+
+		if err := o.Add(ctx, model.NewUserEvent(model.EventUserUpdated, u)); err != nil {
+			return err
+		}
+
+		if prev.Email == u.Email {
+			return nil
+		}
+
+		return o.Add(ctx, model.NewUserEvent(model.EventEmailChanged, u))
+	})
+}
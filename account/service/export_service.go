@@ -0,0 +1,346 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// exportAuditPageSize is how many audit events are read at a time when building an export
+const exportAuditPageSize = 500
+
+// exportTimeout is how long an export is given to be generated
+const exportTimeout = 10 * time.Minute
+
+// exportSweepBatchSize is the most stale pending exports failed by a single sweep
+const exportSweepBatchSize = 100
+
+// exportService acts as a struct for injecting the repositories
+// which hold a user's personal data, along with the secret used
+// to sign download links
+type exportService struct {
+	UserRepository       model.UserRepository
+	TokenRepository      model.TokenRepository
+	ImageRepository      model.ImageRepository
+	ExportRepository     model.ExportRepository
+	AuditEventRepository model.AuditEventRepository
+	Secret               string
+	BaseURL              string
+	ExportTTL            time.Duration
+}
+
+// ESConfig will hold repositories that will eventually be injected
+// into this service layer
+type ESConfig struct {
+	UserRepository       model.UserRepository
+	TokenRepository      model.TokenRepository
+	ImageRepository      model.ImageRepository
+	ExportRepository     model.ExportRepository
+	AuditEventRepository model.AuditEventRepository
+	Secret               string
+	BaseURL              string
+	ExportTTL            time.Duration
+}
+
+// NewExportService is a factory function for initializing
+// an ExportService with its repository layer dependencies
+func NewExportService(c *ESConfig) model.ExportService {
+	return &exportService{
+		UserRepository:       c.UserRepository,
+		TokenRepository:      c.TokenRepository,
+		ImageRepository:      c.ImageRepository,
+		ExportRepository:     c.ExportRepository,
+		AuditEventRepository: c.AuditEventRepository,
+		Secret:               c.Secret,
+		BaseURL:              c.BaseURL,
+		ExportTTL:            c.ExportTTL,
+	}
+}
+
+// RequestExport creates a pending export and generates it in the background,
+// as fetching images from storage can take longer than a request should
+func (s *exportService) RequestExport(ctx context.Context, uid uuid.UUID, format string) (*model.DataExport, error) {
+	if format != model.ExportFormatJSON && format != model.ExportFormatZIP {
+		return nil, apperrors.NewBadRequest("format must be 'json' or 'zip'")
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.Printf("failed to generate export ID: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	now := time.Now()
+	e := &model.DataExport{
+		ID:        id,
+		UID:       uid,
+		Format:    format,
+		Status:    model.ExportStatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ExportTTL),
+	}
+
+	if err := s.ExportRepository.Save(ctx, e); err != nil {
+		return nil, err
+	}
+
+	// the request context is cancelled as soon as we respond. Exports
+	// still pending should this fail, for example when the service
+	// stops, are failed by SweepExports so they can be requested again
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		s.generate(ctx, e)
+	}()
+
+	return e, nil
+}
+
+// GetExport returns one of the user's exports, with a download link once it is ready
+func (s *exportService) GetExport(ctx context.Context, uid uuid.UUID, id uuid.UUID) (*model.DataExport, error) {
+	e, err := s.ExportRepository.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// do not reveal exports of other users exist
+	if e.UID != uid {
+		return nil, apperrors.NewNotFound("export", id.String())
+	}
+
+	if e.Status == model.ExportStatusReady {
+		expires := e.ExpiresAt.Unix()
+		e.DownloadURL = fmt.Sprintf("%s/exports/%s/download?expires=%d&signature=%s", s.BaseURL, e.ID, expires, s.sign(e.ID, expires))
+	}
+
+	return e, nil
+}
+
+// Download verifies a signed download link and returns the export's archive
+func (s *exportService) Download(ctx context.Context, id uuid.UUID, expires int64, signature string) (*model.DataExport, []byte, error) {
+	if time.Now().Unix() > expires {
+		return nil, nil, apperrors.NewAuthorization("Download link has expired")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return nil, nil, apperrors.NewAuthorization("Invalid download link")
+	}
+
+	e, err := s.ExportRepository.Find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if e.Status != model.ExportStatusReady {
+		return nil, nil, apperrors.NewNotFound("export", id.String())
+	}
+
+	archive, err := s.ExportRepository.FindArchive(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e, archive, nil
+}
+
+// SweepExports fails exports which have been pending for longer than they
+// are given to be generated, so the user sees that and can ask again rather
+// than waiting forever. Returns how many were failed
+func (s *exportService) SweepExports(ctx context.Context) (int, error) {
+	// generation is given twice its timeout to save its result
+	exports, err := s.ExportRepository.FindStalePending(ctx, time.Now().Add(-2*exportTimeout), exportSweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+
+	for _, e := range exports {
+		e.Status = model.ExportStatusFailed
+
+		if err := s.ExportRepository.Save(ctx, e); err != nil {
+			log.Printf("failed to fail stale export: %v: %v\n", e.ID, err)
+			continue
+		}
+
+		failed++
+	}
+
+	return failed, nil
+}
+
+// sign creates a signature for a download link so it can be used without an ID token
+func (s *exportService) sign(id uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	fmt.Fprintf(mac, "%s:%d", id, expires)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generate builds and stores the archive of a pending export, marking it ready or failed
+func (s *exportService) generate(ctx context.Context, e *model.DataExport) {
+	archive, err := s.build(ctx, e)
+
+	if err == nil {
+		err = s.ExportRepository.SaveArchive(ctx, e.ID, archive, time.Until(e.ExpiresAt))
+	}
+
+	if err != nil {
+		log.Printf("failed to generate export: %v for uid: %v: %v\n", e.ID, e.UID, err)
+		e.Status = model.ExportStatusFailed
+	} else {
+		e.Status = model.ExportStatusReady
+	}
+
+	if err := s.ExportRepository.Save(ctx, e); err != nil {
+		log.Printf("failed to update status of export: %v: %v\n", e.ID, err)
+	}
+}
+
+// build gathers the user's personal data and packages it in the export's format
+// Only zip exports include the image files themselves
+func (s *exportService) build(ctx context.Context, e *model.DataExport) ([]byte, error) {
+	u, err := s.UserRepository.FindByID(ctx, e.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.TokenRepository.ListUserRefreshTokens(ctx, e.UID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.auditEvents(ctx, e.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &model.PersonalData{
+		ExportedAt:  time.Now(),
+		User:        u,
+		Roles:       u.Roles,
+		Sessions:    sessions,
+		Images:      []*model.ExportedImage{},
+		AuditEvents: events,
+	}
+
+	// every size of the profile image is exported, from smallest to
+	// largest, followed by the previous images kept in its history
+	images := []model.ImageURLs{u.ImageURLs}
+	for _, v := range u.ImageHistory {
+		images = append(images, v.ImageURLs)
+	}
+
+	seen := map[string]bool{}
+
+	for _, imageURLs := range images {
+		for _, size := range model.ProfileImageSizes {
+			imageURL, ok := imageURLs[size]
+			if !ok {
+				continue
+			}
+
+			objName, err := objNameFromURL(imageURL)
+			if err != nil {
+				return nil, err
+			}
+
+			// older images use the same object for every size
+			if seen[objName] {
+				continue
+			}
+			seen[objName] = true
+
+			data.Images = append(data.Images, &model.ExportedImage{
+				URL:        imageURL,
+				ObjectName: objName,
+			})
+		}
+	}
+
+	if e.Format == model.ExportFormatJSON {
+		return json.MarshalIndent(data, "", "  ")
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for _, img := range data.Images {
+		img.File = fmt.Sprintf("images/%s", img.ObjectName)
+
+		if err := s.addImage(ctx, zw, img); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// auditEvents pages through the user's entire audit history
+func (s *exportService) auditEvents(ctx context.Context, uid uuid.UUID) ([]*model.AuditEvent, error) {
+	events := []*model.AuditEvent{}
+	f := &model.AuditFilter{
+		UID:   &uid,
+		Limit: exportAuditPageSize,
+	}
+
+	for {
+		page, err := s.AuditEventRepository.List(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, page...)
+
+		if len(page) < exportAuditPageSize {
+			return events, nil
+		}
+
+		f.Before = page[len(page)-1].ID
+	}
+}
+
+func (s *exportService) addImage(ctx context.Context, zw *zip.Writer, img *model.ExportedImage) error {
+	rc, err := s.ImageRepository.GetProfile(ctx, img.ObjectName)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := zw.Create(img.File)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, rc)
+
+	return err
+}
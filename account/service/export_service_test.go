@@ -0,0 +1,347 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGenerateExport(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+		ImageURLs: model.ImageURLs{
+			64:  "https://storage.googleapis.com/bucket/imageobject-64",
+			512: "https://storage.googleapis.com/bucket/imageobject-512",
+		},
+		Roles: []string{model.RoleSupport},
+	}
+	sessions := []*model.Session{
+		{TokenID: "tokenid", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+
+	t.Run("Zip includes data and images", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockExportRepository := new(mocks.MockExportRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		es := &exportService{
+			UserRepository:       mockUserRepository,
+			TokenRepository:      mockTokenRepository,
+			ImageRepository:      mockImageRepository,
+			ExportRepository:     mockExportRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		}
+
+		e := &model.DataExport{
+			ID:        uuid.New(),
+			UID:       uid,
+			Format:    model.ExportFormatZIP,
+			Status:    model.ExportStatusPending,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+
+		var archive []byte
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockTokenRepository.On("ListUserRefreshTokens", mock.Anything, uid.String()).Return(sessions, nil)
+		mockImageRepository.On("GetProfile", mock.Anything, "imageobject-64").Return(io.NopCloser(strings.NewReader("smallimagebytes")), nil)
+		mockImageRepository.On("GetProfile", mock.Anything, "imageobject-512").Return(io.NopCloser(strings.NewReader("imagebytes")), nil)
+		mockAuditEventRepository.On("List", mock.Anything, mock.MatchedBy(func(f *model.AuditFilter) bool {
+			return *f.UID == uid
+		})).Return([]*model.AuditEvent{{ID: 1, UID: &uid, Event: model.AuditEventSignin}}, nil)
+		mockExportRepository.On("SaveArchive", mock.Anything, e.ID, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).
+			Run(func(args mock.Arguments) {
+				archive = args.Get(2).([]byte)
+			}).Return(nil)
+		mockExportRepository.On("Save", mock.Anything, e).Return(nil)
+
+		es.generate(context.TODO(), e)
+
+		assert.Equal(t, model.ExportStatusReady, e.Status)
+
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		assert.NoError(t, err)
+
+		files := map[string]string{}
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			b, _ := io.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(b)
+		}
+
+		assert.Equal(t, "smallimagebytes", files["images/imageobject-64"])
+		assert.Equal(t, "imagebytes", files["images/imageobject-512"])
+
+		var data model.PersonalData
+		assert.NoError(t, json.Unmarshal([]byte(files["data.json"]), &data))
+		assert.Equal(t, u.Email, data.User.Email)
+		assert.Equal(t, u.Roles, data.Roles)
+		assert.Equal(t, "tokenid", data.Sessions[0].TokenID)
+		assert.Equal(t, "images/imageobject-64", data.Images[0].File)
+		assert.Equal(t, "images/imageobject-512", data.Images[1].File)
+		assert.Equal(t, model.AuditEventSignin, data.AuditEvents[0].Event)
+		assert.NotContains(t, files["data.json"], "password")
+
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+		mockExportRepository.AssertExpectations(t)
+		mockAuditEventRepository.AssertExpectations(t)
+	})
+
+	t.Run("JSON does not fetch images", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockExportRepository := new(mocks.MockExportRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		es := &exportService{
+			UserRepository:       mockUserRepository,
+			TokenRepository:      mockTokenRepository,
+			ImageRepository:      mockImageRepository,
+			ExportRepository:     mockExportRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		}
+
+		e := &model.DataExport{
+			ID:        uuid.New(),
+			UID:       uid,
+			Format:    model.ExportFormatJSON,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+
+		var archive []byte
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockTokenRepository.On("ListUserRefreshTokens", mock.Anything, uid.String()).Return(sessions, nil)
+		mockAuditEventRepository.On("List", mock.Anything, mock.Anything).Return([]*model.AuditEvent{}, nil)
+		mockExportRepository.On("SaveArchive", mock.Anything, e.ID, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).
+			Run(func(args mock.Arguments) {
+				archive = args.Get(2).([]byte)
+			}).Return(nil)
+		mockExportRepository.On("Save", mock.Anything, e).Return(nil)
+
+		es.generate(context.TODO(), e)
+
+		var data model.PersonalData
+		assert.NoError(t, json.Unmarshal(archive, &data))
+		assert.Equal(t, u.ImageURLs[64], data.Images[0].URL)
+		assert.Equal(t, u.ImageURLs[512], data.Images[1].URL)
+		assert.Equal(t, model.ExportStatusReady, e.Status)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Marks export failed", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := &exportService{
+			UserRepository:   mockUserRepository,
+			ExportRepository: mockExportRepository,
+		}
+
+		e := &model.DataExport{
+			ID:     uuid.New(),
+			UID:    uid,
+			Format: model.ExportFormatJSON,
+		}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(nil, apperrors.NewInternal())
+		mockExportRepository.On("Save", mock.Anything, e).Return(nil)
+
+		es.generate(context.TODO(), e)
+
+		assert.Equal(t, model.ExportStatusFailed, e.Status)
+		mockExportRepository.AssertNotCalled(t, "SaveArchive", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRequestExport(t *testing.T) {
+	t.Run("Invalid format", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ESConfig{
+			ExportRepository: mockExportRepository,
+		})
+
+		e, err := es.RequestExport(context.TODO(), uuid.New(), "csv")
+
+		assert.Nil(t, e)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockExportRepository.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetExport(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Ready export has signed download link", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ESConfig{
+			ExportRepository: mockExportRepository,
+			Secret:           "secret",
+			BaseURL:          "/api/account",
+		})
+
+		e := &model.DataExport{
+			ID:        uuid.New(),
+			UID:       uid,
+			Status:    model.ExportStatusReady,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+
+		mockExportRepository.On("Find", mock.Anything, e.ID).Return(e, nil)
+
+		res, err := es.GetExport(context.TODO(), uid, e.ID)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(res.DownloadURL, "/api/account/exports/"+e.ID.String()+"/download?expires="))
+		assert.Contains(t, res.DownloadURL, "signature="+es.(*exportService).sign(e.ID, e.ExpiresAt.Unix()))
+	})
+
+	t.Run("Export of another user", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ESConfig{
+			ExportRepository: mockExportRepository,
+		})
+
+		e := &model.DataExport{
+			ID:     uuid.New(),
+			UID:    uuid.New(),
+			Status: model.ExportStatusReady,
+		}
+
+		mockExportRepository.On("Find", mock.Anything, e.ID).Return(e, nil)
+
+		res, err := es.GetExport(context.TODO(), uid, e.ID)
+
+		assert.Nil(t, res)
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
+
+func TestDownloadExport(t *testing.T) {
+	id := uuid.New()
+	expires := time.Now().Add(time.Hour).Unix()
+
+	es := &exportService{Secret: "secret"}
+	signature := es.sign(id, expires)
+
+	t.Run("Success", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := &exportService{
+			ExportRepository: mockExportRepository,
+			Secret:           "secret",
+		}
+
+		e := &model.DataExport{
+			ID:     id,
+			Status: model.ExportStatusReady,
+		}
+
+		mockExportRepository.On("Find", mock.Anything, id).Return(e, nil)
+		mockExportRepository.On("FindArchive", mock.Anything, id).Return([]byte("archive"), nil)
+
+		res, archive, err := es.Download(context.TODO(), id, expires, signature)
+
+		assert.NoError(t, err)
+		assert.Equal(t, e, res)
+		assert.Equal(t, []byte("archive"), archive)
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := &exportService{
+			ExportRepository: mockExportRepository,
+			Secret:           "othersecret",
+		}
+
+		_, _, err := es.Download(context.TODO(), id, expires, signature)
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockExportRepository.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Tampered expiry", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := &exportService{
+			ExportRepository: mockExportRepository,
+			Secret:           "secret",
+		}
+
+		_, _, err := es.Download(context.TODO(), id, expires+3600, signature)
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Expired link", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := &exportService{
+			ExportRepository: mockExportRepository,
+			Secret:           "secret",
+		}
+
+		expired := time.Now().Add(-time.Minute).Unix()
+
+		_, _, err := es.Download(context.TODO(), id, expired, es.sign(id, expired))
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockExportRepository.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSweepExports(t *testing.T) {
+	t.Run("Fails stale pending exports", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ESConfig{
+			ExportRepository: mockExportRepository,
+		})
+
+		stale := &model.DataExport{ID: uuid.New(), Status: model.ExportStatusPending}
+		unsaved := &model.DataExport{ID: uuid.New(), Status: model.ExportStatusPending}
+
+		before := mock.MatchedBy(func(before time.Time) bool {
+			return before.Before(time.Now().Add(-exportTimeout))
+		})
+		mockExportRepository.On("FindStalePending", mock.Anything, before, exportSweepBatchSize).Return([]*model.DataExport{stale, unsaved}, nil)
+		mockExportRepository.On("Save", mock.Anything, stale).Return(nil)
+		mockExportRepository.On("Save", mock.Anything, unsaved).Return(apperrors.NewInternal())
+
+		swept, err := es.SweepExports(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, swept)
+		assert.Equal(t, model.ExportStatusFailed, stale.Status)
+		mockExportRepository.AssertExpectations(t)
+	})
+
+	t.Run("Error finding stale exports", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ESConfig{
+			ExportRepository: mockExportRepository,
+		})
+
+		mockExportRepository.On("FindStalePending", mock.Anything, mock.Anything, mock.Anything).Return(nil, apperrors.NewInternal())
+
+		swept, err := es.SweepExports(context.TODO())
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, swept)
+		mockExportRepository.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+// defaultImageGCGracePeriod is how old an unreferenced image must be before it is deleted
+const defaultImageGCGracePeriod = 24 * time.Hour
+
+// imageCollector deletes images left in storage which no user refers to,
+// such as those stored before a failed database update, the images of
+// purged accounts, and uploads straight to storage which were never committed
+type imageCollector struct {
+	UserRepository  model.UserRepository
+	ImageRepository model.ImageRepository
+	GracePeriod     time.Duration
+}
+
+// ICConfig will hold repositories that will eventually be injected
+// into the image collector
+type ICConfig struct {
+	UserRepository  model.UserRepository
+	ImageRepository model.ImageRepository
+	// GracePeriod is how old an unreferenced image must be before it is
+	// deleted, defaulting to defaultImageGCGracePeriod. It must be longer
+	// than uploads take to be stored and referred to, and than ImageUploadTTL
+	GracePeriod time.Duration
+}
+
+// NewImageCollector is a factory function for initializing
+// an ImageCollector with its repository layer dependencies
+func NewImageCollector(c *ICConfig) model.ImageCollector {
+	gracePeriod := c.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultImageGCGracePeriod
+	}
+
+	return &imageCollector{
+		UserRepository:  c.UserRepository,
+		ImageRepository: c.ImageRepository,
+		GracePeriod:     gracePeriod,
+	}
+}
+
+// Collect deletes every image in storage which no user refers to and is older
+// than the grace period, reporting all unreferenced images. A dry run only
+// reports what would be deleted. Images which fail to delete are logged and
+// left for the next pass
+func (s *imageCollector) Collect(ctx context.Context, dryRun bool) (*model.ImageGCReport, error) {
+	// images are listed before references are read, so an image which is
+	// stored and referred to in between is never mistaken for an orphan
+	images, err := s.ImageRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	imageURLs, err := s.UserRepository.ListImageURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool, len(imageURLs))
+
+	for _, imageURL := range imageURLs {
+		objName, err := objNameFromURL(imageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		referenced[objName] = true
+	}
+
+	report := &model.ImageGCReport{
+		Scanned: len(images),
+		Orphans: []*model.StoredImage{},
+		Deleted: []string{},
+		DryRun:  dryRun,
+	}
+
+	cutoff := time.Now().Add(-s.GracePeriod)
+
+	for _, img := range images {
+		if referenced[img.ObjectName] {
+			report.Referenced++
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, img)
+
+		// images are stored before they are referred to
+		if img.UpdatedAt.After(cutoff) {
+			continue
+		}
+
+		if !dryRun {
+			if err := s.ImageRepository.DeleteProfile(ctx, img.ObjectName); err != nil {
+				log.Printf("failed to delete orphaned image: %v: %v\n", img.ObjectName, err)
+				continue
+			}
+		}
+
+		report.Deleted = append(report.Deleted, img.ObjectName)
+	}
+
+	return report, nil
+}
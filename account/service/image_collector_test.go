@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCollect(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	// avatar is referred to by URL, and private by object name
+	images := []*model.StoredImage{
+		{ObjectName: "avatar-64", UpdatedAt: old},
+		{ObjectName: "private-64", UpdatedAt: old},
+		{ObjectName: "orphan-64", UpdatedAt: old},
+		{ObjectName: "upload-uid-old", UpdatedAt: old},
+		{ObjectName: "upload-uid-new", UpdatedAt: time.Now()},
+	}
+	imageURLs := []string{
+		"https://storage.googleapis.com/bucket/avatar-64",
+		"private-64",
+	}
+
+	newCollector := func() (model.ImageCollector, *mocks.MockUserRepository, *mocks.MockImageRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		return NewImageCollector(&ICConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+			GracePeriod:     24 * time.Hour,
+		}), mockUserRepository, mockImageRepository
+	}
+
+	t.Run("Deletes orphans after the grace period", func(t *testing.T) {
+		c, mockUserRepository, mockImageRepository := newCollector()
+		mockImageRepository.On("List", mock.Anything).Return(images, nil)
+		mockUserRepository.On("ListImageURLs", mock.Anything).Return(imageURLs, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "orphan-64").Return(nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "upload-uid-old").Return(nil)
+
+		report, err := c.Collect(context.TODO(), false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, report.Scanned)
+		assert.Equal(t, 2, report.Referenced)
+		assert.Equal(t, images[2:], report.Orphans)
+		assert.Equal(t, []string{"orphan-64", "upload-uid-old"}, report.Deleted)
+		assert.False(t, report.DryRun)
+		mockImageRepository.AssertExpectations(t)
+		mockImageRepository.AssertNumberOfCalls(t, "DeleteProfile", 2)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		c, mockUserRepository, mockImageRepository := newCollector()
+		mockImageRepository.On("List", mock.Anything).Return(images, nil)
+		mockUserRepository.On("ListImageURLs", mock.Anything).Return(imageURLs, nil)
+
+		report, err := c.Collect(context.TODO(), true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, images[2:], report.Orphans)
+		assert.Equal(t, []string{"orphan-64", "upload-uid-old"}, report.Deleted)
+		assert.True(t, report.DryRun)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed delete is left for the next pass", func(t *testing.T) {
+		c, mockUserRepository, mockImageRepository := newCollector()
+		mockImageRepository.On("List", mock.Anything).Return(images, nil)
+		mockUserRepository.On("ListImageURLs", mock.Anything).Return(imageURLs, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "orphan-64").Return(apperrors.NewInternal())
+		mockImageRepository.On("DeleteProfile", mock.Anything, "upload-uid-old").Return(nil)
+
+		report, err := c.Collect(context.TODO(), false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"upload-uid-old"}, report.Deleted)
+	})
+
+	t.Run("List error", func(t *testing.T) {
+		c, mockUserRepository, mockImageRepository := newCollector()
+		mockImageRepository.On("List", mock.Anything).Return(nil, apperrors.NewInternal())
+
+		report, err := c.Collect(context.TODO(), false)
+
+		assert.Nil(t, report)
+		assert.Equal(t, apperrors.NewInternal(), err)
+		mockUserRepository.AssertNotCalled(t, "ListImageURLs", mock.Anything)
+	})
+
+	t.Run("References error", func(t *testing.T) {
+		c, mockUserRepository, mockImageRepository := newCollector()
+		mockImageRepository.On("List", mock.Anything).Return(images, nil)
+		mockUserRepository.On("ListImageURLs", mock.Anything).Return(nil, apperrors.NewInternal())
+
+		report, err := c.Collect(context.TODO(), false)
+
+		assert.Nil(t, report)
+		assert.Equal(t, apperrors.NewInternal(), err)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif" // register GIF decoding, of which only the first frame is used
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/url"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register WebP decoding
+)
+
+// profileImageQuality is the quality of JPEG encoded profile images
+const profileImageQuality = 85
+
+// defaultMaxImageDimension is the largest width or height of an
+// uploaded image when the service isn't configured with one
+const defaultMaxImageDimension = 8192
+
+// defaultMaxImageBytes is the largest image uploaded straight to storage
+// when the service isn't configured with a limit
+const defaultMaxImageBytes = 4 * 1024 * 1024
+
+// defaultImageUploadTTL is how long uploads straight to storage can be
+// started when the service isn't configured with a time
+const defaultImageUploadTTL = 15 * time.Minute
+
+// errImageTooLarge is returned for images with more pixels than allowed
+var errImageTooLarge = errors.New("image dimensions exceed the maximum")
+
+// processProfileImage decodes an uploaded image, crops the largest square from
+// its center, turns it upright according to its EXIF orientation and encodes it
+// in each of sizes. Opaque images are encoded as JPEG, others as PNG.
+// Only pixels are carried over, so none of the upload's metadata (EXIF with
+// GPS coordinates and serial numbers, XMP, comments or PNG text chunks) is
+// published. The orientation is the one piece worth keeping, and it is
+// applied to the pixels rather than copied as a tag.
+// The dimensions are read from the header before decoding, so that an image
+// wider or taller than maxDimension never has its pixels allocated
+func processProfileImage(data []byte, sizes []int, maxDimension int) (map[int][]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return nil, errImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if src.Bounds().Empty() {
+		return nil, errors.New("image has no pixels")
+	}
+
+	// the centered square is the same whichever way the image is turned, so
+	// it is scaled down before being oriented, which is far cheaper
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	crop := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+	orientation := exifOrientation(data)
+
+	// each size is scaled from the next larger one
+	versions := make(map[int][]byte, len(sizes))
+	var prev image.Image = src
+	prevRect := crop
+
+	for i := len(sizes) - 1; i >= 0; i-- {
+		size := sizes[i]
+
+		dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), prev, prevRect, draw.Src, nil)
+		prev, prevRect = dst, dst.Bounds()
+
+		encoded, err := encodeProfileImage(orient(dst, orientation))
+		if err != nil {
+			return nil, err
+		}
+
+		versions[size] = encoded
+	}
+
+	return versions, nil
+}
+
+// encodeProfileImage encodes img as JPEG, or as PNG when it has transparency.
+// Decoding jpeg and png images works as those packages are imported here.
+// Neither encoder writes metadata: JPEGs hold no APPn or COM segments and
+// PNGs only the IHDR, PLTE, tRNS, IDAT and IEND chunks
+func encodeProfileImage(img *image.NRGBA) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if img.Opaque() {
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: profileImageQuality}); err != nil {
+			return nil, err
+		}
+	} else if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// orient applies an EXIF orientation to img, returning an upright image
+// Orientations 5 to 8 swap width and height, which for a square changes nothing
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 counter-clockwise, so turn it clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 clockwise, so turn it counter-clockwise
+				dx, dy = y, w-1-x
+			}
+
+			si := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
+
+// exifOrientation returns the orientation stored in a JPEG's EXIF
+// data, or 1, meaning upright, when there is none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+
+		// markers may be padded with fill bytes
+		if marker == 0xFF {
+			i++
+			continue
+		}
+
+		// metadata is only found before the image data starts
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of TIFF structured EXIF data
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+
+	entries := int(order.Uint16(t[ifd:]))
+
+	for k := 0; k < entries; k++ {
+		e := ifd + 2 + 12*k
+		if e+12 > len(t) {
+			return 1
+		}
+
+		if order.Uint16(t[e:]) == 0x0112 {
+			if o := int(order.Uint16(t[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+
+	return 1
+}
+
+// resolveImageURLs turns the image stored for a user into URLs clients can
+// load. Users without an image are given the URLs of their default avatar,
+// served under baseURL. Other images are signed by signImageURLs
+func resolveImageURLs(ctx context.Context, r model.ImageRepository, ttl time.Duration, publicURL string, baseURL string, u *model.User) error {
+	if len(u.ImageURLs) == 0 {
+		u.ImageURLs = defaultAvatarURLs(baseURL, u.UID)
+		return nil
+	}
+
+	signed, err := signImageURLs(ctx, r, ttl, publicURL, u.ImageURLs)
+	if err != nil {
+		return err
+	}
+
+	u.ImageURLs = signed
+
+	return nil
+}
+
+// signImageURLs swaps object keys in private storage for URLs signed for ttl.
+// Public storage has a zero ttl, and its stored URLs are returned as they are,
+// or moved under publicURL when there is one. Images stored before the storage
+// was made private still hold URLs, so they are signed by their object name
+func signImageURLs(ctx context.Context, r model.ImageRepository, ttl time.Duration, publicURL string, imageURLs model.ImageURLs) (model.ImageURLs, error) {
+	if ttl <= 0 && publicURL == "" {
+		return imageURLs, nil
+	}
+
+	signed := make(model.ImageURLs, len(imageURLs))
+
+	for size, stored := range imageURLs {
+		objName, err := objNameFromURL(stored)
+		if err != nil {
+			return nil, err
+		}
+
+		if ttl <= 0 {
+			signed[size] = publicURL + "/" + url.PathEscape(objName)
+			continue
+		}
+
+		if signed[size], err = r.SignURL(ctx, objName, ttl); err != nil {
+			return nil, err
+		}
+	}
+
+	return signed, nil
+}
+
+// imageFile is an in memory multipart.File, used to store processed images
+type imageFile struct {
+	*bytes.Reader
+}
+
+func newImageFile(data []byte) multipart.File {
+	return imageFile{bytes.NewReader(data)}
+}
+
+// Close does nothing as there is nothing to release
+func (imageFile) Close() error { return nil }
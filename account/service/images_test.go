@@ -0,0 +1,343 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withOrientation inserts an EXIF APP1 segment holding orientation into a JPEG
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := &bytes.Buffer{}
+	tiff.WriteString("MM")
+	binary.Write(tiff, binary.BigEndian, uint16(42))
+	binary.Write(tiff, binary.BigEndian, uint32(8))
+	binary.Write(tiff, binary.BigEndian, uint16(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(tiff, binary.BigEndian, uint32(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(tiff, binary.BigEndian, uint32(0))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	out := &bytes.Buffer{}
+	out.Write(data[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(data[2:])
+
+	return out.Bytes()
+}
+
+// withSegment inserts a JPEG segment after the start of image marker
+func withSegment(data []byte, marker byte, payload []byte) []byte {
+	out := &bytes.Buffer{}
+	out.Write(data[:2])
+	out.Write([]byte{0xFF, marker})
+	binary.Write(out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(data[2:])
+
+	return out.Bytes()
+}
+
+// withChunk inserts a PNG chunk after the IHDR chunk
+func withChunk(data []byte, chunkType string, payload []byte) []byte {
+	// signature (8) and IHDR: length (4), type (4), data (13), crc (4)
+	const afterIHDR = 8 + 4 + 4 + 13 + 4
+
+	chunk := &bytes.Buffer{}
+	binary.Write(chunk, binary.BigEndian, uint32(len(payload)))
+	chunk.WriteString(chunkType)
+	chunk.Write(payload)
+	binary.Write(chunk, binary.BigEndian, crc32.ChecksumIEEE(chunk.Bytes()[4:]))
+
+	out := &bytes.Buffer{}
+	out.Write(data[:afterIHDR])
+	out.Write(chunk.Bytes())
+	out.Write(data[afterIHDR:])
+
+	return out.Bytes()
+}
+
+// jpegMarkers lists the markers of the segments before a JPEG's image data
+func jpegMarkers(t *testing.T, data []byte) []byte {
+	var markers []byte
+
+	for i := 2; i+4 <= len(data); {
+		assert.Equal(t, byte(0xFF), data[i])
+
+		marker := data[i+1]
+		if marker == 0xDA {
+			break
+		}
+
+		markers = append(markers, marker)
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+
+	return markers
+}
+
+// pngChunkTypes lists the type of every chunk in a PNG
+func pngChunkTypes(data []byte) []string {
+	var types []string
+
+	for i := 8; i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		types = append(types, string(data[i+4:i+8]))
+		i += 12 + length
+	}
+
+	return types
+}
+
+func TestProcessProfileImage(t *testing.T) {
+	sizes := []int{64, 128, 512}
+
+	t.Run("Crops JPEG to square sizes", func(t *testing.T) {
+		// left half red, right half blue
+		src := image.NewRGBA(image.Rect(0, 0, 800, 600))
+		for y := 0; y < 600; y++ {
+			for x := 0; x < 800; x++ {
+				c := color.RGBA{255, 0, 0, 255}
+				if x >= 400 {
+					c = color.RGBA{0, 0, 255, 255}
+				}
+				src.Set(x, y, c)
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, jpeg.Encode(buf, src, nil))
+
+		versions, err := processProfileImage(buf.Bytes(), sizes, defaultMaxImageDimension)
+		assert.NoError(t, err)
+		assert.Len(t, versions, len(sizes))
+
+		for _, size := range sizes {
+			img, format, err := image.Decode(bytes.NewReader(versions[size]))
+			assert.NoError(t, err)
+			assert.Equal(t, "jpeg", format)
+			assert.Equal(t, image.Rect(0, 0, size, size), img.Bounds())
+		}
+	})
+
+	t.Run("Applies EXIF orientation", func(t *testing.T) {
+		// top half red, bottom half blue. Orientation 6 means it has to be
+		// turned clockwise, after which red is on the right
+		src := image.NewRGBA(image.Rect(0, 0, 200, 200))
+		for y := 0; y < 200; y++ {
+			for x := 0; x < 200; x++ {
+				c := color.RGBA{255, 0, 0, 255}
+				if y >= 100 {
+					c = color.RGBA{0, 0, 255, 255}
+				}
+				src.Set(x, y, c)
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, jpeg.Encode(buf, src, &jpeg.Options{Quality: 100}))
+		data := withOrientation(buf.Bytes(), 6)
+
+		assert.Equal(t, 6, exifOrientation(data))
+
+		versions, err := processProfileImage(data, []int{64}, defaultMaxImageDimension)
+		assert.NoError(t, err)
+
+		img, _, err := image.Decode(bytes.NewReader(versions[64]))
+		assert.NoError(t, err)
+
+		r, _, b, _ := img.At(60, 32).RGBA()
+		assert.Greater(t, r, b)
+		r, _, b, _ = img.At(4, 32).RGBA()
+		assert.Greater(t, b, r)
+	})
+
+	t.Run("Keeps transparency as PNG", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, png.Encode(buf, src))
+
+		versions, err := processProfileImage(buf.Bytes(), []int{64}, defaultMaxImageDimension)
+		assert.NoError(t, err)
+
+		_, format, err := image.Decode(bytes.NewReader(versions[64]))
+		assert.NoError(t, err)
+		assert.Equal(t, "png", format)
+	})
+
+	t.Run("Decodes the first frame of a GIF", func(t *testing.T) {
+		palette := color.Palette{color.Black, color.White}
+		first := image.NewPaletted(image.Rect(0, 0, 100, 100), palette)
+		second := image.NewPaletted(image.Rect(0, 0, 100, 100), palette)
+		for i := range second.Pix {
+			second.Pix[i] = 1
+		}
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, gif.EncodeAll(buf, &gif.GIF{
+			Image: []*image.Paletted{first, second},
+			Delay: []int{10, 10},
+		}))
+
+		versions, err := processProfileImage(buf.Bytes(), []int{64}, defaultMaxImageDimension)
+		assert.NoError(t, err)
+
+		img, format, err := image.Decode(bytes.NewReader(versions[64]))
+		assert.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+
+		// the first frame is black
+		r, g, b, _ := img.At(32, 32).RGBA()
+		assert.Less(t, r+g+b, uint32(3*0x1000))
+	})
+
+	t.Run("Decodes WebP", func(t *testing.T) {
+		// a transparent 1x1 lossless WebP
+		data := []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+		versions, err := processProfileImage(data, []int{64}, defaultMaxImageDimension)
+		assert.NoError(t, err)
+
+		img, format, err := image.Decode(bytes.NewReader(versions[64]))
+		assert.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, image.Rect(0, 0, 64, 64), img.Bounds())
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 101, 10))))
+
+		versions, err := processProfileImage(buf.Bytes(), sizes, 100)
+		assert.Equal(t, errImageTooLarge, err)
+		assert.Nil(t, versions)
+	})
+
+	t.Run("Decompression bomb", func(t *testing.T) {
+		// a tiny PNG whose header claims to be 100000x100000 pixels
+		buf := &bytes.Buffer{}
+		assert.NoError(t, png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 1, 1))))
+		data := buf.Bytes()
+
+		// the IHDR chunk's data starts after the signature, length and type
+		binary.BigEndian.PutUint32(data[16:], 100000)
+		binary.BigEndian.PutUint32(data[20:], 100000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+		versions, err := processProfileImage(data, sizes, defaultMaxImageDimension)
+		assert.Equal(t, errImageTooLarge, err)
+		assert.Nil(t, versions)
+	})
+
+	t.Run("Invalid image", func(t *testing.T) {
+		versions, err := processProfileImage([]byte("not an image"), sizes, defaultMaxImageDimension)
+		assert.Error(t, err)
+		assert.Nil(t, versions)
+	})
+}
+
+func TestProcessProfileImageStripsMetadata(t *testing.T) {
+	sizes := []int{64, 128, 512}
+	secrets := []string{"Exif", "GPS", "serial-1234", "xmpmeta", "ns.adobe.com", "a comment", "Author"}
+
+	assertNoSecrets := func(t *testing.T, data []byte) {
+		for _, secret := range secrets {
+			assert.False(t, bytes.Contains(data, []byte(secret)), "found %q", secret)
+		}
+	}
+
+	t.Run("JPEG", func(t *testing.T) {
+		// top half red, bottom half blue
+		src := image.NewRGBA(image.Rect(0, 0, 300, 200))
+		for y := 0; y < 200; y++ {
+			for x := 0; x < 300; x++ {
+				c := color.RGBA{255, 0, 0, 255}
+				if y >= 100 {
+					c = color.RGBA{0, 0, 255, 255}
+				}
+				src.Set(x, y, c)
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, jpeg.Encode(buf, src, &jpeg.Options{Quality: 100}))
+
+		data := withSegment(buf.Bytes(), 0xFE, []byte("a comment"))
+		data = withSegment(data, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>GPS serial-1234</x:xmpmeta>"))
+		data = withSegment(data, 0xED, []byte("Photoshop 3.0\x00Author"))
+		data = withOrientation(data, 6)
+		assert.True(t, bytes.Contains(data, []byte("xmpmeta")))
+
+		versions, err := processProfileImage(data, sizes, defaultMaxImageDimension)
+		assert.NoError(t, err)
+
+		for _, size := range sizes {
+			// no APPn or COM segments, which is where EXIF and XMP live
+			for _, marker := range jpegMarkers(t, versions[size]) {
+				assert.False(t, marker >= 0xE0 && marker <= 0xEF, "found APP%d segment", marker-0xE0)
+				assert.NotEqual(t, byte(0xFE), marker, "found COM segment")
+			}
+
+			assertNoSecrets(t, versions[size])
+			assert.Equal(t, 1, exifOrientation(versions[size]))
+		}
+
+		// the orientation is kept by turning the pixels: red ends up on the right
+		img, _, err := image.Decode(bytes.NewReader(versions[64]))
+		assert.NoError(t, err)
+
+		r, _, b, _ := img.At(60, 32).RGBA()
+		assert.Greater(t, r, b)
+		r, _, b, _ = img.At(4, 32).RGBA()
+		assert.Greater(t, b, r)
+	})
+
+	t.Run("PNG", func(t *testing.T) {
+		// transparent, so the processed image stays a PNG
+		buf := &bytes.Buffer{}
+		assert.NoError(t, png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 100, 100))))
+
+		data := withChunk(buf.Bytes(), "tEXt", []byte("Author\x00serial-1234"))
+		data = withChunk(data, "zTXt", []byte("Comment\x00\x00x\x9c\x03\x00\x00\x00\x00\x01"))
+		data = withChunk(data, "iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta>GPS</x:xmpmeta>"))
+		data = withChunk(data, "eXIf", []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x00GPS"))
+		assert.Contains(t, pngChunkTypes(data), "tEXt")
+
+		versions, err := processProfileImage(data, sizes, defaultMaxImageDimension)
+		assert.NoError(t, err)
+
+		for _, size := range sizes {
+			for _, chunkType := range pngChunkTypes(versions[size]) {
+				assert.Contains(t, []string{"IHDR", "IDAT", "IEND"}, chunkType)
+			}
+
+			assertNoSecrets(t, versions[size])
+		}
+	})
+}
+
+func TestExifOrientation(t *testing.T) {
+	assert.Equal(t, 1, exifOrientation(nil))
+	assert.Equal(t, 1, exifOrientation([]byte("\x89PNG\r\n\x1a\n")))
+
+	buf := &bytes.Buffer{}
+	jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 1, 1)), nil)
+	assert.Equal(t, 1, exifOrientation(buf.Bytes()))
+	assert.Equal(t, 3, exifOrientation(withOrientation(buf.Bytes(), 3)))
+
+	// out of range orientations are ignored
+	assert.Equal(t, 1, exifOrientation(withOrientation(buf.Bytes(), 9)))
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// invitationService acts as a struct for injecting the repositories
+// invitations are kept in, along with the UserService used to sign up invitees
+type invitationService struct {
+	InvitationRepository   model.InvitationRepository
+	OrganizationRepository model.OrganizationRepository
+	UserRepository         model.UserRepository
+	UserService            model.UserService
+	Transactor             model.Transactor
+	InvitationTTL          time.Duration
+}
+
+// ISConfig will hold repositories that will eventually be injected
+// into this service layer
+type ISConfig struct {
+	InvitationRepository   model.InvitationRepository
+	OrganizationRepository model.OrganizationRepository
+	UserRepository         model.UserRepository
+	UserService            model.UserService
+	Transactor             model.Transactor
+	InvitationTTL          time.Duration
+}
+
+// NewInvitationService is a factory function for initializing
+// an InvitationService with its repository layer dependencies
+func NewInvitationService(c *ISConfig) model.InvitationService {
+	return &invitationService{
+		InvitationRepository:   c.InvitationRepository,
+		OrganizationRepository: c.OrganizationRepository,
+		UserRepository:         c.UserRepository,
+		UserService:            c.UserService,
+		Transactor:             c.Transactor,
+		InvitationTTL:          c.InvitationTTL,
+	}
+}
+
+// Invite invites an email into an organization, returning the invitation along
+// with the token to send to the invitee, which is not stored and cannot be
+// retrieved again. The actor must be an admin or owner, and only owners may
+// invite owners. Emails which already belong to a member cannot be invited
+func (s *invitationService) Invite(ctx context.Context, actor *model.User, orgID uuid.UUID, email string, role string) (*model.Invitation, string, error) {
+	if !model.ValidOrgRole(role) {
+		return nil, "", apperrors.NewBadRequest("role must be one of owner, admin or member")
+	}
+
+	am, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, orgID, model.OrgRoleAdmin)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !model.OrgRoleAtLeast(am.Role, role) {
+		return nil, "", apperrors.NewForbidden("only owners can invite owners")
+	}
+
+	if u, err := s.UserRepository.FindByEmail(ctx, email); err == nil {
+		if _, err := s.OrganizationRepository.FindMember(ctx, orgID, u.UID); err == nil {
+			return nil, "", apperrors.NewConflict("member", email)
+		}
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		log.Printf("unable to generate invitation token: %v\n", err)
+		return nil, "", apperrors.NewInternal()
+	}
+
+	inviter := actor.UID
+	inv := &model.Invitation{
+		OrgID:     orgID,
+		Email:     email,
+		Role:      role,
+		InvitedBy: &inviter,
+		TokenHash: hashInvitationToken(token),
+		ExpiresAt: time.Now().Add(s.InvitationTTL),
+	}
+
+	if err := s.InvitationRepository.Create(ctx, inv); err != nil {
+		return nil, "", err
+	}
+
+	return inv, token, nil
+}
+
+// ListSent returns the invitations the actor has sent
+func (s *invitationService) ListSent(ctx context.Context, actor *model.User) ([]*model.Invitation, error) {
+	return s.InvitationRepository.ListByInviter(ctx, actor.UID)
+}
+
+// ListReceived returns the pending invitations sent to the actor's email.
+// They are accepted with the token sent to the email, which proves the
+// actor owns it
+func (s *invitationService) ListReceived(ctx context.Context, actor *model.User) ([]*model.Invitation, error) {
+	return s.InvitationRepository.ListByEmail(ctx, actor.Email)
+}
+
+// Revoke withdraws a pending invitation. The actor must
+// have sent it or be an admin or owner of its organization
+func (s *invitationService) Revoke(ctx context.Context, actor *model.User, id uuid.UUID) error {
+	return s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		inv, err := s.InvitationRepository.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if inv.InvitedBy == nil || *inv.InvitedBy != actor.UID {
+			if _, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, inv.OrgID, model.OrgRoleAdmin); err != nil {
+				// hide invitations of other organizations
+				return apperrors.NewNotFound("invitation", id.String())
+			}
+		}
+
+		if inv.Status != model.InvitationPending {
+			return apperrors.NewBadRequest("invitation is " + inv.Status)
+		}
+
+		now := time.Now()
+		inv.Status = model.InvitationRevoked
+		inv.RevokedAt = &now
+
+		return s.InvitationRepository.Update(ctx, inv)
+	})
+}
+
+// Accept adds the actor to the organization they were invited to. The
+// invitation must have been sent to the actor's email
+func (s *invitationService) Accept(ctx context.Context, actor *model.User, token string) (*model.OrgMember, error) {
+	var m *model.OrgMember
+
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		inv, err := s.pending(ctx, token, actor.Email)
+		if err != nil {
+			return err
+		}
+
+		m, err = s.join(ctx, inv, actor)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	m.Name = actor.Name
+	m.Email = actor.Email
+
+	return m, nil
+}
+
+// Signup signs up an invitee who does not have an account and adds them to
+// the organization they were invited to. As the token was sent to the invited
+// email, the new user's email is verified
+func (s *invitationService) Signup(ctx context.Context, u *model.User, token string) error {
+	return s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		inv, err := s.pending(ctx, token, u.Email)
+		if err != nil {
+			return err
+		}
+
+		u.EmailVerified = true
+
+		if err := s.UserService.Signup(ctx, u); err != nil {
+			return err
+		}
+
+		m, err := s.join(ctx, inv, u)
+		if err != nil {
+			return err
+		}
+
+		// the user was loaded before joining, so their tokens need the organization added
+		u.Orgs = map[string]string{m.OrgID.String(): m.Role}
+
+		return nil
+	})
+}
+
+// pending finds the pending invitation for token, checking it was sent to email
+func (s *invitationService) pending(ctx context.Context, token string, email string) (*model.Invitation, error) {
+	inv, err := s.InvitationRepository.FindByTokenHash(ctx, hashInvitationToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if inv.Status != model.InvitationPending {
+		return nil, apperrors.NewBadRequest("invitation is " + inv.Status)
+	}
+
+	if !strings.EqualFold(inv.Email, email) {
+		return nil, apperrors.NewForbidden("invitation was sent to another email")
+	}
+
+	return inv, nil
+}
+
+// join adds u to the invitation's organization and marks it as accepted
+func (s *invitationService) join(ctx context.Context, inv *model.Invitation, u *model.User) (*model.OrgMember, error) {
+	m := &model.OrgMember{
+		OrgID: inv.OrgID,
+		UID:   u.UID,
+		Role:  inv.Role,
+	}
+
+	if err := s.OrganizationRepository.AddMember(ctx, m); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv.Status = model.InvitationAccepted
+	inv.AcceptedBy = &u.UID
+	inv.AcceptedAt = &now
+
+	if err := s.InvitationRepository.Update(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// newInvitationToken creates a random token to send to an invitee
+func newInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashInvitationToken hashes a token for storage. Tokens are random,
+// so they don't need a slow password hash
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
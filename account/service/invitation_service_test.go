@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type invitationMocks struct {
+	InvitationRepository   *mocks.MockInvitationRepository
+	OrganizationRepository *mocks.MockOrganizationRepository
+	UserRepository         *mocks.MockUserRepository
+	UserService            *mocks.MockUserService
+}
+
+func newInvitationService() (model.InvitationService, *invitationMocks) {
+	m := &invitationMocks{
+		InvitationRepository:   new(mocks.MockInvitationRepository),
+		OrganizationRepository: new(mocks.MockOrganizationRepository),
+		UserRepository:         new(mocks.MockUserRepository),
+		UserService:            new(mocks.MockUserService),
+	}
+
+	mockTransactor := new(mocks.MockTransactor)
+	mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+	s := NewInvitationService(&ISConfig{
+		InvitationRepository:   m.InvitationRepository,
+		OrganizationRepository: m.OrganizationRepository,
+		UserRepository:         m.UserRepository,
+		UserService:            m.UserService,
+		Transactor:             mockTransactor,
+		InvitationTTL:          72 * time.Hour,
+	})
+
+	return s, m
+}
+
+func TestInvite(t *testing.T) {
+	orgID := uuid.New()
+	actor := &model.User{UID: uuid.New()}
+
+	t.Run("Invites email without an account", func(t *testing.T) {
+		s, m := newInvitationService()
+
+		m.OrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleAdmin), nil)
+		m.UserRepository.On("FindByEmail", mock.Anything, "new@example.com").Return(nil, apperrors.NewNotFound("email", "new@example.com"))
+		m.InvitationRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Invitation")).Return(nil)
+
+		inv, token, err := s.Invite(context.TODO(), actor, orgID, "new@example.com", model.OrgRoleMember)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.Equal(t, hashInvitationToken(token), inv.TokenHash)
+		assert.Equal(t, actor.UID, *inv.InvitedBy)
+		assert.WithinDuration(t, time.Now().Add(72*time.Hour), inv.ExpiresAt, time.Second)
+	})
+
+	t.Run("Email already a member", func(t *testing.T) {
+		s, m := newInvitationService()
+		existing := &model.User{UID: uuid.New(), Email: "bob@example.com"}
+
+		m.OrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleOwner), nil)
+		m.UserRepository.On("FindByEmail", mock.Anything, existing.Email).Return(existing, nil)
+		m.OrganizationRepository.On("FindMember", mock.Anything, orgID, existing.UID).Return(member(orgID, existing.UID, model.OrgRoleMember), nil)
+
+		_, _, err := s.Invite(context.TODO(), actor, orgID, existing.Email, model.OrgRoleMember)
+
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+		m.InvitationRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Admin cannot invite owner", func(t *testing.T) {
+		s, m := newInvitationService()
+
+		m.OrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleAdmin), nil)
+
+		_, _, err := s.Invite(context.TODO(), actor, orgID, "new@example.com", model.OrgRoleOwner)
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+	})
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	orgID := uuid.New()
+	token := "invitation-token"
+
+	pending := func(email string) *model.Invitation {
+		return &model.Invitation{
+			ID:     uuid.New(),
+			OrgID:  orgID,
+			Email:  email,
+			Role:   model.OrgRoleAdmin,
+			Status: model.InvitationPending,
+		}
+	}
+
+	t.Run("Existing user joins", func(t *testing.T) {
+		s, m := newInvitationService()
+		actor := &model.User{UID: uuid.New(), Email: "Alice@Example.com"}
+		inv := pending("alice@example.com")
+
+		m.InvitationRepository.On("FindByTokenHash", mock.Anything, hashInvitationToken(token)).Return(inv, nil)
+		m.OrganizationRepository.On("AddMember", mock.Anything, member(orgID, actor.UID, model.OrgRoleAdmin)).Return(nil)
+		m.InvitationRepository.On("Update", mock.Anything, inv).Return(nil)
+
+		om, err := s.Accept(context.TODO(), actor, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.OrgRoleAdmin, om.Role)
+		assert.Equal(t, model.InvitationAccepted, inv.Status)
+		assert.Equal(t, actor.UID, *inv.AcceptedBy)
+	})
+
+	t.Run("Sent to another email", func(t *testing.T) {
+		s, m := newInvitationService()
+		actor := &model.User{UID: uuid.New(), Email: "mallory@example.com"}
+
+		m.InvitationRepository.On("FindByTokenHash", mock.Anything, hashInvitationToken(token)).Return(pending("alice@example.com"), nil)
+
+		_, err := s.Accept(context.TODO(), actor, token)
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+		m.OrganizationRepository.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		s, m := newInvitationService()
+		actor := &model.User{UID: uuid.New(), Email: "alice@example.com"}
+		inv := pending(actor.Email)
+		inv.Status = model.InvitationExpired
+
+		m.InvitationRepository.On("FindByTokenHash", mock.Anything, hashInvitationToken(token)).Return(inv, nil)
+
+		_, err := s.Accept(context.TODO(), actor, token)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Signup with verified email", func(t *testing.T) {
+		s, m := newInvitationService()
+		u := &model.User{Email: "alice@example.com", Password: "apassword"}
+		inv := pending(u.Email)
+
+		m.InvitationRepository.On("FindByTokenHash", mock.Anything, hashInvitationToken(token)).Return(inv, nil)
+		m.UserService.On("Signup", mock.Anything, u).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.User).UID = uuid.New()
+		}).Return(nil)
+		m.OrganizationRepository.On("AddMember", mock.Anything, mock.AnythingOfType("*model.OrgMember")).Return(nil)
+		m.InvitationRepository.On("Update", mock.Anything, inv).Return(nil)
+
+		err := s.Signup(context.TODO(), u, token)
+
+		assert.NoError(t, err)
+		assert.True(t, u.EmailVerified)
+		assert.Equal(t, model.OrgRoleAdmin, u.Orgs[orgID.String()])
+		assert.Equal(t, model.InvitationAccepted, inv.Status)
+	})
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	orgID := uuid.New()
+
+	t.Run("Inviter revokes", func(t *testing.T) {
+		s, m := newInvitationService()
+		actor := &model.User{UID: uuid.New()}
+		inv := &model.Invitation{ID: uuid.New(), OrgID: orgID, InvitedBy: &actor.UID, Status: model.InvitationPending}
+
+		m.InvitationRepository.On("FindByID", mock.Anything, inv.ID).Return(inv, nil)
+		m.InvitationRepository.On("Update", mock.Anything, inv).Return(nil)
+
+		err := s.Revoke(context.TODO(), actor, inv.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.InvitationRevoked, inv.Status)
+		assert.NotNil(t, inv.RevokedAt)
+	})
+
+	t.Run("Outsider cannot revoke", func(t *testing.T) {
+		s, m := newInvitationService()
+		actor := &model.User{UID: uuid.New()}
+		inviter := uuid.New()
+		inv := &model.Invitation{ID: uuid.New(), OrgID: orgID, InvitedBy: &inviter, Status: model.InvitationPending}
+
+		m.InvitationRepository.On("FindByID", mock.Anything, inv.ID).Return(inv, nil)
+		m.OrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(nil, apperrors.NewNotFound("member", actor.UID.String()))
+
+		err := s.Revoke(context.TODO(), actor, inv.ID)
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+		m.InvitationRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// moderationTimeout is how long the moderator is given for an image held for review
+const moderationTimeout = time.Minute
+
+// sweepBatchSize is the most stale pending reviews quarantined by a single sweep
+const sweepBatchSize = 100
+
+// moderate asks the moderator about an image. An image the moderator
+// fails to decide on is quarantined, so it is neither shown nor lost
+func (s *userService) moderate(ctx context.Context, uid uuid.UUID, data []byte) *model.ModerationResult {
+	if s.ImageModerator == nil {
+		return &model.ModerationResult{Decision: model.ModerationAllow}
+	}
+
+	result, err := s.ImageModerator.Moderate(ctx, uid, data)
+	if err != nil {
+		log.Printf("failed to moderate image for uid: %v: %v\n", uid, err)
+		return &model.ModerationResult{Decision: model.ModerationQuarantine, Reason: "moderation failed"}
+	}
+
+	return result
+}
+
+// holdImage keeps a stored image from the user until it is approved, with the
+// user keeping their current image in the meantime. Without a result the image
+// is pending, and the moderator decides on it in the background
+func (s *userService) holdImage(ctx context.Context, u *model.User, imageURLs model.ImageURLs, data []byte, result *model.ModerationResult) (*model.User, error) {
+	uid := u.UID
+
+	review := &model.ImageReview{
+		UID:       uid,
+		ImageURLs: imageURLs,
+		Status:    model.ImageReviewPending,
+	}
+
+	if result != nil {
+		review.Status = model.ImageReviewQuarantined
+		review.Reason = result.Reason
+	}
+
+	if err := s.ImageReviewRepository.Create(ctx, review); err != nil {
+		log.Printf("unable to hold image for review: %v\n", err)
+		recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "")
+		s.deleteImages(ctx, imageURLs)
+		return nil, err
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeSuccess, fmt.Sprintf("held for review: %s", review.ID))
+
+	if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, u); err != nil {
+		return nil, err
+	}
+
+	u.ImageReview = review
+
+	// the moderator gets its own copy, as the review is returned to the caller
+	if review.Status == model.ImageReviewPending {
+		pending := *review
+		go s.moderateReview(&pending, data)
+	}
+
+	return u, nil
+}
+
+// moderateReview runs the moderator on an image held for review, approving
+// or rejecting it. Quarantined images are left for an admin. Images still
+// pending should this fail, for example when the service stops, are
+// quarantined by SweepImageReviews
+func (s *userService) moderateReview(review *model.ImageReview, data []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), moderationTimeout)
+	defer cancel()
+
+	result := s.moderate(ctx, review.UID, data)
+
+	var err error
+
+	switch result.Decision {
+	case model.ModerationAllow:
+		_, err = s.decideReview(ctx, nil, review.ID, true, result.Reason)
+	case model.ModerationReject:
+		_, err = s.decideReview(ctx, nil, review.ID, false, result.Reason)
+	default:
+		review.Status = model.ImageReviewQuarantined
+		review.Reason = result.Reason
+		err = s.ImageReviewRepository.Update(ctx, review)
+	}
+
+	if err != nil {
+		log.Printf("failed to moderate image review: %v: %v\n", review.ID, err)
+	}
+}
+
+// SweepImageReviews quarantines images which have been pending for longer
+// than the moderator is given, so they are left for an admin rather than
+// hidden from them forever. Each review is locked and checked again, as the
+// moderator may decide on it in the meantime. Returns how many were quarantined
+func (s *userService) SweepImageReviews(ctx context.Context) (int, error) {
+	// the moderator is given twice its timeout to save its decision
+	reviews, err := s.ImageReviewRepository.FindStalePending(ctx, time.Now().Add(-2*moderationTimeout), sweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	quarantined := 0
+
+	for _, stale := range reviews {
+		swept := false
+
+		err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			review, err := s.ImageReviewRepository.FindByID(ctx, stale.ID)
+			if err != nil {
+				return err
+			}
+
+			if review.Status != model.ImageReviewPending {
+				return nil
+			}
+
+			review.Status = model.ImageReviewQuarantined
+			review.Reason = "moderation timed out"
+			swept = true
+
+			return s.ImageReviewRepository.Update(ctx, review)
+		})
+
+		if err != nil {
+			log.Printf("failed to quarantine stale image review: %v: %v\n", stale.ID, err)
+			continue
+		}
+
+		if swept {
+			quarantined++
+		}
+	}
+
+	return quarantined, nil
+}
+
+// ImageReviews lists up to limit images of users of the request's realm
+// with a review status, oldest first. Quarantined images are listed by default
+func (s *userService) ImageReviews(ctx context.Context, status string, limit int) ([]*model.ImageReview, error) {
+	if status == "" {
+		status = model.ImageReviewQuarantined
+	}
+
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	reviews, err := s.ImageReviewRepository.List(ctx, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// admins look at the images, so they must load from private storage too
+	for _, r := range reviews {
+		if r.ImageURLs, err = signImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, r.ImageURLs); err != nil {
+			return nil, err
+		}
+	}
+
+	return reviews, nil
+}
+
+// ReviewImage approves an image held for review, making it the user's profile
+// image, or rejects and deletes it. Only pending and quarantined images can be reviewed
+func (s *userService) ReviewImage(ctx context.Context, actor *model.User, id uuid.UUID, approve bool, reason string) (*model.ImageReview, error) {
+	return s.decideReview(ctx, &actor.UID, id, approve, reason)
+}
+
+// decideReview approves or rejects an image held for review. actor
+// is the admin deciding, and is nil when the moderator decides
+func (s *userService) decideReview(ctx context.Context, actor *uuid.UUID, id uuid.UUID, approve bool, reason string) (*model.ImageReview, error) {
+	var review *model.ImageReview
+	var expired []model.ImageURLs
+
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		// locked so only one decision is made
+		review, err = s.ImageReviewRepository.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !review.IsOpen() {
+			return apperrors.NewBadRequest(fmt.Sprintf("image has already been %s", review.Status))
+		}
+
+		// the user is looked up in the request's realm for admins
+		u, err := s.UserRepository.FindByID(ctx, review.UID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		review.Reason = reason
+		review.ReviewedBy = actor
+		review.ReviewedAt = &now
+		review.Status = model.ImageReviewRejected
+
+		if approve {
+			review.Status = model.ImageReviewApproved
+
+			var history model.ImageHistory
+			history, expired = s.archiveImage(u.ImageHistory, u.ImageURLs)
+
+			if _, err := s.replaceImage(ctx, u, review.ImageURLs, history); err != nil {
+				return err
+			}
+		}
+
+		return s.ImageReviewRepository.Update(ctx, review)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &review.UID, actor, model.AuditEventReviewImage, model.AuditOutcomeSuccess, review.Status)
+
+	if !approve {
+		expired = append(expired, review.ImageURLs)
+	}
+
+	for _, imageURLs := range expired {
+		s.deleteImages(ctx, imageURLs)
+	}
+
+	return review, nil
+}
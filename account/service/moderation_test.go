@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/fixture"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type moderationMocks struct {
+	users     *mocks.MockUserRepository
+	images    *mocks.MockImageRepository
+	moderator *mocks.MockImageModerator
+	reviews   *mocks.MockImageReviewRepository
+}
+
+func newModerationService(async bool) (model.UserService, *moderationMocks) {
+	m := &moderationMocks{
+		users:     new(mocks.MockUserRepository),
+		images:    new(mocks.MockImageRepository),
+		moderator: new(mocks.MockImageModerator),
+		reviews:   new(mocks.MockImageReviewRepository),
+	}
+
+	mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+	mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockOutboxRepository := new(mocks.MockOutboxRepository)
+	mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+	mockTransactor := new(mocks.MockTransactor)
+	mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+	us := NewUserService(&USConfig{
+		UserRepository:        m.users,
+		ImageRepository:       m.images,
+		AuditEventRepository:  mockAuditEventRepository,
+		OutboxRepository:      mockOutboxRepository,
+		Transactor:            mockTransactor,
+		ImageModerator:        m.moderator,
+		ImageReviewRepository: m.reviews,
+		ModerateAsync:         async,
+	})
+
+	return us, m
+}
+
+func TestModerateProfileImage(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	current := model.ImageURLs{64: "http://imageurl.com/current-64"}
+
+	// uploads every size of the image once
+	uploads := func(m *moderationMocks) {
+		for _, size := range model.ProfileImageSizes {
+			suffix := fmt.Sprintf("-%d", size)
+
+			m.images.
+				On("UpdateProfile", mock.Anything, mock.MatchedBy(func(objName string) bool {
+					return strings.HasSuffix(objName, suffix)
+				}), mock.Anything).
+				Return(fmt.Sprintf("http://imageurl.com/new-%d", size), nil).
+				Once()
+		}
+	}
+
+	newImage := model.ImageURLs{
+		64:  "http://imageurl.com/new-64",
+		128: "http://imageurl.com/new-128",
+		512: "http://imageurl.com/new-512",
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.moderator.On("Moderate", mock.Anything, uid, mock.Anything).
+			Return(&model.ModerationResult{Decision: model.ModerationAllow}, nil)
+		uploads(m)
+		m.users.On("UpdateImage", mock.Anything, uid, newImage).Return(&model.User{UID: uid, ImageURLs: newImage}, nil)
+		m.images.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
+
+		imageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer imageFixture.Close()
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFixture.GetFormFile())
+
+		assert.NoError(t, err)
+		assert.Equal(t, newImage, u.ImageURLs)
+		assert.Nil(t, u.ImageReview)
+		m.reviews.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.moderator.On("Moderate", mock.Anything, uid, mock.Anything).
+			Return(&model.ModerationResult{Decision: model.ModerationReject, Reason: "image is blocked"}, nil)
+
+		imageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer imageFixture.Close()
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFixture.GetFormFile())
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewBadRequest("imageFile was rejected: image is blocked"), err)
+		m.images.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+		m.users.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Quarantined", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.moderator.On("Moderate", mock.Anything, uid, mock.Anything).
+			Return(&model.ModerationResult{Decision: model.ModerationQuarantine, Reason: "too long"}, nil)
+		uploads(m)
+		m.reviews.On("Create", mock.Anything, mock.MatchedBy(func(r *model.ImageReview) bool {
+			return r.UID == uid &&
+				r.Status == model.ImageReviewQuarantined &&
+				r.Reason == "too long" &&
+				assert.ObjectsAreEqual(newImage, r.ImageURLs)
+		})).Return(nil)
+
+		imageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer imageFixture.Close()
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFixture.GetFormFile())
+
+		// the user keeps their current image
+		assert.NoError(t, err)
+		assert.Equal(t, current, u.ImageURLs)
+		assert.Equal(t, model.ImageReviewQuarantined, u.ImageReview.Status)
+		m.reviews.AssertExpectations(t)
+		m.users.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Moderator error quarantines", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.moderator.On("Moderate", mock.Anything, uid, mock.Anything).
+			Return(nil, fmt.Errorf("moderator unavailable"))
+		uploads(m)
+		m.reviews.On("Create", mock.Anything, mock.MatchedBy(func(r *model.ImageReview) bool {
+			return r.Status == model.ImageReviewQuarantined && r.Reason == "moderation failed"
+		})).Return(nil)
+
+		imageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer imageFixture.Close()
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFixture.GetFormFile())
+
+		assert.NoError(t, err)
+		assert.NotNil(t, u.ImageReview)
+		m.reviews.AssertExpectations(t)
+	})
+
+	t.Run("Review error deletes upload", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.moderator.On("Moderate", mock.Anything, uid, mock.Anything).
+			Return(&model.ModerationResult{Decision: model.ModerationQuarantine}, nil)
+		uploads(m)
+		m.reviews.On("Create", mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+		m.images.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
+
+		imageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer imageFixture.Close()
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFixture.GetFormFile())
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewInternal(), err)
+		m.images.AssertNumberOfCalls(t, "DeleteProfile", len(model.ProfileImageSizes))
+	})
+
+	t.Run("Async approves in the background", func(t *testing.T) {
+		us, m := newModerationService(true)
+
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		uploads(m)
+
+		var review *model.ImageReview
+		m.reviews.On("Create", mock.Anything, mock.MatchedBy(func(r *model.ImageReview) bool {
+			return r.Status == model.ImageReviewPending
+		})).Run(func(args mock.Arguments) {
+			review = args.Get(1).(*model.ImageReview)
+			review.ID = uuid.New()
+		}).Return(nil)
+
+		m.moderator.On("Moderate", mock.Anything, uid, mock.Anything).
+			Return(&model.ModerationResult{Decision: model.ModerationAllow}, nil)
+		m.reviews.On("FindByID", mock.Anything, mock.Anything).
+			Return(&model.ImageReview{UID: uid, ImageURLs: newImage, Status: model.ImageReviewPending}, nil)
+		m.users.On("UpdateImage", mock.Anything, uid, newImage).Return(&model.User{UID: uid, ImageURLs: newImage}, nil)
+		m.images.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
+
+		approved := make(chan *model.ImageReview, 1)
+		m.reviews.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			approved <- args.Get(1).(*model.ImageReview)
+		}).Return(nil)
+
+		imageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer imageFixture.Close()
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFixture.GetFormFile())
+
+		assert.NoError(t, err)
+		assert.Equal(t, current, u.ImageURLs)
+		assert.Equal(t, model.ImageReviewPending, u.ImageReview.Status)
+
+		select {
+		case r := <-approved:
+			assert.Equal(t, model.ImageReviewApproved, r.Status)
+			assert.Nil(t, r.ReviewedBy)
+		case <-time.After(time.Second):
+			t.Fatal("image was not moderated")
+		}
+
+		m.users.AssertCalled(t, "UpdateImage", mock.Anything, uid, newImage)
+	})
+}
+
+func TestReviewImage(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	admin := &model.User{UID: uuid.New()}
+	id := uuid.New()
+
+	current := model.ImageURLs{64: "http://imageurl.com/current-64"}
+	held := model.ImageURLs{64: "http://imageurl.com/held-64"}
+
+	t.Run("Approve", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.reviews.On("FindByID", mock.Anything, id).
+			Return(&model.ImageReview{ID: id, UID: uid, ImageURLs: held, Status: model.ImageReviewQuarantined}, nil)
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.users.On("UpdateImage", mock.Anything, uid, held).Return(&model.User{UID: uid, ImageURLs: held}, nil)
+		m.reviews.On("Update", mock.Anything, mock.Anything).Return(nil)
+		m.images.On("DeleteProfile", mock.Anything, "current-64").Return(nil)
+
+		review, err := us.ReviewImage(context.TODO(), admin, id, true, "looks fine")
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageReviewApproved, review.Status)
+		assert.Equal(t, "looks fine", review.Reason)
+		assert.Equal(t, &admin.UID, review.ReviewedBy)
+		assert.NotNil(t, review.ReviewedAt)
+		m.images.AssertExpectations(t)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.reviews.On("FindByID", mock.Anything, id).
+			Return(&model.ImageReview{ID: id, UID: uid, ImageURLs: held, Status: model.ImageReviewQuarantined}, nil)
+		m.users.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURLs: current}, nil)
+		m.reviews.On("Update", mock.Anything, mock.Anything).Return(nil)
+		m.images.On("DeleteProfile", mock.Anything, "held-64").Return(nil)
+
+		review, err := us.ReviewImage(context.TODO(), admin, id, false, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageReviewRejected, review.Status)
+		m.images.AssertExpectations(t)
+		m.users.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already reviewed", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.reviews.On("FindByID", mock.Anything, id).
+			Return(&model.ImageReview{ID: id, UID: uid, ImageURLs: held, Status: model.ImageReviewRejected}, nil)
+
+		review, err := us.ReviewImage(context.TODO(), admin, id, true, "")
+
+		assert.Nil(t, review)
+		assert.Equal(t, apperrors.NewBadRequest("image has already been rejected"), err)
+		m.reviews.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("User of another realm", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.reviews.On("FindByID", mock.Anything, id).
+			Return(&model.ImageReview{ID: id, UID: uid, ImageURLs: held, Status: model.ImageReviewQuarantined}, nil)
+		m.users.On("FindByID", mock.Anything, uid).Return(nil, apperrors.NewNotFound("uid", uid.String()))
+
+		review, err := us.ReviewImage(context.TODO(), admin, id, true, "")
+
+		assert.Nil(t, review)
+		assert.Equal(t, apperrors.NewNotFound("uid", uid.String()), err)
+		m.reviews.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImageReviews(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		reviews := []*model.ImageReview{{ID: uuid.New(), ImageURLs: model.ImageURLs{64: "http://imageurl.com/held-64"}}}
+		m.reviews.On("List", mock.Anything, model.ImageReviewQuarantined, defaultSearchLimit).Return(reviews, nil)
+
+		got, err := us.ImageReviews(context.TODO(), "", 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, reviews, got)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		us, m := newModerationService(false)
+
+		m.reviews.On("List", mock.Anything, model.ImageReviewPending, 5).Return(nil, apperrors.NewInternal())
+
+		got, err := us.ImageReviews(context.TODO(), model.ImageReviewPending, 5)
+
+		assert.Nil(t, got)
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
+
+func TestSweepImageReviews(t *testing.T) {
+	staleBefore := mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= 2*moderationTimeout && time.Since(before) < 2*moderationTimeout+time.Minute
+	})
+
+	t.Run("Quarantines stale pending reviews", func(t *testing.T) {
+		us, m := newModerationService(true)
+
+		stale := &model.ImageReview{ID: uuid.New(), Status: model.ImageReviewPending}
+		decided := &model.ImageReview{ID: uuid.New(), Status: model.ImageReviewPending}
+
+		m.reviews.On("FindStalePending", mock.Anything, staleBefore, sweepBatchSize).Return([]*model.ImageReview{stale, decided}, nil)
+		m.reviews.On("FindByID", mock.Anything, stale.ID).Return(&model.ImageReview{ID: stale.ID, Status: model.ImageReviewPending}, nil)
+		// the moderator decided after the reviews were listed
+		m.reviews.On("FindByID", mock.Anything, decided.ID).Return(&model.ImageReview{ID: decided.ID, Status: model.ImageReviewApproved}, nil)
+		m.reviews.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		n, err := us.SweepImageReviews(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		m.reviews.AssertNumberOfCalls(t, "Update", 1)
+		m.reviews.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(r *model.ImageReview) bool {
+			return r.ID == stale.ID && r.Status == model.ImageReviewQuarantined && r.Reason == "moderation timed out"
+		}))
+	})
+
+	t.Run("Failed update is not counted", func(t *testing.T) {
+		us, m := newModerationService(true)
+
+		stale := &model.ImageReview{ID: uuid.New(), Status: model.ImageReviewPending}
+
+		m.reviews.On("FindStalePending", mock.Anything, staleBefore, sweepBatchSize).Return([]*model.ImageReview{stale}, nil)
+		m.reviews.On("FindByID", mock.Anything, stale.ID).Return(&model.ImageReview{ID: stale.ID, Status: model.ImageReviewPending}, nil)
+		m.reviews.On("Update", mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+
+		n, err := us.SweepImageReviews(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		us, m := newModerationService(true)
+
+		m.reviews.On("FindStalePending", mock.Anything, staleBefore, sweepBatchSize).Return(nil, apperrors.NewInternal())
+
+		n, err := us.SweepImageReviews(context.TODO())
+
+		assert.Equal(t, 0, n)
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
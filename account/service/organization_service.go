@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// organizationService acts as a struct for injecting the repositories
+// organizations and their members are stored in
+type organizationService struct {
+	OrganizationRepository model.OrganizationRepository
+	UserRepository         model.UserRepository
+	Transactor             model.Transactor
+}
+
+// OSConfig will hold repositories that will eventually be injected
+// into this service layer
+type OSConfig struct {
+	OrganizationRepository model.OrganizationRepository
+	UserRepository         model.UserRepository
+	Transactor             model.Transactor
+}
+
+// NewOrganizationService is a factory function for initializing
+// an OrganizationService with its repository layer dependencies
+func NewOrganizationService(c *OSConfig) model.OrganizationService {
+	return &organizationService{
+		OrganizationRepository: c.OrganizationRepository,
+		UserRepository:         c.UserRepository,
+		Transactor:             c.Transactor,
+	}
+}
+
+// CreateOrganization creates an organization owned by the actor
+func (s *organizationService) CreateOrganization(ctx context.Context, actor *model.User, name string) (*model.Organization, error) {
+	o := &model.Organization{
+		Name: strings.TrimSpace(name),
+	}
+
+	if o.Name == "" {
+		return nil, apperrors.NewBadRequest("name must not be empty")
+	}
+
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.OrganizationRepository.Create(ctx, o); err != nil {
+			return err
+		}
+
+		return s.OrganizationRepository.AddMember(ctx, &model.OrgMember{
+			OrgID: o.ID,
+			UID:   actor.UID,
+			Role:  model.OrgRoleOwner,
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	o.Role = model.OrgRoleOwner
+
+	return o, nil
+}
+
+// ListOrganizations returns the organizations the actor belongs to
+func (s *organizationService) ListOrganizations(ctx context.Context, actor *model.User) ([]*model.Organization, error) {
+	return s.OrganizationRepository.ListByUser(ctx, actor.UID)
+}
+
+// GetOrganization returns an organization the actor belongs to
+func (s *organizationService) GetOrganization(ctx context.Context, actor *model.User, id uuid.UUID) (*model.Organization, error) {
+	m, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, id, model.OrgRoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.OrganizationRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	o.Role = m.Role
+
+	return o, nil
+}
+
+// UpdateOrganization renames an organization. The actor must be an admin or owner
+func (s *organizationService) UpdateOrganization(ctx context.Context, actor *model.User, id uuid.UUID, name string) (*model.Organization, error) {
+	m, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, id, model.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &model.Organization{
+		ID:   id,
+		Name: strings.TrimSpace(name),
+	}
+
+	if o.Name == "" {
+		return nil, apperrors.NewBadRequest("name must not be empty")
+	}
+
+	if err := s.OrganizationRepository.Update(ctx, o); err != nil {
+		return nil, err
+	}
+
+	o.Role = m.Role
+
+	return o, nil
+}
+
+// DeleteOrganization deletes an organization along with its
+// memberships. The actor must be an owner
+func (s *organizationService) DeleteOrganization(ctx context.Context, actor *model.User, id uuid.UUID) error {
+	if _, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, id, model.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	return s.OrganizationRepository.Delete(ctx, id)
+}
+
+// ListMembers returns the members of an organization the actor belongs to
+func (s *organizationService) ListMembers(ctx context.Context, actor *model.User, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	if _, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, orgID, model.OrgRoleMember); err != nil {
+		return nil, err
+	}
+
+	return s.OrganizationRepository.ListMembers(ctx, orgID)
+}
+
+// AddMember adds the user with the given email to an organization. The actor
+// must be an admin or owner, and only owners may add other owners
+func (s *organizationService) AddMember(ctx context.Context, actor *model.User, orgID uuid.UUID, email string, role string) (*model.OrgMember, error) {
+	if !model.ValidOrgRole(role) {
+		return nil, apperrors.NewBadRequest("role must be one of owner, admin or member")
+	}
+
+	am, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, orgID, model.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if !model.OrgRoleAtLeast(am.Role, role) {
+		return nil, apperrors.NewForbidden("only owners can add owners")
+	}
+
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model.OrgMember{
+		OrgID: orgID,
+		UID:   u.UID,
+		Role:  role,
+		Name:  u.Name,
+		Email: u.Email,
+	}
+
+	if err := s.OrganizationRepository.AddMember(ctx, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// UpdateMember changes a member's role. The actor must be an admin or owner,
+// and only owners may change the role of an owner or make someone an owner.
+// The last owner of an organization cannot be demoted
+func (s *organizationService) UpdateMember(ctx context.Context, actor *model.User, orgID uuid.UUID, uid uuid.UUID, role string) (*model.OrgMember, error) {
+	if !model.ValidOrgRole(role) {
+		return nil, apperrors.NewBadRequest("role must be one of owner, admin or member")
+	}
+
+	var m *model.OrgMember
+
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		am, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, orgID, model.OrgRoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		m, err = s.OrganizationRepository.FindMember(ctx, orgID, uid)
+		if err != nil {
+			return err
+		}
+
+		if !model.OrgRoleAtLeast(am.Role, m.Role) || !model.OrgRoleAtLeast(am.Role, role) {
+			return apperrors.NewForbidden("only owners can change the role of owners")
+		}
+
+		if m.Role == model.OrgRoleOwner && role != model.OrgRoleOwner {
+			if err := s.keepOwner(ctx, orgID); err != nil {
+				return err
+			}
+		}
+
+		m.Role = role
+
+		return s.OrganizationRepository.UpdateMember(ctx, m)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// RemoveMember removes a member from an organization. Any member may leave,
+// otherwise the actor must be an admin or owner and only owners may remove
+// owners. The last owner of an organization cannot be removed
+func (s *organizationService) RemoveMember(ctx context.Context, actor *model.User, orgID uuid.UUID, uid uuid.UUID) error {
+	return s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		minRole := model.OrgRoleAdmin
+		if uid == actor.UID {
+			minRole = model.OrgRoleMember
+		}
+
+		am, err := authorizeOrgMember(ctx, s.OrganizationRepository, actor, orgID, minRole)
+		if err != nil {
+			return err
+		}
+
+		m, err := s.OrganizationRepository.FindMember(ctx, orgID, uid)
+		if err != nil {
+			return err
+		}
+
+		if !model.OrgRoleAtLeast(am.Role, m.Role) {
+			return apperrors.NewForbidden("only owners can remove owners")
+		}
+
+		if m.Role == model.OrgRoleOwner {
+			if err := s.keepOwner(ctx, orgID); err != nil {
+				return err
+			}
+		}
+
+		return s.OrganizationRepository.RemoveMember(ctx, orgID, uid)
+	})
+}
+
+// authorizeOrgMember checks the actor belongs to the organization with at least
+// the given role. Organizations the actor does not belong to are reported as
+// not found, so their existence is not revealed
+func authorizeOrgMember(ctx context.Context, r model.OrganizationRepository, actor *model.User, orgID uuid.UUID, minRole string) (*model.OrgMember, error) {
+	m, err := r.FindMember(ctx, orgID, actor.UID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("organization", orgID.String())
+	}
+
+	if !model.OrgRoleAtLeast(m.Role, minRole) {
+		return nil, apperrors.NewForbidden("requires the " + minRole + " role in the organization")
+	}
+
+	return m, nil
+}
+
+// keepOwner checks an owner can be removed or demoted
+// without leaving the organization without an owner
+func (s *organizationService) keepOwner(ctx context.Context, orgID uuid.UUID) error {
+	owners, err := s.OrganizationRepository.CountOwners(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	if owners <= 1 {
+		return apperrors.NewBadRequest("an organization must have at least one owner")
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newOrganizationService() (model.OrganizationService, *mocks.MockOrganizationRepository, *mocks.MockUserRepository) {
+	mockOrganizationRepository := new(mocks.MockOrganizationRepository)
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockTransactor := new(mocks.MockTransactor)
+	mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+	s := NewOrganizationService(&OSConfig{
+		OrganizationRepository: mockOrganizationRepository,
+		UserRepository:         mockUserRepository,
+		Transactor:             mockTransactor,
+	})
+
+	return s, mockOrganizationRepository, mockUserRepository
+}
+
+func member(orgID uuid.UUID, uid uuid.UUID, role string) *model.OrgMember {
+	return &model.OrgMember{
+		OrgID: orgID,
+		UID:   uid,
+		Role:  role,
+	}
+}
+
+func TestCreateOrganization(t *testing.T) {
+	t.Run("Creator becomes owner", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		actor := &model.User{UID: uuid.New()}
+		orgID := uuid.New()
+
+		mockOrganizationRepository.On("Create", mock.Anything, mock.MatchedBy(func(o *model.Organization) bool {
+			return o.Name == "Acme"
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Organization).ID = orgID
+		}).Return(nil)
+		mockOrganizationRepository.On("AddMember", mock.Anything, member(orgID, actor.UID, model.OrgRoleOwner)).Return(nil)
+
+		o, err := s.CreateOrganization(context.TODO(), actor, "  Acme ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, orgID, o.ID)
+		assert.Equal(t, model.OrgRoleOwner, o.Role)
+		mockOrganizationRepository.AssertExpectations(t)
+	})
+
+	t.Run("Empty name", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+
+		_, err := s.CreateOrganization(context.TODO(), &model.User{UID: uuid.New()}, "   ")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockOrganizationRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetOrganization(t *testing.T) {
+	t.Run("Non member", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		actor := &model.User{UID: uuid.New()}
+		orgID := uuid.New()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(nil, apperrors.NewNotFound("member", actor.UID.String()))
+
+		_, err := s.GetOrganization(context.TODO(), actor, orgID)
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+		mockOrganizationRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAddMember(t *testing.T) {
+	orgID := uuid.New()
+	actor := &model.User{UID: uuid.New()}
+	invitee := &model.User{UID: uuid.New(), Email: "alice@example.com", Name: "Alice"}
+
+	t.Run("Admin adds member", func(t *testing.T) {
+		s, mockOrganizationRepository, mockUserRepository := newOrganizationService()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleAdmin), nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, invitee.Email).Return(invitee, nil)
+		mockOrganizationRepository.On("AddMember", mock.Anything, mock.MatchedBy(func(m *model.OrgMember) bool {
+			return m.OrgID == orgID && m.UID == invitee.UID && m.Role == model.OrgRoleMember
+		})).Return(nil)
+
+		m, err := s.AddMember(context.TODO(), actor, orgID, invitee.Email, model.OrgRoleMember)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Alice", m.Name)
+		mockOrganizationRepository.AssertExpectations(t)
+	})
+
+	t.Run("Admin cannot add owner", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleAdmin), nil)
+
+		_, err := s.AddMember(context.TODO(), actor, orgID, invitee.Email, model.OrgRoleOwner)
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+		mockOrganizationRepository.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Member cannot add members", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleMember), nil)
+
+		_, err := s.AddMember(context.TODO(), actor, orgID, invitee.Email, model.OrgRoleMember)
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+	})
+}
+
+func TestUpdateMember(t *testing.T) {
+	orgID := uuid.New()
+	owner := &model.User{UID: uuid.New()}
+
+	t.Run("Last owner cannot be demoted", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, owner.UID).Return(member(orgID, owner.UID, model.OrgRoleOwner), nil)
+		mockOrganizationRepository.On("CountOwners", mock.Anything, orgID).Return(1, nil)
+
+		_, err := s.UpdateMember(context.TODO(), owner, orgID, owner.UID, model.OrgRoleAdmin)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockOrganizationRepository.AssertNotCalled(t, "UpdateMember", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Owner promotes admin", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		uid := uuid.New()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, owner.UID).Return(member(orgID, owner.UID, model.OrgRoleOwner), nil)
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, uid).Return(member(orgID, uid, model.OrgRoleAdmin), nil)
+		mockOrganizationRepository.On("UpdateMember", mock.Anything, member(orgID, uid, model.OrgRoleOwner)).Return(nil)
+
+		m, err := s.UpdateMember(context.TODO(), owner, orgID, uid, model.OrgRoleOwner)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.OrgRoleOwner, m.Role)
+		mockOrganizationRepository.AssertNotCalled(t, "CountOwners", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Admin cannot demote owner", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		admin := &model.User{UID: uuid.New()}
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, admin.UID).Return(member(orgID, admin.UID, model.OrgRoleAdmin), nil)
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, owner.UID).Return(member(orgID, owner.UID, model.OrgRoleOwner), nil)
+
+		_, err := s.UpdateMember(context.TODO(), admin, orgID, owner.UID, model.OrgRoleMember)
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+	})
+}
+
+func TestRemoveMember(t *testing.T) {
+	orgID := uuid.New()
+
+	t.Run("Member leaves", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		actor := &model.User{UID: uuid.New()}
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleMember), nil)
+		mockOrganizationRepository.On("RemoveMember", mock.Anything, orgID, actor.UID).Return(nil)
+
+		err := s.RemoveMember(context.TODO(), actor, orgID, actor.UID)
+
+		assert.NoError(t, err)
+		mockOrganizationRepository.AssertExpectations(t)
+	})
+
+	t.Run("Member cannot remove others", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		actor := &model.User{UID: uuid.New()}
+		uid := uuid.New()
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleMember), nil)
+
+		err := s.RemoveMember(context.TODO(), actor, orgID, uid)
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+		mockOrganizationRepository.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Owner leaves with another owner", func(t *testing.T) {
+		s, mockOrganizationRepository, _ := newOrganizationService()
+		actor := &model.User{UID: uuid.New()}
+
+		mockOrganizationRepository.On("FindMember", mock.Anything, orgID, actor.UID).Return(member(orgID, actor.UID, model.OrgRoleOwner), nil)
+		mockOrganizationRepository.On("CountOwners", mock.Anything, orgID).Return(2, nil)
+		mockOrganizationRepository.On("RemoveMember", mock.Anything, orgID, actor.UID).Return(nil)
+
+		err := s.RemoveMember(context.TODO(), actor, orgID, actor.UID)
+
+		assert.NoError(t, err)
+	})
+}
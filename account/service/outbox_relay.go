@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ndenisj/go_mem/account/model"
+)
+
+const (
+	// outboxBatchSize is the most messages published by a single relay
+	outboxBatchSize = 100
+	// outboxRetention is how long published messages are kept for inspection
+	outboxRetention = 24 * time.Hour
+	// maxRetryBackoff caps the exponential delay between attempts to publish a message
+	maxRetryBackoff = time.Hour
+)
+
+// outboxRelay publishes the events written to the outbox to the events broker
+type outboxRelay struct {
+	OutboxRepository model.OutboxRepository
+	EventsBroker     model.EventsBroker
+	Transactor       model.Transactor
+	MaxAttempts      int
+	RetryBackoff     time.Duration
+	ImageRepository  model.ImageRepository
+	ImageURLTTL      time.Duration
+	ImagePublicURL   string
+	BaseURL          string
+}
+
+// ORConfig will hold repositories that will eventually be injected
+// into the outbox relay
+type ORConfig struct {
+	OutboxRepository model.OutboxRepository
+	EventsBroker     model.EventsBroker
+	Transactor       model.Transactor
+	MaxAttempts      int
+	RetryBackoff     time.Duration
+	// ImageRepository, ImageURLTTL, ImagePublicURL and BaseURL resolve the
+	// images of users in events, as in the user service's USConfig
+	ImageRepository model.ImageRepository
+	ImageURLTTL     time.Duration
+	ImagePublicURL  string
+	BaseURL         string
+}
+
+// NewOutboxRelay is a factory function for initializing
+// an OutboxRelay with its repository layer dependencies
+func NewOutboxRelay(c *ORConfig) model.OutboxRelay {
+	return &outboxRelay{
+		OutboxRepository: c.OutboxRepository,
+		EventsBroker:     c.EventsBroker,
+		Transactor:       c.Transactor,
+		MaxAttempts:      c.MaxAttempts,
+		RetryBackoff:     c.RetryBackoff,
+		ImageRepository:  c.ImageRepository,
+		ImageURLTTL:      c.ImageURLTTL,
+		ImagePublicURL:   strings.TrimSuffix(c.ImagePublicURL, "/"),
+		BaseURL:          c.BaseURL,
+	}
+}
+
+// Relay publishes a batch of pending messages, returning how many were published.
+// Messages are marked as published after the broker accepts them, so a message
+// is published again if the relay fails in between. Consumers must tolerate
+// duplicates by ignoring event IDs they have already seen
+func (s *outboxRelay) Relay(ctx context.Context) (int, error) {
+	published := 0
+
+	// the batch stays locked until it is committed, so other relays skip it
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		published = 0
+
+		msgs, err := s.OutboxRepository.FetchPending(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range msgs {
+			e, err := m.Event()
+			if err != nil {
+				log.Printf("dead lettering undecodable outbox message: %v: %v\n", m.ID, err)
+				if err := s.OutboxRepository.DeadLetter(ctx, m.ID, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// events are written with the user's image as stored, and are given
+			// the URLs clients load it from as they leave, so signed ones are fresh
+			if e.User != nil {
+				if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, e.User); err != nil {
+					if err := s.fail(ctx, m, err); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			if err := s.EventsBroker.Publish(ctx, e); err != nil {
+				if err := s.fail(ctx, m, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.OutboxRepository.MarkPublished(ctx, m.ID); err != nil {
+				return err
+			}
+
+			published++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.OutboxRepository.DeletePublished(ctx, time.Now().Add(-outboxRetention)); err != nil {
+		log.Printf("failed to delete published outbox messages: %v\n", err)
+	}
+
+	return published, nil
+}
+
+// fail schedules another attempt to publish a message with exponential
+// backoff, or dead letters it once it has used up its attempts
+func (s *outboxRelay) fail(ctx context.Context, m *model.OutboxMessage, reason error) error {
+	attempts := m.Attempts + 1
+
+	if attempts >= s.MaxAttempts {
+		log.Printf("dead lettering outbox message: %v after %d attempts: %v\n", m.ID, attempts, reason)
+		return s.OutboxRepository.DeadLetter(ctx, m.ID, reason.Error())
+	}
+
+	return s.OutboxRepository.MarkFailed(ctx, m.ID, reason.Error(), time.Now().Add(retryBackoff(s.RetryBackoff, attempts)))
+}
+
+// retryBackoff doubles the base delay for every attempt already made
+func retryBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
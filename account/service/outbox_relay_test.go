@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func outboxMessage(id int64, attempts int) (*model.OutboxMessage, *model.UserEvent) {
+	e := model.NewUserEvent(model.EventUserUpdated, &model.User{UID: uuid.New()})
+	payload, _ := json.Marshal(e)
+
+	return &model.OutboxMessage{
+		ID:        id,
+		EventID:   e.ID,
+		UID:       e.UID,
+		EventType: e.Type,
+		Payload:   payload,
+		Attempts:  attempts,
+	}, e
+}
+
+func TestRelay(t *testing.T) {
+	eventID := func(id uuid.UUID) interface{} {
+		return mock.MatchedBy(func(e *model.UserEvent) bool {
+			return e.ID == id
+		})
+	}
+
+	t.Run("Publishes and retries", func(t *testing.T) {
+		published, publishedEvent := outboxMessage(1, 0)
+		failed, failedEvent := outboxMessage(2, 2)
+
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockEventsBroker := new(mocks.MockEventsBroker)
+		mockTransactor := new(mocks.MockTransactor)
+		relay := NewOutboxRelay(&ORConfig{
+			OutboxRepository: mockOutboxRepository,
+			EventsBroker:     mockEventsBroker,
+			Transactor:       mockTransactor,
+			MaxAttempts:      5,
+			RetryBackoff:     10 * time.Second,
+		})
+
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockOutboxRepository.On("FetchPending", mock.Anything, outboxBatchSize).Return([]*model.OutboxMessage{published, failed}, nil)
+		mockEventsBroker.On("Publish", mock.Anything, eventID(publishedEvent.ID)).Return(nil)
+		mockEventsBroker.On("Publish", mock.Anything, eventID(failedEvent.ID)).Return(apperrors.NewInternal())
+		mockOutboxRepository.On("MarkPublished", mock.Anything, int64(1)).Return(nil)
+		// third attempt waits four times the base backoff
+		mockOutboxRepository.On("MarkFailed", mock.Anything, int64(2), mock.AnythingOfType("string"), mock.MatchedBy(func(next time.Time) bool {
+			return time.Until(next) > 39*time.Second && time.Until(next) <= 40*time.Second
+		})).Return(nil)
+		mockOutboxRepository.On("DeletePublished", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+		n, err := relay.Relay(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		mockOutboxRepository.AssertExpectations(t)
+		mockEventsBroker.AssertExpectations(t)
+		mockOutboxRepository.AssertNotCalled(t, "DeadLetter", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Dead letters after max attempts", func(t *testing.T) {
+		msg, e := outboxMessage(3, 4)
+		undecodable := &model.OutboxMessage{ID: 4, Payload: []byte("not json")}
+
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockEventsBroker := new(mocks.MockEventsBroker)
+		mockTransactor := new(mocks.MockTransactor)
+		relay := NewOutboxRelay(&ORConfig{
+			OutboxRepository: mockOutboxRepository,
+			EventsBroker:     mockEventsBroker,
+			Transactor:       mockTransactor,
+			MaxAttempts:      5,
+			RetryBackoff:     10 * time.Second,
+		})
+
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockOutboxRepository.On("FetchPending", mock.Anything, outboxBatchSize).Return([]*model.OutboxMessage{msg, undecodable}, nil)
+		mockEventsBroker.On("Publish", mock.Anything, eventID(e.ID)).Return(apperrors.NewInternal())
+		mockOutboxRepository.On("DeadLetter", mock.Anything, int64(3), mock.AnythingOfType("string")).Return(nil)
+		mockOutboxRepository.On("DeadLetter", mock.Anything, int64(4), mock.AnythingOfType("string")).Return(nil)
+		mockOutboxRepository.On("DeletePublished", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+		n, err := relay.Relay(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		mockOutboxRepository.AssertExpectations(t)
+		mockEventsBroker.AssertNumberOfCalls(t, "Publish", 1)
+	})
+
+	t.Run("Failure to mark published rolls back", func(t *testing.T) {
+		msg, e := outboxMessage(5, 0)
+
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockEventsBroker := new(mocks.MockEventsBroker)
+		mockTransactor := new(mocks.MockTransactor)
+		relay := NewOutboxRelay(&ORConfig{
+			OutboxRepository: mockOutboxRepository,
+			EventsBroker:     mockEventsBroker,
+			Transactor:       mockTransactor,
+			MaxAttempts:      5,
+		})
+
+		mockErr := apperrors.NewInternal()
+
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockOutboxRepository.On("FetchPending", mock.Anything, outboxBatchSize).Return([]*model.OutboxMessage{msg}, nil)
+		mockEventsBroker.On("Publish", mock.Anything, eventID(e.ID)).Return(nil)
+		mockOutboxRepository.On("MarkPublished", mock.Anything, int64(5)).Return(mockErr)
+
+		n, err := relay.Relay(context.TODO())
+
+		assert.Equal(t, mockErr, err)
+		assert.Equal(t, 0, n)
+		mockOutboxRepository.AssertNotCalled(t, "DeletePublished", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Publishes images under the public URL", func(t *testing.T) {
+		e := model.NewUserEvent(model.EventImageChanged, &model.User{
+			UID: uuid.New(),
+			ImageURLs: model.ImageURLs{
+				64: "https://storage.googleapis.com/bucket/image-64",
+			},
+		})
+		payload, _ := json.Marshal(e)
+		msg := &model.OutboxMessage{ID: 6, EventID: e.ID, UID: e.UID, EventType: e.Type, Payload: payload}
+
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockEventsBroker := new(mocks.MockEventsBroker)
+		mockTransactor := new(mocks.MockTransactor)
+		relay := NewOutboxRelay(&ORConfig{
+			OutboxRepository: mockOutboxRepository,
+			EventsBroker:     mockEventsBroker,
+			Transactor:       mockTransactor,
+			MaxAttempts:      5,
+			ImagePublicURL:   "https://account.example.com/images",
+		})
+
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockOutboxRepository.On("FetchPending", mock.Anything, outboxBatchSize).Return([]*model.OutboxMessage{msg}, nil)
+		mockEventsBroker.On("Publish", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository.On("MarkPublished", mock.Anything, int64(6)).Return(nil)
+		mockOutboxRepository.On("DeletePublished", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+		n, err := relay.Relay(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		// consumers are never sent the bucket the image is kept in
+		mockEventsBroker.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(published *model.UserEvent) bool {
+			return published.ID == e.ID && published.User.ImageURLs[64] == "https://account.example.com/images/image-64"
+		}))
+	})
+
+	t.Run("Publishes private images signed and default avatars", func(t *testing.T) {
+		withImage := model.NewUserEvent(model.EventImageChanged, &model.User{
+			UID:       uuid.New(),
+			ImageURLs: model.ImageURLs{64: "image-64"},
+		})
+		withoutImage := model.NewUserEvent(model.EventUserCreated, &model.User{UID: uuid.New()})
+
+		msgs := []*model.OutboxMessage{}
+		for i, e := range []*model.UserEvent{withImage, withoutImage} {
+			payload, _ := json.Marshal(e)
+			msgs = append(msgs, &model.OutboxMessage{ID: int64(7 + i), EventID: e.ID, UID: e.UID, EventType: e.Type, Payload: payload})
+		}
+
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockEventsBroker := new(mocks.MockEventsBroker)
+		mockTransactor := new(mocks.MockTransactor)
+		mockImageRepository := new(mocks.MockImageRepository)
+		relay := NewOutboxRelay(&ORConfig{
+			OutboxRepository: mockOutboxRepository,
+			EventsBroker:     mockEventsBroker,
+			Transactor:       mockTransactor,
+			MaxAttempts:      5,
+			ImageRepository:  mockImageRepository,
+			ImageURLTTL:      10 * time.Minute,
+			BaseURL:          "https://account.example.com",
+		})
+
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockOutboxRepository.On("FetchPending", mock.Anything, outboxBatchSize).Return(msgs, nil)
+		mockImageRepository.On("SignURL", mock.Anything, "image-64", 10*time.Minute).Return("https://storage.example.com/image-64?sig=abc", nil)
+		mockEventsBroker.On("Publish", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository.On("MarkPublished", mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+		mockOutboxRepository.On("DeletePublished", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+		n, err := relay.Relay(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		mockEventsBroker.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(published *model.UserEvent) bool {
+			return published.ID == withImage.ID && published.User.ImageURLs[64] == "https://storage.example.com/image-64?sig=abc"
+		}))
+		mockEventsBroker.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(published *model.UserEvent) bool {
+			return published.ID == withoutImage.ID && published.User.ImageURLs[64] == "https://account.example.com/avatars/"+withoutImage.UID.String()+"?size=64"
+		}))
+	})
+
+	t.Run("Retries when images can't be signed", func(t *testing.T) {
+		e := model.NewUserEvent(model.EventImageChanged, &model.User{
+			UID:       uuid.New(),
+			ImageURLs: model.ImageURLs{64: "image-64"},
+		})
+		payload, _ := json.Marshal(e)
+		msg := &model.OutboxMessage{ID: 9, EventID: e.ID, UID: e.UID, EventType: e.Type, Payload: payload}
+
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockEventsBroker := new(mocks.MockEventsBroker)
+		mockTransactor := new(mocks.MockTransactor)
+		mockImageRepository := new(mocks.MockImageRepository)
+		relay := NewOutboxRelay(&ORConfig{
+			OutboxRepository: mockOutboxRepository,
+			EventsBroker:     mockEventsBroker,
+			Transactor:       mockTransactor,
+			MaxAttempts:      5,
+			RetryBackoff:     10 * time.Second,
+			ImageRepository:  mockImageRepository,
+			ImageURLTTL:      10 * time.Minute,
+		})
+
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockOutboxRepository.On("FetchPending", mock.Anything, outboxBatchSize).Return([]*model.OutboxMessage{msg}, nil)
+		mockImageRepository.On("SignURL", mock.Anything, "image-64", 10*time.Minute).Return("", apperrors.NewInternal())
+		mockOutboxRepository.On("MarkFailed", mock.Anything, int64(9), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+		mockOutboxRepository.On("DeletePublished", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+		n, err := relay.Relay(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		mockOutboxRepository.AssertExpectations(t)
+		mockEventsBroker.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryBackoff(10*time.Second, 1))
+	assert.Equal(t, 80*time.Second, retryBackoff(10*time.Second, 4))
+	assert.Equal(t, maxRetryBackoff, retryBackoff(10*time.Second, 1000))
+}
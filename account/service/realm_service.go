@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+// realmService holds the realms loaded from the RealmRepository in memory,
+// as they are needed on every request and rarely change
+type realmService struct {
+	RealmRepository model.RealmRepository
+	mu              sync.RWMutex
+	byID            map[string]*model.Realm
+	byHost          map[string]*model.Realm
+}
+
+// RSConfig will hold repositories that will eventually be injected into this service layer
+type RSConfig struct {
+	RealmRepository model.RealmRepository
+}
+
+// NewRealmService is a factory function for initializing a RealmService
+// with its repository layer dependencies. Realms are not available until
+// the first call to Reload
+func NewRealmService(c *RSConfig) model.RealmService {
+	return &realmService{
+		RealmRepository: c.RealmRepository,
+		byID:            map[string]*model.Realm{},
+		byHost:          map[string]*model.Realm{},
+	}
+}
+
+// Get returns the realm with the given id
+func (s *realmService) Get(id string) (*model.Realm, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	realm, ok := s.byID[id]
+	if !ok {
+		return nil, apperrors.NewNotFound("realm", id)
+	}
+
+	return realm, nil
+}
+
+// ResolveHost returns the realm serving the given host, ignoring case
+func (s *realmService) ResolveHost(host string) (*model.Realm, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	realm, ok := s.byHost[strings.ToLower(host)]
+
+	return realm, ok
+}
+
+// Reload replaces the realms held in memory with those in the repository
+// The previous realms are kept if they can't be loaded
+func (s *realmService) Reload(ctx context.Context) error {
+	realms, err := s.RealmRepository.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*model.Realm, len(realms))
+	byHost := map[string]*model.Realm{}
+
+	for _, realm := range realms {
+		byID[realm.ID] = realm
+
+		for _, host := range realm.Hosts {
+			byHost[strings.ToLower(host)] = realm
+		}
+	}
+
+	s.mu.Lock()
+	s.byID = byID
+	s.byHost = byHost
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRealmService(t *testing.T) {
+	acme := &model.Realm{ID: "acme", Name: "Acme", Hosts: []string{"Accounts.Acme.test"}}
+	defaultRealm := &model.Realm{ID: model.DefaultRealmID, Name: "Default"}
+
+	t.Run("Reload", func(t *testing.T) {
+		mockRealmRepository := new(mocks.MockRealmRepository)
+		rs := NewRealmService(&RSConfig{
+			RealmRepository: mockRealmRepository,
+		})
+
+		mockRealmRepository.On("List", mock.Anything).Return([]*model.Realm{defaultRealm, acme}, nil).Once()
+
+		// nothing is available until the first reload
+		_, err := rs.Get("acme")
+		assert.Equal(t, apperrors.NewNotFound("realm", "acme"), err)
+
+		assert.NoError(t, rs.Reload(context.Background()))
+
+		realm, err := rs.Get("acme")
+		assert.NoError(t, err)
+		assert.Equal(t, acme, realm)
+
+		realm, ok := rs.ResolveHost("accounts.acme.test")
+		assert.True(t, ok)
+		assert.Equal(t, acme, realm)
+
+		_, ok = rs.ResolveHost("unknown.test")
+		assert.False(t, ok)
+
+		// realms removed from the repository are dropped on reload
+		mockRealmRepository.On("List", mock.Anything).Return([]*model.Realm{defaultRealm}, nil).Once()
+		assert.NoError(t, rs.Reload(context.Background()))
+
+		_, err = rs.Get("acme")
+		assert.Error(t, err)
+		_, ok = rs.ResolveHost("accounts.acme.test")
+		assert.False(t, ok)
+	})
+
+	t.Run("Keeps realms when reload fails", func(t *testing.T) {
+		mockRealmRepository := new(mocks.MockRealmRepository)
+		rs := NewRealmService(&RSConfig{
+			RealmRepository: mockRealmRepository,
+		})
+
+		mockRealmRepository.On("List", mock.Anything).Return([]*model.Realm{acme}, nil).Once()
+		mockRealmRepository.On("List", mock.Anything).Return(nil, apperrors.NewInternal()).Once()
+
+		assert.NoError(t, rs.Reload(context.Background()))
+		assert.Error(t, rs.Reload(context.Background()))
+
+		realm, err := rs.Get("acme")
+		assert.NoError(t, err)
+		assert.Equal(t, acme, realm)
+	})
+}
@@ -4,49 +4,95 @@ import (
 	"context"
 	"crypto/rsa"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ndenisj/go_mem/account/model"
 	"github.com/ndenisj/go_mem/account/model/apperrors"
 )
 
+// maxImpersonationExpirationSecs caps the lifetime of impersonation
+// tokens whatever the configured lifetime is
+const maxImpersonationExpirationSecs = 15 * 60
+
 // tokenService used for injecting an implementation of TokenRepository
 // for use in service methods along with keys and secrets for signing JWT
 type tokenService struct {
-	TokenRepository       model.TokenRepository
-	PrivKey               *rsa.PrivateKey
-	PubKey                *rsa.PublicKey
-	RefreshSecret         string
-	IDExpirationSecs      int64
-	RefreshExpirationSecs int64
+	TokenRepository             model.TokenRepository
+	AuditEventRepository        model.AuditEventRepository
+	RealmService                model.RealmService
+	PrivKey                     *rsa.PrivateKey
+	PubKey                      *rsa.PublicKey
+	RefreshSecret               string
+	IDExpirationSecs            int64
+	RefreshExpirationSecs       int64
+	ImpersonationExpirationSecs int64
+	ImageRepository             model.ImageRepository
+	ImageURLTTL                 time.Duration
+	ImagePublicURL              string
+	BaseURL                     string
 }
 
 // TSConfig will hold repositories that will eventually be injected into this service layer
 type TSConfig struct {
-	TokenRepository       model.TokenRepository
-	PrivKey               *rsa.PrivateKey
-	PubKey                *rsa.PublicKey
-	RefreshSecret         string
-	IDExpirationSecs      int64
-	RefreshExpirationSecs int64
+	TokenRepository             model.TokenRepository
+	AuditEventRepository        model.AuditEventRepository
+	RealmService                model.RealmService
+	PrivKey                     *rsa.PrivateKey
+	PubKey                      *rsa.PublicKey
+	RefreshSecret               string
+	IDExpirationSecs            int64
+	RefreshExpirationSecs       int64
+	ImpersonationExpirationSecs int64
+	// ImageRepository, ImageURLTTL, ImagePublicURL and BaseURL resolve the
+	// images of users put in ID tokens, as in the user service's USConfig
+	ImageRepository model.ImageRepository
+	ImageURLTTL     time.Duration
+	ImagePublicURL  string
+	BaseURL         string
 }
 
 // NewTokenService is a factory function for initializing a UserService with its
 // repository layer dependencies
 func NewTokenService(c *TSConfig) model.TokenService {
 	return &tokenService{
-		TokenRepository:       c.TokenRepository,
-		PrivKey:               c.PrivKey,
-		PubKey:                c.PubKey,
-		RefreshSecret:         c.RefreshSecret,
-		IDExpirationSecs:      c.IDExpirationSecs,
-		RefreshExpirationSecs: c.RefreshExpirationSecs,
+		TokenRepository:             c.TokenRepository,
+		AuditEventRepository:        c.AuditEventRepository,
+		RealmService:                c.RealmService,
+		PrivKey:                     c.PrivKey,
+		PubKey:                      c.PubKey,
+		RefreshSecret:               c.RefreshSecret,
+		IDExpirationSecs:            c.IDExpirationSecs,
+		RefreshExpirationSecs:       c.RefreshExpirationSecs,
+		ImpersonationExpirationSecs: c.ImpersonationExpirationSecs,
+		ImageRepository:             c.ImageRepository,
+		ImageURLTTL:                 c.ImageURLTTL,
+		ImagePublicURL:              strings.TrimSuffix(c.ImagePublicURL, "/"),
+		BaseURL:                     c.BaseURL,
 	}
 }
 
 // NewPairFromUser creates fresh id and refresh tokens for the current user
 // If a previous token is included, the previous token is removed from the repository
+// and the refresh is recorded in the audit log
 func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error) {
+	pair, err := s.newPairFromUser(ctx, u, prevTokenID)
+
+	if prevTokenID != "" {
+		recordAudit(ctx, s.AuditEventRepository, &u.UID, &u.UID, model.AuditEventRefresh, auditOutcome(err), "")
+	}
+
+	return pair, err
+}
+
+func (s *tokenService) newPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error) {
+	// suspended and disabled users may not sign in or refresh their tokens
+	if err := u.StatusError(); err != nil {
+		log.Printf("refusing to create tokens for inactive uid: %v\n", u.UID)
+		return nil, err
+	}
+
 	if prevTokenID != "" {
 		if err := s.TokenRepository.DeleteRefreshToken(ctx, u.UID.String(), prevTokenID); err != nil {
 			log.Printf("could not delete previous refreshToken for uid: %v, tokenID: %v\n", u.UID.String(), prevTokenID)
@@ -55,15 +101,26 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevT
 		}
 	}
 
+	keys, err := s.realmKeys(realmOf(u.RealmID))
+	if err != nil {
+		log.Printf("Unable to find keys of realm: %v for uid: %v. Error: %v\n", u.RealmID, u.UID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	claimsUser, err := s.claimsUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
 	// No need to use a repository for idToken as it is unrelated to any data source
-	idToken, err := generateIDToken(u, s.PrivKey, s.IDExpirationSecs)
+	idToken, err := generateIDToken(claimsUser, keys.PrivKey, keys.IDExpirationSecs)
 
 	if err != nil {
 		log.Printf("Error generating idToken for uid: %v. Error: %v\n", u.UID, err.Error())
 		return nil, apperrors.NewInternal()
 	}
 
-	refreshToken, err := generateRefreshToken(u.UID, s.RefreshSecret, s.RefreshExpirationSecs)
+	refreshToken, err := generateRefreshToken(u.UID, u.RealmID, keys.RefreshSecret, keys.RefreshExpirationSecs)
 
 	if err != nil {
 		log.Printf("Error generating refreshToken for uid: %v. Error: %v\n", u.UID, err.Error())
@@ -88,14 +145,109 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevT
 	}, nil
 }
 
+// NewImpersonationToken creates an ID token for actor to act as u, marked with an
+// act claim. Impersonation tokens can't be refreshed and live for at most
+// maxImpersonationExpirationSecs. Every attempt is recorded in u's audit log
+func (s *tokenService) NewImpersonationToken(ctx context.Context, actor *model.User, u *model.User) (*model.ImpersonationToken, error) {
+	token, err := s.newImpersonationToken(ctx, actor, u)
+
+	detail := ""
+	if err == nil {
+		detail = "expires at " + token.ExpiresAt.Format(time.RFC3339)
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &u.UID, &actor.UID, model.AuditEventImpersonate, auditOutcome(err), detail)
+
+	return token, err
+}
+
+func (s *tokenService) newImpersonationToken(ctx context.Context, actor *model.User, u *model.User) (*model.ImpersonationToken, error) {
+	if actor.ActorUID != nil {
+		return nil, apperrors.NewForbidden("cannot impersonate while impersonating")
+	}
+
+	if actor.UID == u.UID {
+		return nil, apperrors.NewBadRequest("cannot impersonate yourself")
+	}
+
+	// impersonating a user with roles would hand out their privileges
+	if len(u.Roles) > 0 {
+		return nil, apperrors.NewForbidden("users with roles cannot be impersonated")
+	}
+
+	if err := u.StatusError(); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.realmKeys(realmOf(u.RealmID))
+	if err != nil {
+		log.Printf("Unable to find keys of realm: %v for uid: %v. Error: %v\n", u.RealmID, u.UID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	exp := s.ImpersonationExpirationSecs
+	if exp <= 0 || exp > maxImpersonationExpirationSecs {
+		exp = maxImpersonationExpirationSecs
+	}
+
+	if exp > keys.IDExpirationSecs {
+		exp = keys.IDExpirationSecs
+	}
+
+	expiresAt := time.Now().Add(time.Duration(exp) * time.Second)
+
+	claimsUser, err := s.claimsUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := generateImpersonationToken(actor, claimsUser, keys.PrivKey, exp)
+	if err != nil {
+		log.Printf("Error generating impersonation token for uid: %v. Error: %v\n", u.UID, err.Error())
+		return nil, apperrors.NewInternal()
+	}
+
+	return &model.ImpersonationToken{
+		IDToken: model.IDToken{
+			SS: idToken,
+		},
+		ExpiresAt: expiresAt.Truncate(time.Second),
+	}, nil
+}
+
+// claimsUser copies u for an ID token, with its image resolved to URLs
+// clients can load rather than where it is kept in storage
+func (s *tokenService) claimsUser(ctx context.Context, u *model.User) (*model.User, error) {
+	claimsUser := *u
+
+	if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, &claimsUser); err != nil {
+		log.Printf("Error resolving image URLs for uid: %v. Error: %v\n", u.UID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return &claimsUser, nil
+}
+
+// Signout revokes all of the user's refresh tokens
 func (s *tokenService) Signout(ctx context.Context, uid uuid.UUID) error {
-	return s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String())
+	err := s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String())
+
+	recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventSignout, auditOutcome(err), "")
+
+	return err
 }
 
 // ValidateIDToken validates the id token jwt string
 // It returns the user extract from the IDTokenCustomClaims
 func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	claims, err := validateIDToken(tokenString, s.PubKey) // uses public RSA key
+	// uses the public RSA key of the token's realm
+	claims, err := validateIDToken(tokenString, func(realmID string) (*rsa.PublicKey, error) {
+		keys, err := s.realmKeys(realmID)
+		if err != nil {
+			return nil, err
+		}
+		return keys.PubKey, nil
+	})
 
 	// We'll just return unauthorized error in all instances of failing to verify user
 	if err != nil {
@@ -103,6 +255,27 @@ func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error)
 		return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
 	}
 
+	if claims.User == nil {
+		log.Printf("idToken is missing the user claim\n")
+		return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
+	}
+
+	// roles are carried in their own claims rather than on the user
+	claims.User.Roles = claims.Roles
+	claims.User.Permissions = claims.Permissions
+	claims.User.Orgs = claims.Orgs
+	claims.User.RealmID = realmOf(claims.Realm)
+
+	if claims.Act != nil {
+		actorUID, err := uuid.Parse(claims.Act.Sub)
+		if err != nil {
+			log.Printf("idToken has an invalid act claim: %v\n", claims.Act.Sub)
+			return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
+		}
+
+		claims.User.ActorUID = &actorUID
+	}
+
 	return claims.User, nil
 }
 
@@ -110,7 +283,13 @@ func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error)
 // and returns a RefreshToken if valid
 func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshToken, error) {
 	// validate actual JWT with string of secret
-	claims, err := validateRefreshToken(tokenString, s.RefreshSecret)
+	claims, err := validateRefreshToken(tokenString, func(realmID string) (string, error) {
+		keys, err := s.realmKeys(realmID)
+		if err != nil {
+			return "", err
+		}
+		return keys.RefreshSecret, nil
+	})
 	// Return unauthorized error in all instances of failing to verify user
 	if err != nil {
 		log.Printf("unable to validate or parse refreshToken for token string: %s\n%v\n", tokenString, err)
@@ -131,3 +310,44 @@ func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshT
 	}, nil
 
 }
+
+// realmKeys returns the keys and token lifetimes of a realm, using the
+// deployment's for those the realm does not set
+func (s *tokenService) realmKeys(realmID string) (*model.Realm, error) {
+	keys := &model.Realm{
+		ID:                    realmID,
+		PrivKey:               s.PrivKey,
+		PubKey:                s.PubKey,
+		RefreshSecret:         s.RefreshSecret,
+		IDExpirationSecs:      s.IDExpirationSecs,
+		RefreshExpirationSecs: s.RefreshExpirationSecs,
+	}
+
+	if s.RealmService == nil {
+		return keys, nil
+	}
+
+	realm, err := s.RealmService.Get(realmID)
+	if err != nil {
+		return nil, err
+	}
+
+	if realm.PrivKey != nil && realm.PubKey != nil {
+		keys.PrivKey = realm.PrivKey
+		keys.PubKey = realm.PubKey
+	}
+
+	if realm.RefreshSecret != "" {
+		keys.RefreshSecret = realm.RefreshSecret
+	}
+
+	if realm.IDExpirationSecs > 0 {
+		keys.IDExpirationSecs = realm.IDExpirationSecs
+	}
+
+	if realm.RefreshExpirationSecs > 0 {
+		keys.RefreshExpirationSecs = realm.RefreshExpirationSecs
+	}
+
+	return keys, nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"io/ioutil"
 
@@ -28,10 +30,13 @@ func TestNewPairFromUser(t *testing.T) {
 	secret := "anotsorandomtestsecret"
 
 	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+	mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
 
 	// instantiate a common token service to be used by all tests
 	tokenService := NewTokenService(&TSConfig{
 		TokenRepository:       mockTokenRepository,
+		AuditEventRepository:  mockAuditEventRepository,
 		PrivKey:               privKey,
 		PubKey:                pubKey,
 		RefreshSecret:         secret,
@@ -105,19 +110,19 @@ func TestNewPairFromUser(t *testing.T) {
 
 		assert.NoError(t, err)
 
-		// assert claims on idToken
+		// assert claims on idToken, where users without an image are given their default avatar
 		expectedClaims := []interface{}{
 			u.UID,
 			u.Email,
 			u.Name,
-			u.ImageURL,
+			defaultAvatarURLs("", u.UID),
 			u.Website,
 		}
 		actualIDClaims := []interface{}{
 			idTokenClaims.User.UID,
 			idTokenClaims.User.Email,
 			idTokenClaims.User.Name,
-			idTokenClaims.User.ImageURL,
+			idTokenClaims.User.ImageURLs,
 			idTokenClaims.User.Website,
 		}
 
@@ -155,6 +160,23 @@ func TestNewPairFromUser(t *testing.T) {
 		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 	})
 
+	t.Run("Suspended user", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		uSuspended := &model.User{
+			UID:          uid,
+			Status:       model.UserStatusSuspended,
+			StatusReason: "abuse",
+		}
+
+		ctx := context.Background()
+		_, err := tokenService.NewPairFromUser(ctx, uSuspended, prevID)
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.AccountInactive, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken", mock.Anything, uid.String(), prevID)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, uid.String(), mock.Anything, mock.Anything)
+	})
+
 	t.Run("Empty string provided for prevID", func(t *testing.T) {
 		ctx := context.Background()
 		_, err := tokenService.NewPairFromUser(ctx, u, "")
@@ -165,12 +187,121 @@ func TestNewPairFromUser(t *testing.T) {
 		// DeleteRefreshToken should not be called since prevID is ""
 		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 	})
+
+	t.Run("Images linked to under the public URL", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		ts := NewTokenService(&TSConfig{
+			TokenRepository:       mockTokenRepository,
+			AuditEventRepository:  mockAuditEventRepository,
+			PrivKey:               privKey,
+			PubKey:                pubKey,
+			RefreshSecret:         secret,
+			IDExpirationSecs:      idExp,
+			RefreshExpirationSecs: refreshExp,
+			ImagePublicURL:        "https://account.example.com/images/",
+		})
+
+		uid, _ := uuid.NewRandom()
+		uImage := &model.User{
+			UID: uid,
+			ImageURLs: model.ImageURLs{
+				64:  "https://storage.googleapis.com/bucket/image-64",
+				128: "https://storage.googleapis.com/bucket/image-128",
+			},
+		}
+
+		tokenPair, err := ts.NewPairFromUser(context.Background(), uImage, "")
+		assert.NoError(t, err)
+
+		idTokenClaims := &idTokenCustomClaims{}
+		_, err = jwt.ParseWithClaims(tokenPair.IDToken.SS, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		})
+
+		// the token never holds the bucket the image is kept in
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageURLs{
+			64:  "https://account.example.com/images/image-64",
+			128: "https://account.example.com/images/image-128",
+		}, idTokenClaims.User.ImageURLs)
+		assert.Equal(t, "https://storage.googleapis.com/bucket/image-64", uImage.ImageURLs[64])
+	})
+
+	t.Run("Private images are signed", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockImageRepository.On("SignURL", mock.Anything, "image-64", 10*time.Minute).Return("https://storage.example.com/image-64?sig=abc", nil)
+
+		ts := NewTokenService(&TSConfig{
+			TokenRepository:       mockTokenRepository,
+			AuditEventRepository:  mockAuditEventRepository,
+			PrivKey:               privKey,
+			PubKey:                pubKey,
+			RefreshSecret:         secret,
+			IDExpirationSecs:      idExp,
+			RefreshExpirationSecs: refreshExp,
+			ImageRepository:       mockImageRepository,
+			ImageURLTTL:           10 * time.Minute,
+		})
+
+		uid, _ := uuid.NewRandom()
+		uImage := &model.User{
+			UID:       uid,
+			ImageURLs: model.ImageURLs{64: "image-64"},
+		}
+
+		tokenPair, err := ts.NewPairFromUser(context.Background(), uImage, "")
+		assert.NoError(t, err)
+
+		idTokenClaims := &idTokenCustomClaims{}
+		_, err = jwt.ParseWithClaims(tokenPair.IDToken.SS, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		})
+
+		// the token holds a link which can be loaded rather than the object key
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageURLs{64: "https://storage.example.com/image-64?sig=abc"}, idTokenClaims.User.ImageURLs)
+	})
+
+	t.Run("Error signing images", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockImageRepository.On("SignURL", mock.Anything, "image-64", 10*time.Minute).Return("", apperrors.NewInternal())
+
+		ts := NewTokenService(&TSConfig{
+			TokenRepository:       mockTokenRepository,
+			AuditEventRepository:  mockAuditEventRepository,
+			PrivKey:               privKey,
+			PubKey:                pubKey,
+			RefreshSecret:         secret,
+			IDExpirationSecs:      idExp,
+			RefreshExpirationSecs: refreshExp,
+			ImageRepository:       mockImageRepository,
+			ImageURLTTL:           10 * time.Minute,
+		})
+
+		uid, _ := uuid.NewRandom()
+		uImage := &model.User{
+			UID:       uid,
+			ImageURLs: model.ImageURLs{64: "image-64"},
+		}
+
+		_, err := ts.NewPairFromUser(context.Background(), uImage, "")
+
+		assert.Error(t, err)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestSignout(t *testing.T) {
 	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditEventRepository := new(mocks.MockAuditEventRepository)
 	tokenService := NewTokenService(&TSConfig{
-		TokenRepository: mockTokenRepository,
+		TokenRepository:      mockTokenRepository,
+		AuditEventRepository: mockAuditEventRepository,
 	})
 
 	t.Run("No error", func(t *testing.T) {
@@ -178,10 +309,14 @@ func TestSignout(t *testing.T) {
 		mockTokenRepository.
 			On("DeleteUserRefreshTokens", mock.AnythingOfType("*context.emptyCtx"), uidSuccess.String()).
 			Return(nil)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return *e.UID == uidSuccess && e.Event == model.AuditEventSignout && e.Outcome == model.AuditOutcomeSuccess
+		})).Return(nil)
 
 		ctx := context.Background()
 		err := tokenService.Signout(ctx, uidSuccess)
 		assert.NoError(t, err)
+		mockAuditEventRepository.AssertExpectations(t)
 	})
 
 	t.Run("Error", func(t *testing.T) {
@@ -189,6 +324,9 @@ func TestSignout(t *testing.T) {
 		mockTokenRepository.
 			On("DeleteUserRefreshTokens", mock.AnythingOfType("*context.emptyCtx"), uidError.String()).
 			Return(apperrors.NewInternal())
+		mockAuditEventRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return *e.UID == uidError && e.Event == model.AuditEventSignout && e.Outcome == model.AuditOutcomeFailure
+		})).Return(nil)
 
 		ctx := context.Background()
 		err := tokenService.Signout(ctx, uidError)
@@ -236,11 +374,52 @@ func TestValidateIDToken(t *testing.T) {
 
 		assert.ElementsMatch(
 			t,
-			[]interface{}{u.Email, u.Name, u.UID, u.Website, u.ImageURL},
-			[]interface{}{uFromToken.Email, uFromToken.Name, uFromToken.UID, uFromToken.Website, uFromToken.ImageURL},
+			[]interface{}{u.Email, u.Name, u.UID, u.Website, u.ImageURLs},
+			[]interface{}{uFromToken.Email, uFromToken.Name, uFromToken.UID, uFromToken.Website, uFromToken.ImageURLs},
 		)
 	})
 
+	t.Run("Roles claim", func(t *testing.T) {
+		uWithRoles := *u
+		uWithRoles.Roles = []string{model.RoleAdmin}
+		uWithRoles.Permissions = []string{model.PermissionUsersRead, model.PermissionUsersWrite}
+
+		ss, _ := generateIDToken(&uWithRoles, privKey, idExp)
+
+		claims := &idTokenCustomClaims{}
+		_, err := jwt.ParseWithClaims(ss, claims, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uWithRoles.Roles, claims.Roles)
+		assert.Equal(t, uWithRoles.Permissions, claims.Permissions)
+
+		uFromToken, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.True(t, uFromToken.HasRole(model.RoleAdmin))
+		assert.True(t, uFromToken.HasPermission(model.PermissionUsersWrite))
+		assert.False(t, uFromToken.HasRole(model.RoleSupport))
+	})
+
+	t.Run("Orgs claim", func(t *testing.T) {
+		orgID := uuid.New().String()
+		uWithOrgs := *u
+		uWithOrgs.Orgs = map[string]string{orgID: model.OrgRoleAdmin}
+
+		ss, _ := generateIDToken(&uWithOrgs, privKey, idExp)
+
+		claims := &idTokenCustomClaims{}
+		_, err := jwt.ParseWithClaims(ss, claims, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uWithOrgs.Orgs, claims.Orgs)
+
+		uFromToken, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.Equal(t, model.OrgRoleAdmin, uFromToken.Orgs[orgID])
+	})
+
 	t.Run("Expired token", func(t *testing.T) {
 		// maybe not the best approach to depend on utility method
 		// token will be valid for 15 minutes
@@ -283,7 +462,7 @@ func TestValidateRefreshToken(t *testing.T) {
 	}
 
 	t.Run("Valid token", func(t *testing.T) {
-		testRefreshToken, _ := generateRefreshToken(u.UID, secret, refreshExp)
+		testRefreshToken, _ := generateRefreshToken(u.UID, u.RealmID, secret, refreshExp)
 
 		validatedRefreshToken, err := tokenService.ValidateRefreshToken(testRefreshToken.SS)
 		assert.NoError(t, err)
@@ -294,7 +473,7 @@ func TestValidateRefreshToken(t *testing.T) {
 	})
 
 	t.Run("Expired token", func(t *testing.T) {
-		// testRefreshToken, _ := generateRefreshToken(u.UID, secret, -1)
+		// testRefreshToken, _ := generateRefreshToken(u.UID, u.RealmID, secret, -1)
 
 		// expectedErr := apperrors.NewAuthorization("Unable to verify user from refresh token")
 
@@ -302,3 +481,206 @@ func TestValidateRefreshToken(t *testing.T) {
 		// assert.EqualError(t, err, expectedErr.Message)
 	})
 }
+
+func TestRealmTokens(t *testing.T) {
+	priv, _ := os.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := os.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+	secret := "anotsorandomtestsecret"
+
+	acmeKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	acme := &model.Realm{
+		ID:               "acme",
+		PrivKey:          acmeKey,
+		PubKey:           &acmeKey.PublicKey,
+		RefreshSecret:    "acmerefreshsecret",
+		IDExpirationSecs: 60,
+	}
+
+	mockRealmService := new(mocks.MockRealmService)
+	mockRealmService.On("Get", model.DefaultRealmID).Return(&model.Realm{ID: model.DefaultRealmID}, nil)
+	mockRealmService.On("Get", "acme").Return(acme, nil)
+	mockRealmService.On("Get", "gone").Return(nil, apperrors.NewNotFound("realm", "gone"))
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository:       mockTokenRepository,
+		RealmService:          mockRealmService,
+		PrivKey:               privKey,
+		PubKey:                pubKey,
+		RefreshSecret:         secret,
+		IDExpirationSecs:      15 * 60,
+		RefreshExpirationSecs: 3 * 24 * 3600,
+	})
+
+	t.Run("Signs with the realm's keys", func(t *testing.T) {
+		u := &model.User{UID: uuid.New(), RealmID: "acme", Email: "bob@acme.test"}
+
+		pair, err := tokenService.NewPairFromUser(context.Background(), u, "")
+		assert.NoError(t, err)
+
+		claims := &idTokenCustomClaims{}
+		_, err = jwt.ParseWithClaims(pair.IDToken.SS, claims, func(token *jwt.Token) (interface{}, error) {
+			return &acmeKey.PublicKey, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", claims.Realm)
+		assert.Equal(t, int64(60), claims.ExpiresAt-claims.IssuedAt)
+
+		uFromToken, err := tokenService.ValidateIDToken(pair.IDToken.SS)
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", uFromToken.RealmID)
+
+		refreshToken, err := tokenService.ValidateRefreshToken(pair.RefreshToken.SS)
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, refreshToken.UID)
+
+		// the deployment's secret is not accepted for the realm
+		_, err = validateRefreshToken(pair.RefreshToken.SS, func(string) (string, error) {
+			return secret, nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Falls back to the deployment's keys", func(t *testing.T) {
+		u := &model.User{UID: uuid.New(), Email: "bob@bob.com"}
+
+		pair, err := tokenService.NewPairFromUser(context.Background(), u, "")
+		assert.NoError(t, err)
+
+		uFromToken, err := tokenService.ValidateIDToken(pair.IDToken.SS)
+		assert.NoError(t, err)
+		assert.Equal(t, model.DefaultRealmID, uFromToken.RealmID)
+	})
+
+	t.Run("Token claiming another realm", func(t *testing.T) {
+		// signed with the deployment's key, but claiming to be from acme
+		forged := &model.User{UID: uuid.New(), RealmID: "acme"}
+		ss, _ := generateIDToken(forged, privKey, 60)
+
+		_, err := tokenService.ValidateIDToken(ss)
+		assert.EqualError(t, err, "Unable to verify user from idToken")
+	})
+
+	t.Run("Unknown realm", func(t *testing.T) {
+		u := &model.User{UID: uuid.New(), RealmID: "gone"}
+
+		_, err := tokenService.NewPairFromUser(context.Background(), u, "")
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+
+		ss, _ := generateIDToken(u, privKey, 60)
+		_, err = tokenService.ValidateIDToken(ss)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewImpersonationToken(t *testing.T) {
+	priv, _ := os.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := os.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	admin := &model.User{UID: uuid.New(), Roles: []string{model.RoleAdmin}}
+	u := &model.User{UID: uuid.New(), Email: "bob@bob.com"}
+
+	newService := func(impersonationExp int64) (model.TokenService, *mocks.MockAuditEventRepository, *mocks.MockTokenRepository) {
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		return NewTokenService(&TSConfig{
+			TokenRepository:             mockTokenRepository,
+			AuditEventRepository:        mockAuditEventRepository,
+			PrivKey:                     privKey,
+			PubKey:                      pubKey,
+			RefreshSecret:               "anotsorandomtestsecret",
+			IDExpirationSecs:            60 * 60,
+			RefreshExpirationSecs:       3 * 24 * 3600,
+			ImpersonationExpirationSecs: impersonationExp,
+		}), mockAuditEventRepository, mockTokenRepository
+	}
+
+	auditEvent := func(outcome string) interface{} {
+		return mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return *e.UID == u.UID && *e.ActorUID == admin.UID && e.Event == model.AuditEventImpersonate && e.Outcome == outcome
+		})
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		tokenService, mockAuditEventRepository, mockTokenRepository := newService(5 * 60)
+
+		token, err := tokenService.NewImpersonationToken(context.Background(), admin, u)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), token.ExpiresAt, 2*time.Second)
+
+		claims := &idTokenCustomClaims{}
+		_, err = jwt.ParseWithClaims(token.SS, claims, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, admin.UID.String(), claims.Act.Sub)
+		assert.Equal(t, int64(5*60), claims.ExpiresAt-claims.IssuedAt)
+
+		uFromToken, err := tokenService.ValidateIDToken(token.SS)
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, uFromToken.UID)
+		assert.Equal(t, admin.UID, *uFromToken.ActorUID)
+
+		// no refresh token is stored
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockAuditEventRepository.AssertCalled(t, "Create", mock.Anything, auditEvent(model.AuditOutcomeSuccess))
+	})
+
+	t.Run("Lifetime is capped", func(t *testing.T) {
+		tokenService, _, _ := newService(24 * 60 * 60)
+
+		token, err := tokenService.NewImpersonationToken(context.Background(), admin, u)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(maxImpersonationExpirationSecs*time.Second), token.ExpiresAt, 2*time.Second)
+	})
+
+	t.Run("Regular tokens have no act claim", func(t *testing.T) {
+		tokenService, _, _ := newService(5 * 60)
+
+		ss, _ := generateIDToken(u, privKey, 60)
+
+		uFromToken, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.Nil(t, uFromToken.ActorUID)
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		impersonating := &model.User{UID: admin.UID, ActorUID: &admin.UID}
+		support := &model.User{UID: uuid.New(), Roles: []string{model.RoleSupport}}
+		disabled := &model.User{UID: uuid.New(), Status: model.UserStatusDisabled}
+
+		tests := []struct {
+			name   string
+			actor  *model.User
+			target *model.User
+			status int
+		}{
+			{"Self", admin, admin, 400},
+			{"User with roles", admin, support, 403},
+			{"While impersonating", impersonating, u, 403},
+			{"Disabled user", admin, disabled, 403},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tokenService, mockAuditEventRepository, _ := newService(5 * 60)
+
+				token, err := tokenService.NewImpersonationToken(context.Background(), tt.actor, tt.target)
+				assert.Nil(t, token)
+				assert.Equal(t, tt.status, apperrors.Status(err))
+
+				mockAuditEventRepository.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+					return *e.UID == tt.target.UID && e.Event == model.AuditEventImpersonate && e.Outcome == model.AuditOutcomeFailure
+				}))
+			})
+		}
+	})
+}
@@ -12,11 +12,26 @@ import (
 )
 
 // idTokenCustomClaims holds structure of jwt claims of idTokens
+// Roles, Permissions and Orgs are top level claims so downstream
+// services can authorize without knowing the user shape. Orgs maps
+// organization ids to the user's role, so services can authorize per organization.
+// Realm names the realm whose keys signed the token. Act is only present
+// on impersonation tokens, identifying the admin acting as the user
 type idTokenCustomClaims struct {
-	User *model.User `json:"user"`
+	User        *model.User       `json:"user"`
+	Realm       string            `json:"realm"`
+	Roles       []string          `json:"roles"`
+	Permissions []string          `json:"permissions"`
+	Orgs        map[string]string `json:"orgs"`
+	Act         *actorClaim       `json:"act,omitempty"`
 	jwt.StandardClaims
 }
 
+// actorClaim is the act claim of RFC 8693, where Sub is the actor's uid
+type actorClaim struct {
+	Sub string `json:"sub"`
+}
+
 // refreshTokenData holds the actual signed jwt string along with the ID
 // We return the id so it can be used without re-parsing the JWT from signed string
 type refreshTokenData struct {
@@ -29,24 +44,47 @@ type refreshTokenData struct {
 // This can be used to extract user id for subsequent
 // application operations (IE, fetch user in Redis)
 type refreshTokenCustomClaims struct {
-	UID uuid.UUID `json:"uid"`
+	UID   uuid.UUID `json:"uid"`
+	Realm string    `json:"realm"`
 	jwt.StandardClaims
 }
 
 // generateIDToken generates an IDToken which is a jwt with myCustomClaims
 // Could call this GenerateIDTokenString, but the signature makes this fairly clear
 func generateIDToken(u *model.User, key *rsa.PrivateKey, exp int64) (string, error) {
+	return signIDToken(newIDTokenClaims(u, exp), key)
+}
+
+// generateImpersonationToken generates an IDToken for u marked with
+// an act claim identifying the admin impersonating them
+func generateImpersonationToken(actor *model.User, u *model.User, key *rsa.PrivateKey, exp int64) (string, error) {
+	claims := newIDTokenClaims(u, exp)
+	claims.Act = &actorClaim{
+		Sub: actor.UID.String(),
+	}
+
+	return signIDToken(claims, key)
+}
+
+// newIDTokenClaims returns the claims of an ID token for u expiring after exp seconds
+func newIDTokenClaims(u *model.User, exp int64) *idTokenCustomClaims {
 	unixTime := time.Now().Unix()
 	tokenExp := unixTime + exp
 
-	claims := idTokenCustomClaims{
-		User: u,
+	return &idTokenCustomClaims{
+		User:        u,
+		Realm:       realmOf(u.RealmID),
+		Roles:       nonNil(u.Roles),
+		Permissions: nonNil(u.Permissions),
+		Orgs:        nonNilMap(u.Orgs),
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  unixTime,
 			ExpiresAt: tokenExp,
 		},
 	}
+}
 
+func signIDToken(claims *idTokenCustomClaims, key *rsa.PrivateKey) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	ss, err := token.SignedString(key)
 
@@ -58,9 +96,32 @@ func generateIDToken(u *model.User, key *rsa.PrivateKey, exp int64) (string, err
 	return ss, nil
 }
 
+// nonNil and nonNilMap make sure empty claims are encoded as [] and {} rather than null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// realmOf returns realmID, treating users from before realms as members of the default realm
+func realmOf(realmID string) string {
+	if realmID == "" {
+		return model.DefaultRealmID
+	}
+	return realmID
+}
+
 // generateRefreshToken creates a refresh token
-// The refresh token stores only the user's ID, a string
-func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenData, error) {
+// The refresh token stores only the user's ID and realm
+func generateRefreshToken(uid uuid.UUID, realmID string, key string, exp int64) (*refreshTokenData, error) {
 	currentTime := time.Now()
 	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
 	tokenID, err := uuid.NewRandom() // v4 uuid in the google uuid lib
@@ -71,7 +132,8 @@ func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenDa
 	}
 
 	claims := refreshTokenCustomClaims{
-		UID: uid,
+		UID:   uid,
+		Realm: realmOf(realmID),
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  currentTime.Unix(),
 			ExpiresAt: tokenExp.Unix(),
@@ -95,11 +157,12 @@ func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenDa
 }
 
 // validateIDToken returns the token's claims if the token is valid
-func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClaims, error) {
+// key returns the public key of the realm named by the token's realm claim
+func validateIDToken(tokenString string, key func(realmID string) (*rsa.PublicKey, error)) (*idTokenCustomClaims, error) {
 	claims := &idTokenCustomClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return key, nil
+		return key(realmOf(claims.Realm))
 	})
 
 	// For now we'll just return the error and handle logging in service level
@@ -120,11 +183,17 @@ func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClai
 	return claims, nil
 }
 
-func validateRefreshToken(tokenString string, key string) (*refreshTokenCustomClaims, error) {
+// validateRefreshToken returns the token's claims if the token is valid
+// key returns the refresh secret of the realm named by the token's realm claim
+func validateRefreshToken(tokenString string, key func(realmID string) (string, error)) (*refreshTokenCustomClaims, error) {
 	claims := &refreshTokenCustomClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(key), nil
+		secret, err := key(realmOf(claims.Realm))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(secret), nil
 	})
 
 	// for now we will just return the error and handle logging in service layer
@@ -2,36 +2,122 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
 	"net/url"
 	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ndenisj/go_mem/account/model"
 	"github.com/ndenisj/go_mem/account/model/apperrors"
 )
 
+// purgeBatchSize is the most soft deleted users removed by a single purge
+const purgeBatchSize = 100
+
 // userService acts as a struct for injecting an implementation of
 // UserRepository for use in service methods
 type userService struct {
-	UserRepository  model.UserRepository
-	ImageRepository model.ImageRepository
+	UserRepository        model.UserRepository
+	ImageRepository       model.ImageRepository
+	TokenRepository       model.TokenRepository
+	AuditEventRepository  model.AuditEventRepository
+	OutboxRepository      model.OutboxRepository
+	Transactor            model.Transactor
+	DeletionGracePeriod   time.Duration
+	MaxImageDimension     int
+	ImageURLTTL           time.Duration
+	ImagePublicURL        string
+	ImageUploadTTL        time.Duration
+	MaxImageBytes         int64
+	BaseURL               string
+	ImageHistoryLimit     int
+	ImageModerator        model.ImageModerator
+	ImageReviewRepository model.ImageReviewRepository
+	ModerateAsync         bool
 }
 
 // USConfig will hold repository that will eventually be injected
 // into this service layer
 type USConfig struct {
-	UserRepository  model.UserRepository
-	ImageRepository model.ImageRepository
+	UserRepository       model.UserRepository
+	ImageRepository      model.ImageRepository
+	TokenRepository      model.TokenRepository
+	AuditEventRepository model.AuditEventRepository
+	OutboxRepository     model.OutboxRepository
+	Transactor           model.Transactor
+	DeletionGracePeriod  time.Duration
+	// MaxImageDimension is the largest width or height of an uploaded
+	// profile image, defaulting to defaultMaxImageDimension
+	MaxImageDimension int
+	// ImageURLTTL is how long signed image URLs last when image storage
+	// is private. It is zero for public storage, where URLs are stored
+	ImageURLTTL time.Duration
+	// ImagePublicURL is where public images are linked to, under their object
+	// names, instead of at the URLs they were stored with. Unused when private
+	ImagePublicURL string
+	// ImageUploadTTL is how long an upload straight to storage can be
+	// started, defaulting to defaultImageUploadTTL
+	ImageUploadTTL time.Duration
+	// MaxImageBytes is the largest image which can be uploaded straight
+	// to storage, defaulting to defaultMaxImageBytes
+	MaxImageBytes int64
+	// BaseURL is where this service is served, which default avatars are linked to under
+	BaseURL string
+	// ImageHistoryLimit is how many replaced profile images are kept for
+	// the user to revert to. Replaced images are deleted when it is zero
+	ImageHistoryLimit int
+	// ImageModerator decides whether new profile images may be shown,
+	// allowing every image when nil. Images it quarantines are held in
+	// ImageReviewRepository until an admin approves them
+	ImageModerator        model.ImageModerator
+	ImageReviewRepository model.ImageReviewRepository
+	// ModerateAsync holds every new profile image for review and runs
+	// the moderator in the background, rather than during the upload
+	ModerateAsync bool
 }
 
 // NewUserService is a factory function for initializing
 // a UserService with its repository layer dependencies
 func NewUserService(c *USConfig) model.UserService {
+	maxImageDimension := c.MaxImageDimension
+	if maxImageDimension <= 0 {
+		maxImageDimension = defaultMaxImageDimension
+	}
+
+	maxImageBytes := c.MaxImageBytes
+	if maxImageBytes <= 0 {
+		maxImageBytes = defaultMaxImageBytes
+	}
+
+	imageUploadTTL := c.ImageUploadTTL
+	if imageUploadTTL <= 0 {
+		imageUploadTTL = defaultImageUploadTTL
+	}
+
 	return &userService{
-		UserRepository:  c.UserRepository,
-		ImageRepository: c.ImageRepository,
+		UserRepository:        c.UserRepository,
+		ImageRepository:       c.ImageRepository,
+		TokenRepository:       c.TokenRepository,
+		AuditEventRepository:  c.AuditEventRepository,
+		OutboxRepository:      c.OutboxRepository,
+		Transactor:            c.Transactor,
+		DeletionGracePeriod:   c.DeletionGracePeriod,
+		MaxImageDimension:     maxImageDimension,
+		ImageURLTTL:           c.ImageURLTTL,
+		ImagePublicURL:        strings.TrimSuffix(c.ImagePublicURL, "/"),
+		ImageUploadTTL:        imageUploadTTL,
+		MaxImageBytes:         maxImageBytes,
+		BaseURL:               c.BaseURL,
+		ImageHistoryLimit:     c.ImageHistoryLimit,
+		ImageModerator:        c.ImageModerator,
+		ImageReviewRepository: c.ImageReviewRepository,
+		ModerateAsync:         c.ModerateAsync,
 	}
 }
 
@@ -42,6 +128,22 @@ func (s *userService) Get(ctx context.Context, uid uuid.UUID) (*model.User, erro
 	return u, err
 }
 
+// Profile retrieves a user to be shown to them, with URLs to their image
+// which can be loaded even when image storage is private, or to their
+// default avatar when they have no image
+func (s *userService) Profile(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
 // Signup reaches our UserRepository to verify the email address is
 // available and signs up the user if that is the case
 func (s *userService) Signup(ctx context.Context, u *model.User) error {
@@ -56,16 +158,16 @@ func (s *userService) Signup(ctx context.Context, u *model.User) error {
 	// then created a user. It's somewhat un-natural to mutate the user here
 	u.Password = pw
 
-	if err := s.UserRepository.Create(ctx, u); err != nil {
+	err = withEvent(ctx, s.Transactor, s.OutboxRepository, model.EventUserCreated, func(ctx context.Context) (*model.User, error) {
+		return u, s.UserRepository.Create(ctx, u)
+	})
+
+	if err != nil {
+		recordAudit(ctx, s.AuditEventRepository, nil, nil, model.AuditEventSignup, model.AuditOutcomeFailure, fmt.Sprintf("email=%q", u.Email))
 		return err
 	}
 
-	// If we get around to add events, we'd provide it here
-	// err := s.EventsBroker.PublishUserUpdated(u, true)
-
-	// if err != nil {
-	// 	return nil, apperrors.NewInternal()
-	// }
+	recordAudit(ctx, s.AuditEventRepository, &u.UID, &u.UID, model.AuditEventSignup, model.AuditOutcomeSuccess, "")
 
 	return nil
 }
@@ -79,6 +181,7 @@ func (s *userService) Signin(ctx context.Context, u *model.User) error {
 
 	// Will return NotAuthorized to client to omit details of why
 	if err != nil {
+		recordAudit(ctx, s.AuditEventRepository, nil, nil, model.AuditEventSignin, model.AuditOutcomeFailure, fmt.Sprintf("unknown email=%q", u.Email))
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
@@ -90,9 +193,18 @@ func (s *userService) Signin(ctx context.Context, u *model.User) error {
 	}
 
 	if !match {
+		recordAudit(ctx, s.AuditEventRepository, &uFetched.UID, &uFetched.UID, model.AuditEventSignin, model.AuditOutcomeFailure, "invalid password")
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
+	// only tell the user why they cannot sign in once they have proven who they are
+	if err := uFetched.StatusError(); err != nil {
+		recordAudit(ctx, s.AuditEventRepository, &uFetched.UID, &uFetched.UID, model.AuditEventSignin, model.AuditOutcomeFailure, fmt.Sprintf("account %s", uFetched.Status))
+		return err
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &uFetched.UID, &uFetched.UID, model.AuditEventSignin, model.AuditOutcomeSuccess, "")
+
 	*u = *uFetched
 
 	return nil
@@ -100,90 +212,476 @@ func (s *userService) Signin(ctx context.Context, u *model.User) error {
 
 func (s *userService) UpdateDetails(ctx context.Context, u *model.User) error {
 	// Update user in UserRepository
-	err := s.UserRepository.Update(ctx, u)
+	err := updateWithEvents(ctx, s.Transactor, s.OutboxRepository, s.UserRepository, u, nil)
+
+	recordAudit(ctx, s.AuditEventRepository, &u.UID, &u.UID, model.AuditEventUpdateDetails, auditOutcome(err), fmt.Sprintf("name=%q email=%q website=%q", u.Name, u.Email, u.Website))
+
 	if err != nil {
 		return err
 	}
 
-	// Publish user updated
-	// err = s.EventsBroker.PublishUserUpdated(u, false)
-	// if err != nil {
-	// 	return apperrors.NewInternal()
-	// }
-
-	return nil
+	// u is returned to the user, holding their image as stored
+	return resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, u)
 }
 
+// SetProfileImage processes an uploaded image into a square in every one of
+// model.ProfileImageSizes and stores each of them as a new object. The objects
+// of the user's previous image are only deleted once the new one is saved
 func (s *userService) SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*model.User, error) {
 	u, err := s.UserRepository.FindByID(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
 
-	objName, err := objNameFromURL(u.ImageURL)
+	imageFile, err := imageFileHeader.Open()
+	if err != nil {
+		log.Printf("failed to open image file: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+	defer imageFile.Close()
+
+	data, err := io.ReadAll(imageFile)
+	if err != nil {
+		log.Printf("failed to read image file: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return s.setProfileImage(ctx, u, data)
+}
+
+// NewImageUpload lets a user upload an image straight to storage, rather than
+// through this service. The object is only a candidate for their profile
+// image, named after them, until committed with CommitImageUpload
+func (s *userService) NewImageUpload(ctx context.Context, uid uuid.UUID, contentType string, size int64) (*model.ImageUpload, error) {
+	if size <= 0 || size > s.MaxImageBytes {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("size must be between 1 and %d bytes", s.MaxImageBytes))
+	}
+
+	uploadID, _ := uuid.NewRandom()
+	objName := fmt.Sprintf("%s%s", uploadPrefix(uid), uploadID)
+
+	upload, err := s.ImageRepository.SignUpload(ctx, objName, contentType, size, s.ImageUploadTTL)
 	if err != nil {
 		return nil, err
 	}
 
-	imageFile, err := imageFileHeader.Open()
+	upload.ExpiresAt = time.Now().Add(s.ImageUploadTTL)
+
+	return upload, nil
+}
+
+// CommitImageUpload makes an image the user uploaded with NewImageUpload their
+// profile image. The uploaded object is checked and processed the same as an
+// image sent to SetProfileImage, and is removed once done with
+func (s *userService) CommitImageUpload(ctx context.Context, uid uuid.UUID, objName string) (*model.User, error) {
+	if !strings.HasPrefix(objName, uploadPrefix(uid)) {
+		return nil, apperrors.NewBadRequest("objectName is not an upload of this user")
+	}
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
 	if err != nil {
-		log.Printf("failed to open image file: %v\n", err)
+		return nil, err
+	}
+
+	rc, err := s.ImageRepository.GetProfile(ctx, objName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the processed sizes are stored as new objects either way
+	defer func() {
+		if err := s.ImageRepository.DeleteProfile(ctx, objName); err != nil {
+			log.Printf("unable to delete uploaded image: %v: %v\n", objName, err)
+		}
+	}()
+
+	// storage is trusted to have enforced the size signed for
+	// the upload, but a reader can't be made to read forever
+	data, err := io.ReadAll(io.LimitReader(rc, s.MaxImageBytes+1))
+	rc.Close()
+	if err != nil {
+		log.Printf("failed to read uploaded image: %v: %v\n", objName, err)
 		return nil, apperrors.NewInternal()
 	}
 
-	// Upload user's image to imageRepository
-	// possibly receive updated image url
-	imageURL, err := s.ImageRepository.UpdateProfile(ctx, objName, imageFile)
+	if int64(len(data)) > s.MaxImageBytes {
+		recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "upload too large")
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("imageFile must be at most %d bytes", s.MaxImageBytes))
+	}
+
+	return s.setProfileImage(ctx, u, data)
+}
+
+// OpenImage opens a public image object for the route serving images in
+// place of the storage. Only the current and previous images of users are
+// served. Private images are only served through signed URLs, uploads are
+// unchecked until committed, and images held for review are hidden until
+// they are approved, so none of them are found. The caller must close the reader
+func (s *userService) OpenImage(ctx context.Context, objName string) (io.ReadCloser, error) {
+	// names are checked so only objects, never paths, are opened
+	if s.ImageURLTTL > 0 || objName == "" || strings.HasPrefix(objName, ".") || strings.ContainsAny(objName, "/\\") {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	// uploads keep the metadata processing strips until they are committed
+	if strings.HasPrefix(objName, uploadObjectPrefix) {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	found, err := s.UserRepository.HasImage(ctx, objName)
 	if err != nil {
-		log.Printf("unable to upload image to cloud provider: %v\n", err)
 		return nil, err
 	}
 
-	updatedUser, err := s.UserRepository.UpdateImage(ctx, u.UID, imageURL)
+	if !found {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	held, err := s.ImageReviewRepository.HoldsImage(ctx, objName)
 	if err != nil {
-		log.Printf("unable to update imageURL: %v\n", err)
+		return nil, err
+	}
+
+	if held {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	return s.ImageRepository.GetProfile(ctx, objName)
+}
+
+// setProfileImage processes an image into a square in every one of
+// model.ProfileImageSizes and makes the stored objects the user's image,
+// unless the image is rejected or held for review by moderation
+func (s *userService) setProfileImage(ctx context.Context, u *model.User, data []byte) (*model.User, error) {
+	uid := u.UID
+
+	versions, err := processProfileImage(data, model.ProfileImageSizes, s.MaxImageDimension)
+	if err == errImageTooLarge {
+		recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "image too large")
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("imageFile must be at most %d by %d pixels", s.MaxImageDimension, s.MaxImageDimension))
+	}
+	if err != nil {
+		log.Printf("failed to process image for uid: %v: %v\n", uid, err)
+		recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "invalid image")
+		return nil, apperrors.NewBadRequest("imageFile could not be read as an image")
+	}
+
+	// images moderated in the background are all held for review until they are
+	var result *model.ModerationResult
+	if !s.ModerateAsync {
+		result = s.moderate(ctx, uid, data)
+
+		if result.Decision == model.ModerationReject {
+			recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "rejected: "+result.Reason)
+			return nil, apperrors.NewBadRequest(fmt.Sprintf("imageFile was rejected: %s", result.Reason))
+		}
+	}
+
+	imageID, _ := uuid.NewRandom()
+	imageURLs := model.ImageURLs{}
+
+	// Upload every size of the user's image to imageRepository
+	for _, size := range model.ProfileImageSizes {
+		objName := fmt.Sprintf("%s-%d", imageID, size)
+
+		imageURL, err := s.ImageRepository.UpdateProfile(ctx, objName, newImageFile(versions[size]))
+		if err != nil {
+			log.Printf("unable to upload image to cloud provider: %v\n", err)
+			recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "upload failed")
+			s.deleteImages(ctx, imageURLs)
+			return nil, err
+		}
+
+		// private storage only keeps the key, as its URLs are signed when read
+		if s.ImageURLTTL > 0 {
+			imageURL = objName
+		}
+
+		imageURLs[size] = imageURL
+	}
+
+	if result == nil || result.Decision == model.ModerationQuarantine {
+		return s.holdImage(ctx, u, imageURLs, data, result)
+	}
+
+	history, expired := s.archiveImage(u.ImageHistory, u.ImageURLs)
+
+	updatedUser, err := s.replaceImage(ctx, u, imageURLs, history)
+	if err != nil {
+		log.Printf("unable to update imageURLs: %v\n", err)
+		recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "")
+		s.deleteImages(ctx, imageURLs)
+		return nil, err
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeSuccess, imageURLs[largestImageSize()])
+
+	// the user has their new image either way, so failing to remove
+	// images which fell out of their history only leaves unused objects behind
+	for _, imageURLs := range expired {
+		s.deleteImages(ctx, imageURLs)
+	}
+
+	if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, updatedUser); err != nil {
 		return nil, err
 	}
 
 	return updatedUser, nil
 }
 
+// uploadObjectPrefix starts the name of every object uploaded with NewImageUpload
+const uploadObjectPrefix = "upload-"
+
+// uploadPrefix starts the name of every object uploaded by a user
+// with NewImageUpload, which commits are checked against
+func uploadPrefix(uid uuid.UUID) string {
+	return fmt.Sprintf("%s%s-", uploadObjectPrefix, uid)
+}
+
+// ImageHistory lists the user's previous profile images, from the most
+// recently replaced, with URLs which can be loaded even when image storage is private
+func (s *userService) ImageHistory(ctx context.Context, uid uuid.UUID) (model.ImageHistory, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(model.ImageHistory, 0, len(u.ImageHistory))
+
+	for _, v := range u.ImageHistory {
+		imageURLs, err := signImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, v.ImageURLs)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, &model.ImageVersion{
+			ID:         v.ID,
+			ImageURLs:  imageURLs,
+			ReplacedAt: v.ReplacedAt,
+		})
+	}
+
+	return history, nil
+}
+
+// RevertProfileImage makes a previous image the user's profile image
+// again. The image it replaces takes its place at the front of the history
+func (s *userService) RevertProfileImage(ctx context.Context, uid uuid.UUID, versionID uuid.UUID) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *model.ImageVersion
+	history := model.ImageHistory{}
+
+	for _, v := range u.ImageHistory {
+		if v.ID == versionID {
+			target = v
+			continue
+		}
+
+		history = append(history, v)
+	}
+
+	if target == nil {
+		return nil, apperrors.NewNotFound("image version", versionID.String())
+	}
+
+	history, expired := s.archiveImage(history, u.ImageURLs)
+
+	updatedUser, err := s.replaceImage(ctx, u, target.ImageURLs, history)
+	if err != nil {
+		log.Printf("unable to revert image of uid: %v: %v\n", uid, err)
+		recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeFailure, "")
+		return nil, err
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventUpdateImage, model.AuditOutcomeSuccess, target.ImageURLs[largestImageSize()])
+
+	// only happens when the limit was lowered since the history was stored
+	for _, imageURLs := range expired {
+		s.deleteImages(ctx, imageURLs)
+	}
+
+	if err := resolveImageURLs(ctx, s.ImageRepository, s.ImageURLTTL, s.ImagePublicURL, s.BaseURL, updatedUser); err != nil {
+		return nil, err
+	}
+
+	return updatedUser, nil
+}
+
+// archiveImage puts an image being replaced at the front of the history,
+// returning the history cut down to ImageHistoryLimit and the images cut from it
+func (s *userService) archiveImage(history model.ImageHistory, replaced model.ImageURLs) (model.ImageHistory, []model.ImageURLs) {
+	archived := model.ImageHistory{}
+
+	if len(replaced) > 0 {
+		archived = append(archived, &model.ImageVersion{
+			ID:         uuid.New(),
+			ImageURLs:  replaced,
+			ReplacedAt: time.Now(),
+		})
+	}
+
+	archived = append(archived, history...)
+
+	expired := []model.ImageURLs{}
+
+	for len(archived) > s.ImageHistoryLimit {
+		expired = append(expired, archived[len(archived)-1].ImageURLs)
+		archived = archived[:len(archived)-1]
+	}
+
+	return archived, expired
+}
+
+// replaceImage stores the user's new profile image along with their history,
+// which is only written when it has changed
+func (s *userService) replaceImage(ctx context.Context, u *model.User, imageURLs model.ImageURLs, history model.ImageHistory) (*model.User, error) {
+	historyChanged := len(history) != len(u.ImageHistory)
+	for i := 0; !historyChanged && i < len(history); i++ {
+		historyChanged = history[i].ID != u.ImageHistory[i].ID
+	}
+
+	var updatedUser *model.User
+
+	err := withEvent(ctx, s.Transactor, s.OutboxRepository, model.EventImageChanged, func(ctx context.Context) (*model.User, error) {
+		var err error
+		updatedUser, err = s.UserRepository.UpdateImage(ctx, u.UID, imageURLs)
+		if err != nil || !historyChanged {
+			return updatedUser, err
+		}
+
+		if err := s.UserRepository.UpdateImageHistory(ctx, u.UID, history); err != nil {
+			return nil, err
+		}
+
+		updatedUser.ImageHistory = history
+
+		return updatedUser, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedUser, nil
+}
+
+// ClearProfileImage deletes every size of the user's profile image, along
+// with every previous image kept in their history
 func (s *userService) ClearProfileImage(ctx context.Context, uid uuid.UUID) error {
 	user, err := s.UserRepository.FindByID(ctx, uid)
 	if err != nil {
 		return nil
 	}
 
-	if user.ImageURL == "" {
+	if len(user.ImageURLs) == 0 && len(user.ImageHistory) == 0 {
 		return nil
 	}
 
-	objName, err := objNameFromURL(user.ImageURL)
+	err = deleteAllImages(ctx, s.ImageRepository, user)
+	if err == nil {
+		err = withEvent(ctx, s.Transactor, s.OutboxRepository, model.EventImageChanged, func(ctx context.Context) (*model.User, error) {
+			u, err := s.UserRepository.UpdateImage(ctx, uid, model.ImageURLs{})
+			if err != nil || len(user.ImageHistory) == 0 {
+				return u, err
+			}
+
+			return u, s.UserRepository.UpdateImageHistory(ctx, uid, model.ImageHistory{})
+		})
+	}
+
+	recordAudit(ctx, s.AuditEventRepository, &uid, &uid, model.AuditEventDeleteImage, auditOutcome(err), user.ImageURLs[largestImageSize()])
+
+	return err
+}
+
+// deleteImages removes the objects of an image, logging rather than
+// returning failures for callers which can carry on without them
+func (s *userService) deleteImages(ctx context.Context, imageURLs model.ImageURLs) {
+	if err := deleteImages(ctx, s.ImageRepository, imageURLs); err != nil {
+		log.Printf("unable to delete image objects: %v\n", err)
+	}
+}
+
+// Delete re-authenticates the user with their password and soft deletes
+// their account. Once soft deleted the user can no longer be found, so the
+// account is gone as far as they are concerned. Their refresh tokens and
+// profile image are removed straight away, and anything that fails to be
+// removed is retried by PurgeDeleted before the row is permanently deleted
+func (s *userService) Delete(ctx context.Context, uid uuid.UUID, password string) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
 	if err != nil {
 		return err
 	}
 
-	err = s.ImageRepository.DeleteProfile(ctx, objName)
+	match, err := comparePasswords(u.Password, password)
 	if err != nil {
-		return err
+		return apperrors.NewInternal()
 	}
 
-	_, err = s.UserRepository.UpdateImage(ctx, uid, "")
+	if !match {
+		return apperrors.NewAuthorization("Invalid password")
+	}
+
+	err = withEvent(ctx, s.Transactor, s.OutboxRepository, model.EventUserDeleted, func(ctx context.Context) (*model.User, error) {
+		return u, s.UserRepository.SoftDelete(ctx, uid, time.Now().Add(s.DeletionGracePeriod))
+	})
+
 	if err != nil {
 		return err
 	}
 
+	if err := s.removeUserData(ctx, u); err != nil {
+		log.Printf("failed to remove data of deleted uid: %v, will retry on purge: %v\n", uid, err)
+	}
+
 	return nil
 }
 
-func objNameFromURL(imageURL string) (string, error) {
-	// if user does not have imageURL, create one
-	// else extract last part of url to get cloud storage object name
-	if imageURL == "" {
-		objID, _ := uuid.NewRandom()
-		return objID.String(), nil
+// PurgeDeleted permanently removes a batch of soft deleted
+// users whose grace period has ended, returning how many were removed
+func (s *userService) PurgeDeleted(ctx context.Context) (int, error) {
+	users, err := s.UserRepository.FindPurgeable(ctx, time.Now(), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+
+	for _, u := range users {
+		// leave the row in place so the next purge can retry
+		if err := s.removeUserData(ctx, u); err != nil {
+			log.Printf("failed to remove data of deleted uid: %v: %v\n", u.UID, err)
+			continue
+		}
+
+		if err := s.UserRepository.Delete(ctx, u.UID); err != nil {
+			log.Printf("failed to purge deleted uid: %v: %v\n", u.UID, err)
+			continue
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// removeUserData revokes all of the user's refresh tokens and deletes their
+// profile image along with their previous images
+func (s *userService) removeUserData(ctx context.Context, u *model.User) error {
+	if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, u.UID.String()); err != nil {
+		return err
 	}
 
+	return deleteAllImages(ctx, s.ImageRepository, u)
+}
+
+// objNameFromURL extracts the last part of an image's url
+// to get its storage object name
+func objNameFromURL(imageURL string) (string, error) {
 	// split off last part of URL, which is the image's storage object ID
 	urlPath, err := url.Parse(imageURL)
 	if err != nil {
@@ -195,3 +693,68 @@ func objNameFromURL(imageURL string) (string, error) {
 	// then get "base", the last part
 	return path.Base(urlPath.Path), nil
 }
+
+// imageObjectNames returns the storage object name of every size of an image,
+// from smallest to largest. Names are only listed once as images uploaded
+// before sizes were introduced use the same object for every size
+func imageObjectNames(imageURLs model.ImageURLs) ([]string, error) {
+	sizes := make([]int, 0, len(imageURLs))
+	for size := range imageURLs {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	names := make([]string, 0, len(sizes))
+	seen := map[string]bool{}
+
+	for _, size := range sizes {
+		objName, err := objNameFromURL(imageURLs[size])
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[objName] {
+			seen[objName] = true
+			names = append(names, objName)
+		}
+	}
+
+	return names, nil
+}
+
+// deleteImages removes the storage objects of every size of an image
+func deleteImages(ctx context.Context, r model.ImageRepository, imageURLs model.ImageURLs) error {
+	objNames, err := imageObjectNames(imageURLs)
+	if err != nil {
+		return err
+	}
+
+	for _, objName := range objNames {
+		if err := r.DeleteProfile(ctx, objName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteAllImages removes the storage objects of the user's profile image
+// and of every previous image kept in their history
+func deleteAllImages(ctx context.Context, r model.ImageRepository, u *model.User) error {
+	if err := deleteImages(ctx, r, u.ImageURLs); err != nil {
+		return err
+	}
+
+	for _, v := range u.ImageHistory {
+		if err := deleteImages(ctx, r, v.ImageURLs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// largestImageSize is the size of profile images recorded in the audit log
+func largestImageSize() int {
+	return model.ProfileImageSizes[len(model.ProfileImageSizes)-1]
+}
@@ -1,9 +1,15 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ndenisj/go_mem/account/model"
@@ -64,6 +70,138 @@ func TestGet(t *testing.T) {
 	)
 }
 
+func TestProfile(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Public storage", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUser := &model.User{
+			UID:       uid,
+			ImageURLs: model.ImageURLs{64: "https://storage.googleapis.com/bucket/image-64"},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		u, err := us.Profile(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageURLs{64: "https://storage.googleapis.com/bucket/image-64"}, u.ImageURLs)
+		mockImageRepository.AssertNotCalled(t, "SignURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Public URL", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+			ImagePublicURL:  "https://cdn.example.com/images/",
+		})
+
+		// stored images are linked to under the public URL whichever storage they were put in
+		mockUser := &model.User{
+			UID: uid,
+			ImageURLs: model.ImageURLs{
+				64:  "https://storage.googleapis.com/bucket/image-64",
+				128: "https://bucket.s3.us-east-1.amazonaws.com/image-128",
+			},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		u, err := us.Profile(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageURLs{
+			64:  "https://cdn.example.com/images/image-64",
+			128: "https://cdn.example.com/images/image-128",
+		}, u.ImageURLs)
+		mockImageRepository.AssertNotCalled(t, "SignURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Private storage", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+			ImageURLTTL:     10 * time.Minute,
+		})
+
+		// 128 was stored while the storage was still public
+		mockUser := &model.User{
+			UID: uid,
+			ImageURLs: model.ImageURLs{
+				64:  "image-64",
+				128: "https://storage.googleapis.com/bucket/image-128",
+			},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("SignURL", mock.Anything, "image-64", 10*time.Minute).Return("https://signed/image-64?sig", nil)
+		mockImageRepository.On("SignURL", mock.Anything, "image-128", 10*time.Minute).Return("https://signed/image-128?sig", nil)
+
+		u, err := us.Profile(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageURLs{
+			64:  "https://signed/image-64?sig",
+			128: "https://signed/image-128?sig",
+		}, u.ImageURLs)
+	})
+
+	t.Run("No image", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+			ImageURLTTL:     10 * time.Minute,
+			BaseURL:         "/api/account",
+		})
+
+		mockUser := &model.User{
+			UID: uid,
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		u, err := us.Profile(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageURLs{
+			64:  fmt.Sprintf("/api/account/avatars/%s?size=64", uid),
+			128: fmt.Sprintf("/api/account/avatars/%s?size=128", uid),
+			512: fmt.Sprintf("/api/account/avatars/%s?size=512", uid),
+		}, u.ImageURLs)
+		mockImageRepository.AssertNotCalled(t, "SignURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Signing error", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+			ImageURLTTL:     10 * time.Minute,
+		})
+
+		mockUser := &model.User{
+			UID:       uid,
+			ImageURLs: model.ImageURLs{64: "image-64"},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("SignURL", mock.Anything, "image-64", 10*time.Minute).Return(nil, apperrors.NewInternal())
+
+		u, err := us.Profile(context.TODO(), uid)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
+
 func TestSignup(t *testing.T) {
 	t.Run(
 		"Success",
@@ -76,8 +214,20 @@ func TestSignup(t *testing.T) {
 			}
 
 			mockUserRepository := new(mocks.MockUserRepository)
+			mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+			mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockOutboxRepository := new(mocks.MockOutboxRepository)
+			mockOutboxRepository.On("Add", mock.Anything, mock.MatchedBy(func(e *model.UserEvent) bool {
+				return e.Type == model.EventUserCreated && e.UID == uid && e.User == mockUser
+			})).Return(nil)
+			mockTransactor := new(mocks.MockTransactor)
+			mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
 			us := NewUserService(&USConfig{
-				UserRepository: mockUserRepository,
+				UserRepository:       mockUserRepository,
+				AuditEventRepository: mockAuditEventRepository,
+				OutboxRepository:     mockOutboxRepository,
+				Transactor:           mockTransactor,
 			})
 
 			// we can use Run method modify the user when the create method is called
@@ -97,6 +247,8 @@ func TestSignup(t *testing.T) {
 			assert.Equal(t, uid, mockUser.UID)
 
 			mockUserRepository.AssertExpectations(t)
+			mockOutboxRepository.AssertExpectations(t)
+			mockTransactor.AssertExpectations(t)
 		},
 	)
 
@@ -109,8 +261,17 @@ func TestSignup(t *testing.T) {
 			}
 
 			mockUserRepository := new(mocks.MockUserRepository)
+			mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+			mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockOutboxRepository := new(mocks.MockOutboxRepository)
+			mockTransactor := new(mocks.MockTransactor)
+			mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
 			us := NewUserService(&USConfig{
-				UserRepository: mockUserRepository,
+				UserRepository:       mockUserRepository,
+				AuditEventRepository: mockAuditEventRepository,
+				OutboxRepository:     mockOutboxRepository,
+				Transactor:           mockTransactor,
 			})
 
 			mockErr := apperrors.NewConflict("email", mockUser.Email)
@@ -122,14 +283,215 @@ func TestSignup(t *testing.T) {
 
 			assert.EqualError(t, err, mockErr.Error())
 			mockUserRepository.AssertExpectations(t)
+			mockOutboxRepository.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
 		},
 	)
+	t.Run("Outbox error fails signup", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:    "john@doe.com",
+			Password: "12err434ssss",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return e.Outcome == model.AuditOutcomeFailure
+		})).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+			OutboxRepository:     mockOutboxRepository,
+			Transactor:           mockTransactor,
+		})
+
+		mockUserRepository.On("Create", mock.Anything, mockUser).Return(nil)
+
+		err := us.Signup(context.TODO(), mockUser)
+
+		assert.Error(t, err)
+		mockAuditEventRepository.AssertExpectations(t)
+		mockOutboxRepository.AssertExpectations(t)
+	})
+}
+
+func TestSignin(t *testing.T) {
+	password := "pwcorrect123"
+	hashed, _ := hashPassword(password)
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		uFetched := &model.User{
+			UID:      uid,
+			Email:    "bob@bob.com",
+			Password: hashed,
+			Status:   model.UserStatusActive,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, uFetched.Email).Return(uFetched, nil)
+
+		u := &model.User{Email: uFetched.Email, Password: password}
+		err := us.Signin(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		uFetched := &model.User{
+			Email:    "bob@bob.com",
+			Password: hashed,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, uFetched.Email).Return(uFetched, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: uFetched.Email, Password: "pwincorrect123"})
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Disabled account", func(t *testing.T) {
+		uFetched := &model.User{
+			Email:    "bob@bob.com",
+			Password: hashed,
+			Status:   model.UserStatusDisabled,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, uFetched.Email).Return(uFetched, nil)
+
+		u := &model.User{Email: uFetched.Email, Password: password}
+		err := us.Signin(context.TODO(), u)
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.AccountInactive, err.(*apperrors.Error).Type)
+		assert.Equal(t, uuid.Nil, u.UID)
+	})
+
+	t.Run("Expired suspension", func(t *testing.T) {
+		ended := time.Now().Add(-time.Minute)
+		uFetched := &model.User{
+			Email:          "bob@bob.com",
+			Password:       hashed,
+			Status:         model.UserStatusSuspended,
+			SuspendedUntil: &ended,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, uFetched.Email).Return(uFetched, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: uFetched.Email, Password: password})
+
+		assert.NoError(t, err)
+	})
+	t.Run("Audits failures with client info", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		uFetched := &model.User{
+			UID:      uid,
+			Email:    "bob@bob.com",
+			Password: hashed,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return *e.UID == uid &&
+				e.Event == model.AuditEventSignin &&
+				e.Outcome == model.AuditOutcomeFailure &&
+				e.IP == "10.0.0.1" &&
+				e.UserAgent == "test-agent"
+		})).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, uFetched.Email).Return(uFetched, nil)
+
+		ctx := model.WithClientInfo(context.TODO(), model.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"})
+		err := us.Signin(ctx, &model.User{Email: uFetched.Email, Password: "pwincorrect123"})
+
+		assert.Error(t, err)
+		mockAuditEventRepository.AssertExpectations(t)
+	})
+
+	t.Run("Audits unknown email without uid", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return e.UID == nil &&
+				e.Event == model.AuditEventSignin &&
+				e.Outcome == model.AuditOutcomeFailure
+		})).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			AuditEventRepository: mockAuditEventRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperrors.NewNotFound("email", "nobody@bob.com"))
+
+		err := us.Signin(context.TODO(), &model.User{Email: "nobody@bob.com", Password: password})
+
+		assert.Error(t, err)
+		mockAuditEventRepository.AssertExpectations(t)
+	})
 }
 
 func TestUpdateDetails(t *testing.T) {
 	mockUserRepository := new(mocks.MockUserRepository)
+	mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+	mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockOutboxRepository := new(mocks.MockOutboxRepository)
+	mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+	mockTransactor := new(mocks.MockTransactor)
+	mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
 	us := NewUserService(&USConfig{
-		UserRepository: mockUserRepository,
+		UserRepository:       mockUserRepository,
+		AuditEventRepository: mockAuditEventRepository,
+		OutboxRepository:     mockOutboxRepository,
+		Transactor:           mockTransactor,
 	})
 
 	t.Run("Success", func(t *testing.T) {
@@ -147,6 +509,9 @@ func TestUpdateDetails(t *testing.T) {
 			mockUser,
 		}
 
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, Email: "new@bob.com", EmailVerified: true}, nil)
 		mockUserRepository.
 			On("Update", mockArgs...).Return(nil)
 
@@ -154,7 +519,38 @@ func TestUpdateDetails(t *testing.T) {
 		err := us.UpdateDetails(ctx, mockUser)
 
 		assert.NoError(t, err)
+		assert.True(t, mockUser.EmailVerified)
 		mockUserRepository.AssertCalled(t, "Update", mockArgs...)
+		mockOutboxRepository.AssertCalled(t, "Add", mock.Anything, mock.MatchedBy(func(e *model.UserEvent) bool {
+			return e.Type == model.EventUserUpdated && e.UID == uid
+		}))
+		mockOutboxRepository.AssertNotCalled(t, "Add", mock.Anything, mock.MatchedBy(func(e *model.UserEvent) bool {
+			return e.Type == model.EventEmailChanged && e.UID == uid
+		}))
+	})
+
+	t.Run("Email change", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:   uid,
+			Email: "changed@bob.com",
+		}
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, Email: "old@bob.com", EmailVerified: true}, nil)
+		mockUserRepository.
+			On("Update", mock.AnythingOfType("*context.emptyCtx"), mockUser).Return(nil)
+
+		err := us.UpdateDetails(context.TODO(), mockUser)
+
+		assert.NoError(t, err)
+		// nobody has verified the new address yet
+		assert.False(t, mockUser.EmailVerified)
+		mockOutboxRepository.AssertCalled(t, "Add", mock.Anything, mock.MatchedBy(func(e *model.UserEvent) bool {
+			return e.Type == model.EventEmailChanged && e.UID == uid && e.User.Email == "changed@bob.com"
+		}))
 	})
 
 	t.Run("Failure", func(t *testing.T) {
@@ -171,6 +567,9 @@ func TestUpdateDetails(t *testing.T) {
 
 		mockError := apperrors.NewInternal()
 
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid}, nil)
 		mockUserRepository.
 			On("Update", mockArgs...).Return(mockError)
 
@@ -187,18 +586,49 @@ func TestUpdateDetails(t *testing.T) {
 }
 
 func TestSetProfileImage(t *testing.T) {
-	mockUserRepository := new(mocks.MockUserRepository)
-	mockImageRepository := new(mocks.MockImageRepository)
+	// each case needs its own UserService and repositories
+	// because testify has no way to overwrite a mock's
+	// "On" call.
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockImageRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
 
-	us := NewUserService(&USConfig{
-		UserRepository:  mockUserRepository,
-		ImageRepository: mockImageRepository,
-	})
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			ImageRepository:      mockImageRepository,
+			AuditEventRepository: mockAuditEventRepository,
+			OutboxRepository:     mockOutboxRepository,
+			Transactor:           mockTransactor,
+		})
+
+		return us, mockUserRepository, mockImageRepository
+	}
+
+	// objSize matches the object name of one size of an uploaded image
+	objSize := func(size int) interface{} {
+		return mock.MatchedBy(func(objName string) bool {
+			return strings.HasSuffix(objName, fmt.Sprintf("-%d", size))
+		})
+	}
+
+	imageURLs := model.ImageURLs{
+		64:  "http://imageurl.com/newimage-64",
+		128: "http://imageurl.com/newimage-128",
+		512: "http://imageurl.com/newimage-512",
+	}
 
 	t.Run("Successful new image", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
 		uid, _ := uuid.NewRandom()
 
-		// does not have have imageURL
+		// does not have have imageURLs
 		mockUser := &model.User{
 			UID:     uid,
 			Email:   "new@bob.com",
@@ -215,32 +645,25 @@ func TestSetProfileImage(t *testing.T) {
 		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
 		defer multipartImageFixture.Close()
 		imageFileHeader := multipartImageFixture.GetFormFile()
-		imageFile, _ := imageFileHeader.Open()
 
-		updateProfileArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
-			mock.AnythingOfType("string"),
-			imageFile,
+		for size, imageURL := range imageURLs {
+			mockImageRepository.
+				On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), objSize(size), mock.Anything).
+				Return(imageURL, nil)
 		}
 
-		imageURL := "http://imageurl.com/jdfkj34kljl"
-
-		mockImageRepository.
-			On("UpdateProfile", updateProfileArgs...).
-			Return(imageURL, nil)
-
 		updateImageArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			mockUser.UID,
-			imageURL,
+			imageURLs,
 		}
 
 		mockUpdatedUser := &model.User{
-			UID:      uid,
-			Email:    "new@bob.com",
-			Website:  "https://jacobgoodwin.me",
-			Name:     "A New Bob!",
-			ImageURL: imageURL,
+			UID:       uid,
+			Email:     "new@bob.com",
+			Website:   "https://jacobgoodwin.me",
+			Name:      "A New Bob!",
+			ImageURLs: imageURLs,
 		}
 
 		mockUserRepository.
@@ -254,21 +677,26 @@ func TestSetProfileImage(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, mockUpdatedUser, updatedUser)
 		mockUserRepository.AssertCalled(t, "FindByID", findByIDArgs...)
-		mockImageRepository.AssertCalled(t, "UpdateProfile", updateProfileArgs...)
+		mockImageRepository.AssertNumberOfCalls(t, "UpdateProfile", len(model.ProfileImageSizes))
 		mockUserRepository.AssertCalled(t, "UpdateImage", updateImageArgs...)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
 	})
 
 	t.Run("Successful update image", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
 		uid, _ := uuid.NewRandom()
-		imageURL := "http://imageurl.com/jdfkj34kljl"
 
-		// has imageURL
+		// has imageURLs
 		mockUser := &model.User{
-			UID:      uid,
-			Email:    "new@bob.com",
-			Website:  "https://jacobgoodwin.me",
-			Name:     "A New Bob!",
-			ImageURL: imageURL,
+			UID:     uid,
+			Email:   "new@bob.com",
+			Website: "https://jacobgoodwin.me",
+			Name:    "A New Bob!",
+			ImageURLs: model.ImageURLs{
+				64:  "http://imageurl.com/oldimage-64",
+				128: "http://imageurl.com/oldimage-128",
+				512: "http://imageurl.com/oldimage-512",
+			},
 		}
 
 		findByIDArgs := mock.Arguments{
@@ -280,30 +708,26 @@ func TestSetProfileImage(t *testing.T) {
 		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
 		defer multipartImageFixture.Close()
 		imageFileHeader := multipartImageFixture.GetFormFile()
-		imageFile, _ := imageFileHeader.Open()
 
-		updateProfileArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
-			mock.AnythingOfType("string"),
-			imageFile,
+		for size, imageURL := range imageURLs {
+			mockImageRepository.
+				On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), objSize(size), mock.Anything).
+				Return(imageURL, nil)
 		}
-
-		mockImageRepository.
-			On("UpdateProfile", updateProfileArgs...).
-			Return(imageURL, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
 
 		updateImageArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			mockUser.UID,
-			imageURL,
+			imageURLs,
 		}
 
 		mockUpdatedUser := &model.User{
-			UID:      uid,
-			Email:    "new@bob.com",
-			Website:  "https://jacobgoodwin.me",
-			Name:     "A New Bob!",
-			ImageURL: imageURL,
+			UID:       uid,
+			Email:     "new@bob.com",
+			Website:   "https://jacobgoodwin.me",
+			Name:      "A New Bob!",
+			ImageURLs: imageURLs,
 		}
 
 		mockUserRepository.
@@ -317,11 +741,95 @@ func TestSetProfileImage(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, mockUpdatedUser, updatedUser)
 		mockUserRepository.AssertCalled(t, "FindByID", findByIDArgs...)
-		mockImageRepository.AssertCalled(t, "UpdateProfile", updateProfileArgs...)
+		mockImageRepository.AssertNumberOfCalls(t, "UpdateProfile", len(model.ProfileImageSizes))
 		mockUserRepository.AssertCalled(t, "UpdateImage", updateImageArgs...)
+
+		// the previous image is removed once replaced
+		mockImageRepository.AssertNumberOfCalls(t, "DeleteProfile", 3)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, "oldimage-64")
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, "oldimage-128")
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, "oldimage-512")
+	})
+
+	t.Run("Private storage", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			ImageRepository:      mockImageRepository,
+			AuditEventRepository: mockAuditEventRepository,
+			OutboxRepository:     mockOutboxRepository,
+			Transactor:           mockTransactor,
+			ImageURLTTL:          time.Minute,
+		})
+
+		uid, _ := uuid.NewRandom()
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+
+		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer multipartImageFixture.Close()
+		imageFileHeader := multipartImageFixture.GetFormFile()
+
+		for size, imageURL := range imageURLs {
+			mockImageRepository.
+				On("UpdateProfile", mock.Anything, objSize(size), mock.Anything).
+				Return(imageURL, nil)
+		}
+		mockImageRepository.On("SignURL", mock.Anything, mock.Anything, time.Minute).Return("https://signed", nil)
+
+		// only the object keys are stored
+		var storedURLs model.ImageURLs
+		mockUserRepository.
+			On("UpdateImage", mock.Anything, uid, mock.AnythingOfType("model.ImageURLs")).
+			Run(func(args mock.Arguments) {
+				storedURLs = args.Get(2).(model.ImageURLs)
+			}).
+			Return(&model.User{UID: uid, ImageURLs: model.ImageURLs{64: "new-64", 128: "new-128", 512: "new-512"}}, nil)
+
+		updatedUser, err := us.SetProfileImage(context.TODO(), uid, imageFileHeader)
+
+		assert.NoError(t, err)
+		assert.Len(t, storedURLs, len(model.ProfileImageSizes))
+		for size, objName := range storedURLs {
+			assert.False(t, strings.Contains(objName, "/"))
+			assert.True(t, strings.HasSuffix(objName, fmt.Sprintf("-%d", size)))
+		}
+
+		// the user is given signed URLs
+		assert.Equal(t, model.ImageURLs{64: "https://signed", 128: "https://signed", 512: "https://signed"}, updatedUser.ImageURLs)
+	})
+
+	t.Run("Invalid image", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:   uid,
+			Email: "new@bob.com",
+		}
+		mockUserRepository.On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).Return(mockUser, nil)
+
+		multipartFileFixture := fixture.NewMultipartFile("image.png", "image/png", []byte("definitely not an image"))
+		defer multipartFileFixture.Close()
+		imageFileHeader := multipartFileFixture.GetFormFile()
+
+		updatedUser, err := us.SetProfileImage(context.TODO(), uid, imageFileHeader)
+
+		assert.Nil(t, updatedUser)
+		assert.Equal(t, apperrors.NewBadRequest("imageFile could not be read as an image"), err)
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("UserRepository FindByID Error", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
 		uid, _ := uuid.NewRandom()
 
 		findByIDArgs := mock.Arguments{
@@ -342,32 +850,23 @@ func TestSetProfileImage(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, updatedUser)
 		mockUserRepository.AssertCalled(t, "FindByID", findByIDArgs...)
-		mockImageRepository.AssertNotCalled(t, "UpdateProfile")
-		mockUserRepository.AssertNotCalled(t, "UpdateImage")
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("ImageRepository Error", func(t *testing.T) {
-		// need to create a new UserService and repository
-		// because testify has no way to overwrite a mock's
-		// "On" call.
-		mockUserRepository := new(mocks.MockUserRepository)
-		mockImageRepository := new(mocks.MockImageRepository)
-
-		us := NewUserService(&USConfig{
-			UserRepository:  mockUserRepository,
-			ImageRepository: mockImageRepository,
-		})
-
+		us, mockUserRepository, mockImageRepository := newService()
 		uid, _ := uuid.NewRandom()
-		imageURL := "http://imageurl.com/jdfkj34kljl"
 
-		// has imageURL
+		// has imageURLs
 		mockUser := &model.User{
-			UID:      uid,
-			Email:    "new@bob.com",
-			Website:  "https://jacobgoodwin.me",
-			Name:     "A New Bob!",
-			ImageURL: imageURL,
+			UID:     uid,
+			Email:   "new@bob.com",
+			Website: "https://jacobgoodwin.me",
+			Name:    "A New Bob!",
+			ImageURLs: model.ImageURLs{
+				512: "http://imageurl.com/oldimage",
+			},
 		}
 
 		findByIDArgs := mock.Arguments{
@@ -379,40 +878,44 @@ func TestSetProfileImage(t *testing.T) {
 		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
 		defer multipartImageFixture.Close()
 		imageFileHeader := multipartImageFixture.GetFormFile()
-		imageFile, _ := imageFileHeader.Open()
-
-		updateProfileArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
-			mock.AnythingOfType("string"),
-			imageFile,
-		}
 
+		// sizes are uploaded from smallest to largest, so the
+		// smallest is already stored when the next one fails
 		mockError := apperrors.NewInternal()
 		mockImageRepository.
-			On("UpdateProfile", updateProfileArgs...).
+			On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), objSize(64), mock.Anything).
+			Return(imageURLs[64], nil)
+		mockImageRepository.
+			On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), objSize(128), mock.Anything).
 			Return(nil, mockError)
+		mockImageRepository.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
 
 		ctx := context.TODO()
 		updatedUser, err := us.SetProfileImage(ctx, uid, imageFileHeader)
 
 		assert.Nil(t, updatedUser)
-		assert.Error(t, err)
+		assert.Equal(t, mockError, err)
 		mockUserRepository.AssertCalled(t, "FindByID", findByIDArgs...)
-		mockImageRepository.AssertCalled(t, "UpdateProfile", updateProfileArgs...)
-		mockUserRepository.AssertNotCalled(t, "UpdateImage")
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+
+		// only the new upload is cleaned up, the user keeps their old image
+		mockImageRepository.AssertNumberOfCalls(t, "DeleteProfile", 1)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, "newimage-64")
 	})
 
 	t.Run("UserRepository UpdateImage Error", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
 		uid, _ := uuid.NewRandom()
-		imageURL := "http://imageurl.com/jdfkj34kljl"
 
-		// has imageURL
+		// has imageURLs
 		mockUser := &model.User{
-			UID:      uid,
-			Email:    "new@bob.com",
-			Website:  "https://jacobgoodwin.me",
-			Name:     "A New Bob!",
-			ImageURL: imageURL,
+			UID:     uid,
+			Email:   "new@bob.com",
+			Website: "https://jacobgoodwin.me",
+			Name:    "A New Bob!",
+			ImageURLs: model.ImageURLs{
+				512: "http://imageurl.com/oldimage",
+			},
 		}
 
 		findByIDArgs := mock.Arguments{
@@ -424,22 +927,18 @@ func TestSetProfileImage(t *testing.T) {
 		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
 		defer multipartImageFixture.Close()
 		imageFileHeader := multipartImageFixture.GetFormFile()
-		imageFile, _ := imageFileHeader.Open()
 
-		updateProfileArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
-			mock.AnythingOfType("string"),
-			imageFile,
+		for size, imageURL := range imageURLs {
+			mockImageRepository.
+				On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), objSize(size), mock.Anything).
+				Return(imageURL, nil)
 		}
-
-		mockImageRepository.
-			On("UpdateProfile", updateProfileArgs...).
-			Return(imageURL, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
 
 		updateImageArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			mockUser.UID,
-			imageURL,
+			imageURLs,
 		}
 
 		mockError := apperrors.NewInternal()
@@ -453,8 +952,646 @@ func TestSetProfileImage(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, updatedUser)
-		mockImageRepository.AssertCalled(t, "UpdateProfile", updateProfileArgs...)
+		mockImageRepository.AssertNumberOfCalls(t, "UpdateProfile", len(model.ProfileImageSizes))
 		mockUserRepository.AssertCalled(t, "UpdateImage", updateImageArgs...)
+
+		// every new size is removed again and the old image is kept
+		mockImageRepository.AssertNumberOfCalls(t, "DeleteProfile", 3)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, "oldimage")
+	})
+}
+
+func TestOpenImage(t *testing.T) {
+	t.Run("Public storage", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("HasImage", mock.Anything, "image-64").Return(true, nil)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockImageReviewRepository := new(mocks.MockImageReviewRepository)
+		mockImageReviewRepository.On("HoldsImage", mock.Anything, "image-64").Return(false, nil)
+		us := NewUserService(&USConfig{
+			UserRepository:        mockUserRepository,
+			ImageRepository:       mockImageRepository,
+			ImageReviewRepository: mockImageReviewRepository,
+		})
+
+		rc := io.NopCloser(strings.NewReader("image"))
+		mockImageRepository.On("GetProfile", mock.Anything, "image-64").Return(rc, nil)
+
+		got, err := us.OpenImage(context.TODO(), "image-64")
+
+		assert.NoError(t, err)
+		assert.Equal(t, rc, got)
+	})
+
+	t.Run("No user's image", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("HasImage", mock.Anything, "orphan-64").Return(false, nil)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		got, err := us.OpenImage(context.TODO(), "orphan-64")
+
+		assert.Nil(t, got)
+		assert.Equal(t, apperrors.NewNotFound("image", "orphan-64"), err)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Uncommitted upload", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		objName := fmt.Sprintf("upload-%s-%s", uuid.New(), uuid.New())
+		got, err := us.OpenImage(context.TODO(), objName)
+
+		assert.Nil(t, got)
+		assert.Equal(t, apperrors.NewNotFound("image", objName), err)
+		mockUserRepository.AssertNotCalled(t, "HasImage", mock.Anything, mock.Anything)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Held for review", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("HasImage", mock.Anything, "held-64").Return(true, nil)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockImageReviewRepository := new(mocks.MockImageReviewRepository)
+		mockImageReviewRepository.On("HoldsImage", mock.Anything, "held-64").Return(true, nil)
+		us := NewUserService(&USConfig{
+			UserRepository:        mockUserRepository,
+			ImageRepository:       mockImageRepository,
+			ImageReviewRepository: mockImageReviewRepository,
+		})
+
+		got, err := us.OpenImage(context.TODO(), "held-64")
+
+		assert.Nil(t, got)
+		assert.Equal(t, apperrors.NewNotFound("image", "held-64"), err)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Private storage", func(t *testing.T) {
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			ImageRepository: mockImageRepository,
+			ImageURLTTL:     time.Minute,
+		})
+
+		got, err := us.OpenImage(context.TODO(), "image-64")
+
+		assert.Nil(t, got)
+		assert.Equal(t, apperrors.NewNotFound("image", "image-64"), err)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not an object name", func(t *testing.T) {
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			ImageRepository: mockImageRepository,
+		})
+
+		for _, objName := range []string{"", "..", ".hidden", "dir/image-64", "dir\\image-64"} {
+			got, err := us.OpenImage(context.TODO(), objName)
+
+			assert.Nil(t, got)
+			assert.Equal(t, apperrors.NewNotFound("image", objName), err)
+		}
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
 	})
+}
+
+func TestNewImageUpload(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			ImageRepository: mockImageRepository,
+			ImageUploadTTL:  5 * time.Minute,
+			MaxImageBytes:   1024,
+		})
+
+		objName := mock.MatchedBy(func(objName string) bool {
+			return strings.HasPrefix(objName, fmt.Sprintf("upload-%s-", uid))
+		})
+		mockImageRepository.
+			On("SignUpload", mock.Anything, objName, "image/png", int64(1024), 5*time.Minute).
+			Return(&model.ImageUpload{URL: "https://storage.test/upload"}, nil)
+
+		upload, err := us.NewImageUpload(context.TODO(), uid, "image/png", 1024)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://storage.test/upload", upload.URL)
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), upload.ExpiresAt, time.Second)
+		mockImageRepository.AssertExpectations(t)
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			ImageRepository: mockImageRepository,
+			MaxImageBytes:   1024,
+		})
+
+		upload, err := us.NewImageUpload(context.TODO(), uid, "image/png", 1025)
+
+		assert.Nil(t, upload)
+		assert.Equal(t, apperrors.NewBadRequest("size must be between 1 and 1024 bytes"), err)
+		mockImageRepository.AssertNotCalled(t, "SignUpload", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCommitImageUpload(t *testing.T) {
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockImageRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			ImageRepository:      mockImageRepository,
+			AuditEventRepository: mockAuditEventRepository,
+			OutboxRepository:     mockOutboxRepository,
+			Transactor:           mockTransactor,
+			MaxImageBytes:        1024,
+		})
+
+		return us, mockUserRepository, mockImageRepository
+	}
+
+	uid, _ := uuid.NewRandom()
+	upload := fmt.Sprintf("upload-%s-1234", uid)
+
+	pngBuf := &bytes.Buffer{}
+	png.Encode(pngBuf, image.NewNRGBA(image.Rect(0, 0, 10, 10)))
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockImageRepository.On("GetProfile", mock.Anything, upload).Return(io.NopCloser(bytes.NewReader(pngBuf.Bytes())), nil)
+		mockImageRepository.On("UpdateProfile", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return("https://imageurl.com/image", nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, upload).Return(nil)
+
+		updatedUser := &model.User{UID: uid, ImageURLs: model.ImageURLs{64: "https://imageurl.com/image"}}
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, mock.AnythingOfType("model.ImageURLs")).Return(updatedUser, nil)
+
+		u, err := us.CommitImageUpload(context.TODO(), uid, upload)
+
+		assert.NoError(t, err)
+		assert.Equal(t, updatedUser, u)
+
+		// every size is stored as a new object, and the upload is removed
+		mockImageRepository.AssertNumberOfCalls(t, "UpdateProfile", len(model.ProfileImageSizes))
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, upload)
+	})
+
+	t.Run("Upload of another user", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+
+		otherUID, _ := uuid.NewRandom()
+
+		u, err := us.CommitImageUpload(context.TODO(), uid, fmt.Sprintf("upload-%s-1234", otherUID))
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewBadRequest("objectName is not an upload of this user"), err)
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not uploaded", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockImageRepository.On("GetProfile", mock.Anything, upload).Return(nil, apperrors.NewNotFound("image", upload))
+
+		u, err := us.CommitImageUpload(context.TODO(), uid, upload)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewNotFound("image", upload), err)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockImageRepository.On("GetProfile", mock.Anything, upload).Return(io.NopCloser(bytes.NewReader(make([]byte, 2048))), nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, upload).Return(nil)
+
+		u, err := us.CommitImageUpload(context.TODO(), uid, upload)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewBadRequest("imageFile must be at most 1024 bytes"), err)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, upload)
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not an image", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockImageRepository.On("GetProfile", mock.Anything, upload).Return(io.NopCloser(strings.NewReader("<svg></svg>")), nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, upload).Return(nil)
+
+		u, err := us.CommitImageUpload(context.TODO(), uid, upload)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewBadRequest("imageFile could not be read as an image"), err)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, upload)
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestImageHistory(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	newService := func(limit int, ttl time.Duration) (model.UserService, *mocks.MockUserRepository, *mocks.MockImageRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
 
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			ImageRepository:      mockImageRepository,
+			AuditEventRepository: mockAuditEventRepository,
+			OutboxRepository:     mockOutboxRepository,
+			Transactor:           mockTransactor,
+			ImageURLTTL:          ttl,
+			ImageHistoryLimit:    limit,
+		})
+
+		return us, mockUserRepository, mockImageRepository
+	}
+
+	image := func(name string) model.ImageURLs {
+		return model.ImageURLs{
+			64:  fmt.Sprintf("http://imageurl.com/%s-64", name),
+			512: fmt.Sprintf("http://imageurl.com/%s-512", name),
+		}
+	}
+
+	version := func(name string) *model.ImageVersion {
+		return &model.ImageVersion{
+			ID:         uuid.New(),
+			ImageURLs:  image(name),
+			ReplacedAt: time.Now().Add(-time.Hour),
+		}
+	}
+
+	t.Run("Replaced image is kept", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService(2, 0)
+
+		older := version("older")
+		oldest := version("oldest")
+		mockUser := &model.User{
+			UID:          uid,
+			ImageURLs:    image("current"),
+			ImageHistory: model.ImageHistory{older, oldest},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		multipartImageFixture := fixture.NewMultipartImage("image.png", "image/png")
+		defer multipartImageFixture.Close()
+
+		mockImageRepository.On("UpdateProfile", mock.Anything, mock.Anything, mock.Anything).Return("http://imageurl.com/new", nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, mock.Anything).Return(nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, mock.Anything).Return(&model.User{UID: uid, ImageURLs: image("new")}, nil)
+
+		// the replaced image goes to the front, and the oldest no longer fits
+		mockUserRepository.On("UpdateImageHistory", mock.Anything, uid, mock.MatchedBy(func(h model.ImageHistory) bool {
+			return len(h) == 2 &&
+				assert.ObjectsAreEqual(image("current"), h[0].ImageURLs) &&
+				time.Since(h[0].ReplacedAt) < time.Minute &&
+				h[1] == older
+		})).Return(nil)
+
+		_, err := us.SetProfileImage(context.TODO(), uid, multipartImageFixture.GetFormFile())
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, "oldest-64")
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, "oldest-512")
+		mockImageRepository.AssertNumberOfCalls(t, "DeleteProfile", 2)
+	})
+
+	t.Run("Lists with signed URLs", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService(2, 10*time.Minute)
+
+		older := version("older")
+		mockUser := &model.User{
+			UID:          uid,
+			ImageURLs:    image("current"),
+			ImageHistory: model.ImageHistory{older},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("SignURL", mock.Anything, "older-64", 10*time.Minute).Return("https://signed/older-64", nil)
+		mockImageRepository.On("SignURL", mock.Anything, "older-512", 10*time.Minute).Return("https://signed/older-512", nil)
+
+		history, err := us.ImageHistory(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageHistory{{
+			ID:         older.ID,
+			ImageURLs:  model.ImageURLs{64: "https://signed/older-64", 512: "https://signed/older-512"},
+			ReplacedAt: older.ReplacedAt,
+		}}, history)
+		// the stored history is left as it is
+		assert.Equal(t, image("older"), older.ImageURLs)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		us, mockUserRepository, _ := newService(2, 0)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+
+		history, err := us.ImageHistory(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ImageHistory{}, history)
+	})
+}
+
+func TestRevertProfileImage(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockImageRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+		mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			ImageRepository:      mockImageRepository,
+			AuditEventRepository: mockAuditEventRepository,
+			OutboxRepository:     mockOutboxRepository,
+			Transactor:           mockTransactor,
+			ImageHistoryLimit:    3,
+		})
+
+		return us, mockUserRepository, mockImageRepository
+	}
+
+	current := model.ImageURLs{64: "http://imageurl.com/current-64"}
+	newer := &model.ImageVersion{ID: uuid.New(), ImageURLs: model.ImageURLs{64: "http://imageurl.com/newer-64"}}
+	target := &model.ImageVersion{ID: uuid.New(), ImageURLs: model.ImageURLs{64: "http://imageurl.com/target-64"}}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository, mockImageRepository := newService()
+
+		mockUser := &model.User{
+			UID:          uid,
+			ImageURLs:    current,
+			ImageHistory: model.ImageHistory{newer, target},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		mockUpdatedUser := &model.User{UID: uid, ImageURLs: target.ImageURLs}
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, target.ImageURLs).Return(mockUpdatedUser, nil)
+
+		// the image it replaces takes its place at the front
+		mockUserRepository.On("UpdateImageHistory", mock.Anything, uid, mock.MatchedBy(func(h model.ImageHistory) bool {
+			return len(h) == 2 &&
+				assert.ObjectsAreEqual(current, h[0].ImageURLs) &&
+				h[1] == newer
+		})).Return(nil)
+
+		u, err := us.RevertProfileImage(context.TODO(), uid, target.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, target.ImageURLs, u.ImageURLs)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		us, mockUserRepository, _ := newService()
+
+		mockUser := &model.User{
+			UID:          uid,
+			ImageURLs:    current,
+			ImageHistory: model.ImageHistory{newer},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		u, err := us.RevertProfileImage(context.TODO(), uid, target.ID)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewNotFound("image version", target.ID.String()), err)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Update error", func(t *testing.T) {
+		us, mockUserRepository, _ := newService()
+
+		mockUser := &model.User{
+			UID:          uid,
+			ImageURLs:    current,
+			ImageHistory: model.ImageHistory{target},
+		}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, target.ImageURLs).Return(&model.User{UID: uid}, nil)
+		mockUserRepository.On("UpdateImageHistory", mock.Anything, uid, mock.Anything).Return(apperrors.NewInternal())
+
+		u, err := us.RevertProfileImage(context.TODO(), uid, target.ID)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
+
+func TestClearProfileImage(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockImageRepository := new(mocks.MockImageRepository)
+	mockAuditEventRepository := new(mocks.MockAuditEventRepository)
+	mockAuditEventRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockOutboxRepository := new(mocks.MockOutboxRepository)
+	mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+	mockTransactor := new(mocks.MockTransactor)
+	mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+
+	us := NewUserService(&USConfig{
+		UserRepository:       mockUserRepository,
+		ImageRepository:      mockImageRepository,
+		AuditEventRepository: mockAuditEventRepository,
+		OutboxRepository:     mockOutboxRepository,
+		Transactor:           mockTransactor,
+		ImageHistoryLimit:    3,
+	})
+
+	// every version is deleted along with the current image
+	mockUser := &model.User{
+		UID:       uid,
+		ImageURLs: model.ImageURLs{64: "http://imageurl.com/current-64"},
+		ImageHistory: model.ImageHistory{
+			{ID: uuid.New(), ImageURLs: model.ImageURLs{64: "http://imageurl.com/older-64"}},
+		},
+	}
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+	mockImageRepository.On("DeleteProfile", mock.Anything, "current-64").Return(nil)
+	mockImageRepository.On("DeleteProfile", mock.Anything, "older-64").Return(nil)
+	mockUserRepository.On("UpdateImage", mock.Anything, uid, model.ImageURLs{}).Return(&model.User{UID: uid}, nil)
+	mockUserRepository.On("UpdateImageHistory", mock.Anything, uid, model.ImageHistory{}).Return(nil)
+
+	err := us.ClearProfileImage(context.TODO(), uid)
+
+	assert.NoError(t, err)
+	mockImageRepository.AssertExpectations(t)
+	mockUserRepository.AssertExpectations(t)
+}
+
+func TestDelete(t *testing.T) {
+	password := "pwcorrect123"
+	hashed, _ := hashPassword(password)
+	gracePeriod := 30 * 24 * time.Hour
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{
+			UID:      uid,
+			Password: hashed,
+			ImageURLs: model.ImageURLs{
+				512: "https://storage.googleapis.com/bucket/imageobject",
+			},
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		us := NewUserService(&USConfig{
+			UserRepository:      mockUserRepository,
+			TokenRepository:     mockTokenRepository,
+			ImageRepository:     mockImageRepository,
+			OutboxRepository:    mockOutboxRepository,
+			Transactor:          mockTransactor,
+			DeletionGracePeriod: gracePeriod,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockUserRepository.On("SoftDelete", mock.Anything, uid, mock.MatchedBy(func(purgeAfter time.Time) bool {
+			return purgeAfter.Sub(time.Now().Add(gracePeriod)) < time.Minute
+		})).Return(nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject").Return(nil)
+
+		err := us.Delete(context.TODO(), uid, password)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{
+			UID:      uid,
+			Password: hashed,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:      mockUserRepository,
+			DeletionGracePeriod: gracePeriod,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+
+		err := us.Delete(context.TODO(), uid, "pwincorrect123")
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Cleanup failure is left for purge", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{
+			UID:      uid,
+			Password: hashed,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		mockOutboxRepository.On("Add", mock.Anything, mock.Anything).Return(nil)
+		mockTransactor := new(mocks.MockTransactor)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		us := NewUserService(&USConfig{
+			UserRepository:      mockUserRepository,
+			TokenRepository:     mockTokenRepository,
+			OutboxRepository:    mockOutboxRepository,
+			Transactor:          mockTransactor,
+			DeletionGracePeriod: gracePeriod,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockUserRepository.On("SoftDelete", mock.Anything, uid, mock.AnythingOfType("time.Time")).Return(nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uid.String()).Return(apperrors.NewInternal())
+
+		err := us.Delete(context.TODO(), uid, password)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	t.Run("Skips users whose data could not be removed", func(t *testing.T) {
+		uidOK, _ := uuid.NewRandom()
+		uidFail, _ := uuid.NewRandom()
+		users := []*model.User{
+			{UID: uidOK},
+			{UID: uidFail, ImageURLs: model.ImageURLs{512: "https://storage.googleapis.com/bucket/failobject"}},
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindPurgeable", mock.Anything, mock.AnythingOfType("time.Time"), purgeBatchSize).Return(users, nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "failobject").Return(apperrors.NewInternal())
+		mockUserRepository.On("Delete", mock.Anything, uidOK).Return(nil)
+
+		purged, err := us.PurgeDeleted(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		mockUserRepository.AssertCalled(t, "Delete", mock.Anything, uidOK)
+		mockUserRepository.AssertNotCalled(t, "Delete", mock.Anything, uidFail)
+	})
 }
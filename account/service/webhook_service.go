@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+)
+
+const (
+	// webhookBatchSize is the most deliveries sent by a single run of Deliver
+	webhookBatchSize = 20
+	// webhookLease is how long a claimed batch is left alone by other workers,
+	// which must be longer than it takes to send the whole batch
+	webhookLease = 10 * time.Minute
+	// minWebhookSecretLength is the shortest secret a partner may choose
+	minWebhookSecretLength = 16
+)
+
+// webhookEvents are the event types a webhook can subscribe to
+var webhookEvents = map[string]bool{
+	model.EventUserCreated:  true,
+	model.EventUserUpdated:  true,
+	model.EventUserDeleted:  true,
+	model.EventImageChanged: true,
+	model.EventEmailChanged: true,
+}
+
+// webhookService acts as a struct for injecting the webhook repositories
+// and the client used to send deliveries
+type webhookService struct {
+	WebhookRepository         model.WebhookRepository
+	WebhookDeliveryRepository model.WebhookDeliveryRepository
+	HTTPClient                *http.Client
+	MaxAttempts               int
+	RetryBackoff              time.Duration
+}
+
+// WSConfig will hold repositories that will eventually be injected
+// into this service layer
+type WSConfig struct {
+	WebhookRepository         model.WebhookRepository
+	WebhookDeliveryRepository model.WebhookDeliveryRepository
+	HTTPClient                *http.Client
+	MaxAttempts               int
+	RetryBackoff              time.Duration
+}
+
+// NewWebhookService is a factory function for initializing
+// a WebhookService with its repository layer dependencies
+func NewWebhookService(c *WSConfig) model.WebhookService {
+	return &webhookService{
+		WebhookRepository:         c.WebhookRepository,
+		WebhookDeliveryRepository: c.WebhookDeliveryRepository,
+		HTTPClient:                c.HTTPClient,
+		MaxAttempts:               c.MaxAttempts,
+		RetryBackoff:              c.RetryBackoff,
+	}
+}
+
+// ListWebhooks returns every webhook of the request's realm
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	return s.WebhookRepository.List(ctx)
+}
+
+// CreateWebhook subscribes a partner's url to events. A secret
+// is generated for the webhook when the partner does not choose one
+func (s *webhookService) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	if err := validateWebhookEvents(w.Events); err != nil {
+		return err
+	}
+
+	if w.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			log.Printf("unable to generate secret for webhook: %v\n", err)
+			return apperrors.NewInternal()
+		}
+		w.Secret = secret
+	} else if len(w.Secret) < minWebhookSecretLength {
+		return apperrors.NewBadRequest(fmt.Sprintf("secret must be at least %d characters", minWebhookSecretLength))
+	}
+
+	return s.WebhookRepository.Create(ctx, w)
+}
+
+// UpdateWebhook changes a webhook's url, events and whether it is active
+func (s *webhookService) UpdateWebhook(ctx context.Context, w *model.Webhook) error {
+	if err := validateWebhookEvents(w.Events); err != nil {
+		return err
+	}
+
+	return s.WebhookRepository.Update(ctx, w)
+}
+
+// DeleteWebhook unsubscribes a webhook, discarding its delivery log
+func (s *webhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.WebhookRepository.Delete(ctx, id)
+}
+
+// ListDeliveries returns a page of a webhook's delivery log, newest first.
+// The cursor is the id of the last delivery of the previous page
+func (s *webhookService) ListDeliveries(ctx context.Context, webhookID uuid.UUID, status string, cursor string, limit int) (*model.WebhookDeliveryPage, error) {
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	var before int64
+	if cursor != "" {
+		var err error
+		before, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil || before <= 0 {
+			return nil, apperrors.NewBadRequest("invalid cursor")
+		}
+	}
+
+	if _, err := s.WebhookRepository.FindByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	// fetch one extra delivery to find out if there is another page
+	deliveries, err := s.WebhookDeliveryRepository.List(ctx, webhookID, status, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.WebhookDeliveryPage{
+		Deliveries: deliveries,
+	}
+
+	if len(deliveries) > limit {
+		page.Deliveries = deliveries[:limit]
+		page.NextCursor = strconv.FormatInt(page.Deliveries[limit-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// ReplayDelivery queues a failed delivery of the webhook to be
+// sent again straight away, with a fresh set of attempts
+func (s *webhookService) ReplayDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (*model.WebhookDelivery, error) {
+	// the webhook must be of the request's realm
+	if _, err := s.WebhookRepository.FindByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	d, err := s.WebhookDeliveryRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.WebhookID != webhookID {
+		return nil, apperrors.NewNotFound("delivery", strconv.FormatInt(id, 10))
+	}
+
+	if d.Status != model.WebhookDeliveryFailed {
+		return nil, apperrors.NewBadRequest("only failed deliveries can be replayed")
+	}
+
+	d.Status = model.WebhookDeliveryPending
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextAttemptAt = time.Now()
+
+	if err := s.WebhookDeliveryRepository.Update(ctx, d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Publish implements EventsBroker by queueing a delivery of the event to each
+// webhook subscribed to it. It is called by the outbox relay, so the deliveries
+// are queued within the same transaction as the event is marked as published.
+// Only webhooks of the user's realm are sent the event
+func (s *webhookService) Publish(ctx context.Context, e *model.UserEvent) error {
+	// events added to the outbox before they carried a realm are all of the default realm
+	realmID := e.RealmID
+	if realmID == "" {
+		realmID = model.DefaultRealmID
+	}
+
+	webhooks, err := s.WebhookRepository.ListSubscribed(ctx, realmID, e.Type)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("could not marshal event: %v: %v\n", e.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	for _, w := range webhooks {
+		d := &model.WebhookDelivery{
+			WebhookID: w.ID,
+			EventID:   e.ID,
+			EventType: e.Type,
+			Payload:   payload,
+		}
+
+		if err := s.WebhookDeliveryRepository.Create(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deliver sends a batch of due deliveries, returning how many were accepted
+// by their receivers. Failed deliveries are retried with exponential backoff
+// until they have used up their attempts, after which they can be replayed
+func (s *webhookService) Deliver(ctx context.Context) (int, error) {
+	deliveries, err := s.WebhookDeliveryRepository.ClaimDue(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := map[uuid.UUID]*model.Webhook{}
+	delivered := 0
+
+	for _, d := range deliveries {
+		w, ok := webhooks[d.WebhookID]
+		if !ok {
+			w, err = s.WebhookRepository.FindByID(ctx, d.WebhookID)
+			if err != nil {
+				// the webhook was deleted along with the delivery
+				continue
+			}
+			webhooks[d.WebhookID] = w
+		}
+
+		if !w.Active {
+			d.Attempts++
+			d.Status = model.WebhookDeliveryFailed
+			d.LastError = "webhook is inactive"
+		} else if err := s.send(ctx, w, d); err != nil {
+			s.fail(d, err)
+		} else {
+			now := time.Now()
+			d.Status = model.WebhookDeliveryDelivered
+			d.LastError = ""
+			d.DeliveredAt = &now
+			delivered++
+		}
+
+		if err := s.WebhookDeliveryRepository.Update(ctx, d); err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// send posts the delivery's payload to the webhook, signed with its secret.
+// The X-Webhook-Signature header holds the time of sending and the hex encoded
+// HMAC-SHA256 of that time and the body, joined by a dot, as "t=<unix>,v1=<hmac>".
+// Receivers should check the signature and reject deliveries with stale times
+func (s *webhookService) send(ctx context.Context, w *model.Webhook, d *model.WebhookDelivery) error {
+	d.Attempts++
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Signature", fmt.Sprintf("t=%s,v1=%s", timestamp, signWebhook(w.Secret, timestamp, d.Payload)))
+
+	res, err := s.HTTPClient.Do(req)
+	if err != nil {
+		d.ResponseStatus = 0
+		return err
+	}
+	defer res.Body.Close()
+
+	// drain some of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	d.ResponseStatus = res.StatusCode
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// fail schedules another attempt to send a delivery with exponential
+// backoff, or marks it as failed once it has used up its attempts
+func (s *webhookService) fail(d *model.WebhookDelivery, reason error) {
+	d.LastError = reason.Error()
+
+	if d.Attempts >= s.MaxAttempts {
+		log.Printf("webhook delivery: %v failed after %d attempts: %v\n", d.ID, d.Attempts, reason)
+		d.Status = model.WebhookDeliveryFailed
+		return
+	}
+
+	d.NextAttemptAt = time.Now().Add(retryBackoff(s.RetryBackoff, d.Attempts))
+}
+
+// signWebhook computes the hex encoded signature of a delivery's payload
+func signWebhook(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateWebhookEvents checks the events a webhook subscribes to are known
+func validateWebhookEvents(events []string) error {
+	for _, e := range events {
+		if !webhookEvents[e] {
+			return apperrors.NewBadRequest(fmt.Sprintf("unknown event: %s", e))
+		}
+	}
+
+	return nil
+}
+
+// generateWebhookSecret creates a random secret for signing deliveries
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
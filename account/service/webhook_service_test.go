@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ndenisj/go_mem/account/model"
+	"github.com/ndenisj/go_mem/account/model/apperrors"
+	"github.com/ndenisj/go_mem/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// receiver is a local webhook endpoint which checks the signature
+// of each delivery and responds with the given status
+type receiver struct {
+	*httptest.Server
+	secret     string
+	status     int
+	deliveries []*model.UserEvent
+	valid      []bool
+}
+
+func newReceiver(secret string, status int) *receiver {
+	r := &receiver{
+		secret: secret,
+		status: status,
+	}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		var timestamp, signature string
+		for _, part := range strings.Split(req.Header.Get("X-Webhook-Signature"), ",") {
+			if v := strings.TrimPrefix(part, "t="); v != part {
+				timestamp = v
+			}
+			if v := strings.TrimPrefix(part, "v1="); v != part {
+				signature = v
+			}
+		}
+
+		e := &model.UserEvent{}
+		json.Unmarshal(body, e)
+
+		r.deliveries = append(r.deliveries, e)
+		r.valid = append(r.valid, hmac.Equal([]byte(signature), []byte(signWebhook(r.secret, timestamp, body))))
+
+		w.WriteHeader(r.status)
+	}))
+
+	return r
+}
+
+func webhookDelivery(id int64, w *model.Webhook, attempts int) (*model.WebhookDelivery, *model.UserEvent) {
+	e := model.NewUserEvent(model.EventUserCreated, &model.User{UID: uuid.New(), Email: "bob@bob.com"})
+	payload, _ := json.Marshal(e)
+
+	return &model.WebhookDelivery{
+		ID:        id,
+		WebhookID: w.ID,
+		EventID:   e.ID,
+		EventType: e.Type,
+		Payload:   payload,
+		Status:    model.WebhookDeliveryPending,
+		Attempts:  attempts,
+	}, e
+}
+
+func TestDeliver(t *testing.T) {
+	secret := "a-very-secret-secret"
+
+	newService := func(r *receiver, w *model.Webhook, deliveries []*model.WebhookDelivery) (model.WebhookService, *mocks.MockWebhookDeliveryRepository) {
+		mockWebhookRepository := new(mocks.MockWebhookRepository)
+		mockWebhookDeliveryRepository := new(mocks.MockWebhookDeliveryRepository)
+
+		mockWebhookRepository.On("FindByID", mock.Anything, w.ID).Return(w, nil)
+		mockWebhookDeliveryRepository.On("ClaimDue", mock.Anything, webhookBatchSize, webhookLease).Return(deliveries, nil)
+		mockWebhookDeliveryRepository.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		return NewWebhookService(&WSConfig{
+			WebhookRepository:         mockWebhookRepository,
+			WebhookDeliveryRepository: mockWebhookDeliveryRepository,
+			HTTPClient:                r.Client(),
+			MaxAttempts:               3,
+			RetryBackoff:              10 * time.Second,
+		}), mockWebhookDeliveryRepository
+	}
+
+	t.Run("Delivers signed payload", func(t *testing.T) {
+		r := newReceiver(secret, http.StatusNoContent)
+		defer r.Close()
+
+		w := &model.Webhook{ID: uuid.New(), URL: r.URL, Secret: secret, Active: true}
+		d, e := webhookDelivery(1, w, 0)
+
+		s, mockWebhookDeliveryRepository := newService(r, w, []*model.WebhookDelivery{d})
+
+		delivered, err := s.Deliver(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Len(t, r.deliveries, 1)
+		assert.True(t, r.valid[0])
+		assert.Equal(t, e.ID, r.deliveries[0].ID)
+		assert.Equal(t, "bob@bob.com", r.deliveries[0].User.Email)
+
+		assert.Equal(t, model.WebhookDeliveryDelivered, d.Status)
+		assert.Equal(t, 1, d.Attempts)
+		assert.Equal(t, http.StatusNoContent, d.ResponseStatus)
+		assert.NotNil(t, d.DeliveredAt)
+		mockWebhookDeliveryRepository.AssertCalled(t, "Update", mock.Anything, d)
+	})
+
+	t.Run("Signature does not verify with another secret", func(t *testing.T) {
+		r := newReceiver("not-the-webhooks-secret", http.StatusOK)
+		defer r.Close()
+
+		w := &model.Webhook{ID: uuid.New(), URL: r.URL, Secret: secret, Active: true}
+		d, _ := webhookDelivery(1, w, 0)
+
+		s, _ := newService(r, w, []*model.WebhookDelivery{d})
+
+		_, err := s.Deliver(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Len(t, r.valid, 1)
+		assert.False(t, r.valid[0])
+	})
+
+	t.Run("Retries with backoff", func(t *testing.T) {
+		r := newReceiver(secret, http.StatusInternalServerError)
+		defer r.Close()
+
+		w := &model.Webhook{ID: uuid.New(), URL: r.URL, Secret: secret, Active: true}
+		d, _ := webhookDelivery(1, w, 1)
+
+		s, _ := newService(r, w, []*model.WebhookDelivery{d})
+
+		delivered, err := s.Deliver(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, delivered)
+		assert.Len(t, r.deliveries, 1)
+
+		// second attempt waits twice the base backoff
+		assert.Equal(t, model.WebhookDeliveryPending, d.Status)
+		assert.Equal(t, 2, d.Attempts)
+		assert.Equal(t, http.StatusInternalServerError, d.ResponseStatus)
+		assert.Contains(t, d.LastError, "500")
+		assert.WithinDuration(t, time.Now().Add(20*time.Second), d.NextAttemptAt, time.Second)
+	})
+
+	t.Run("Fails after max attempts", func(t *testing.T) {
+		r := newReceiver(secret, http.StatusBadGateway)
+		defer r.Close()
+
+		w := &model.Webhook{ID: uuid.New(), URL: r.URL, Secret: secret, Active: true}
+		d, _ := webhookDelivery(1, w, 2)
+
+		s, _ := newService(r, w, []*model.WebhookDelivery{d})
+
+		_, err := s.Deliver(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WebhookDeliveryFailed, d.Status)
+		assert.Equal(t, 3, d.Attempts)
+	})
+
+	t.Run("Unreachable receiver", func(t *testing.T) {
+		r := newReceiver(secret, http.StatusOK)
+		r.Close()
+
+		w := &model.Webhook{ID: uuid.New(), URL: r.URL, Secret: secret, Active: true}
+		d, _ := webhookDelivery(1, w, 0)
+
+		s, _ := newService(r, w, []*model.WebhookDelivery{d})
+
+		_, err := s.Deliver(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WebhookDeliveryPending, d.Status)
+		assert.Equal(t, 0, d.ResponseStatus)
+		assert.NotEmpty(t, d.LastError)
+	})
+
+	t.Run("Inactive webhook", func(t *testing.T) {
+		r := newReceiver(secret, http.StatusOK)
+		defer r.Close()
+
+		w := &model.Webhook{ID: uuid.New(), URL: r.URL, Secret: secret, Active: false}
+		d, _ := webhookDelivery(1, w, 0)
+
+		s, _ := newService(r, w, []*model.WebhookDelivery{d})
+
+		_, err := s.Deliver(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Empty(t, r.deliveries)
+		assert.Equal(t, model.WebhookDeliveryFailed, d.Status)
+	})
+}
+
+func TestWebhookPublish(t *testing.T) {
+	t.Run("Queues a delivery for each subscribed webhook", func(t *testing.T) {
+		mockWebhookRepository := new(mocks.MockWebhookRepository)
+		mockWebhookDeliveryRepository := new(mocks.MockWebhookDeliveryRepository)
+		s := NewWebhookService(&WSConfig{
+			WebhookRepository:         mockWebhookRepository,
+			WebhookDeliveryRepository: mockWebhookDeliveryRepository,
+		})
+
+		e := model.NewUserEvent(model.EventEmailChanged, &model.User{UID: uuid.New(), RealmID: "acme"})
+		webhooks := []*model.Webhook{{ID: uuid.New()}, {ID: uuid.New()}}
+
+		// only the webhooks of the user's realm are sent the event
+		mockWebhookRepository.On("ListSubscribed", mock.Anything, "acme", model.EventEmailChanged).Return(webhooks, nil)
+		mockWebhookDeliveryRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		err := s.Publish(context.TODO(), e)
+
+		assert.NoError(t, err)
+		for _, w := range webhooks {
+			id := w.ID
+			mockWebhookDeliveryRepository.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+				return d.WebhookID == id && d.EventID == e.ID && d.EventType == e.Type && len(d.Payload) > 0
+			}))
+		}
+	})
+
+	t.Run("Queue error", func(t *testing.T) {
+		mockWebhookRepository := new(mocks.MockWebhookRepository)
+		mockWebhookDeliveryRepository := new(mocks.MockWebhookDeliveryRepository)
+		s := NewWebhookService(&WSConfig{
+			WebhookRepository:         mockWebhookRepository,
+			WebhookDeliveryRepository: mockWebhookDeliveryRepository,
+		})
+
+		e := model.NewUserEvent(model.EventUserCreated, &model.User{UID: uuid.New()})
+
+		mockWebhookRepository.On("ListSubscribed", mock.Anything, model.DefaultRealmID, model.EventUserCreated).Return([]*model.Webhook{{ID: uuid.New()}}, nil)
+		mockWebhookDeliveryRepository.On("Create", mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+
+		err := s.Publish(context.TODO(), e)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Event from before realms", func(t *testing.T) {
+		mockWebhookRepository := new(mocks.MockWebhookRepository)
+		s := NewWebhookService(&WSConfig{
+			WebhookRepository: mockWebhookRepository,
+		})
+
+		e := &model.UserEvent{ID: uuid.New(), Type: model.EventUserDeleted, UID: uuid.New()}
+
+		mockWebhookRepository.On("ListSubscribed", mock.Anything, model.DefaultRealmID, model.EventUserDeleted).Return([]*model.Webhook{}, nil)
+
+		err := s.Publish(context.TODO(), e)
+
+		assert.NoError(t, err)
+		mockWebhookRepository.AssertExpectations(t)
+	})
+}
+
+func TestCreateWebhook(t *testing.T) {
+	mockWebhookRepository := new(mocks.MockWebhookRepository)
+	s := NewWebhookService(&WSConfig{
+		WebhookRepository: mockWebhookRepository,
+	})
+
+	mockWebhookRepository.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	t.Run("Generates secret", func(t *testing.T) {
+		w := &model.Webhook{URL: "https://partner.example.com/hooks", Events: []string{model.EventUserCreated}}
+
+		err := s.CreateWebhook(context.TODO(), w)
+
+		assert.NoError(t, err)
+		assert.Len(t, w.Secret, 64)
+	})
+
+	t.Run("Short secret", func(t *testing.T) {
+		w := &model.Webhook{URL: "https://partner.example.com/hooks", Secret: "short"}
+
+		err := s.CreateWebhook(context.TODO(), w)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Unknown event", func(t *testing.T) {
+		w := &model.Webhook{URL: "https://partner.example.com/hooks", Events: []string{"user.unknown"}}
+
+		err := s.CreateWebhook(context.TODO(), w)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockWebhookRepository.AssertNumberOfCalls(t, "Create", 1)
+	})
+}
+
+func TestReplayDelivery(t *testing.T) {
+	webhookID := uuid.New()
+
+	newService := func(d *model.WebhookDelivery) (model.WebhookService, *mocks.MockWebhookDeliveryRepository) {
+		mockWebhookRepository := new(mocks.MockWebhookRepository)
+		mockWebhookRepository.On("FindByID", mock.Anything, webhookID).Return(&model.Webhook{ID: webhookID}, nil)
+		mockWebhookDeliveryRepository := new(mocks.MockWebhookDeliveryRepository)
+		mockWebhookDeliveryRepository.On("FindByID", mock.Anything, d.ID).Return(d, nil)
+		mockWebhookDeliveryRepository.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		return NewWebhookService(&WSConfig{
+			WebhookRepository:         mockWebhookRepository,
+			WebhookDeliveryRepository: mockWebhookDeliveryRepository,
+		}), mockWebhookDeliveryRepository
+	}
+
+	t.Run("Requeues failed delivery", func(t *testing.T) {
+		d := &model.WebhookDelivery{ID: 7, WebhookID: webhookID, Status: model.WebhookDeliveryFailed, Attempts: 5, LastError: "timeout"}
+		s, mockWebhookDeliveryRepository := newService(d)
+
+		replayed, err := s.ReplayDelivery(context.TODO(), webhookID, 7)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WebhookDeliveryPending, replayed.Status)
+		assert.Equal(t, 0, replayed.Attempts)
+		assert.Empty(t, replayed.LastError)
+		mockWebhookDeliveryRepository.AssertCalled(t, "Update", mock.Anything, d)
+	})
+
+	t.Run("Delivery is not failed", func(t *testing.T) {
+		d := &model.WebhookDelivery{ID: 8, WebhookID: webhookID, Status: model.WebhookDeliveryDelivered}
+		s, mockWebhookDeliveryRepository := newService(d)
+
+		_, err := s.ReplayDelivery(context.TODO(), webhookID, 8)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockWebhookDeliveryRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Delivery of another webhook", func(t *testing.T) {
+		d := &model.WebhookDelivery{ID: 9, WebhookID: uuid.New(), Status: model.WebhookDeliveryFailed}
+		s, _ := newService(d)
+
+		_, err := s.ReplayDelivery(context.TODO(), webhookID, 9)
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("Webhook of another realm", func(t *testing.T) {
+		otherID := uuid.New()
+		mockWebhookRepository := new(mocks.MockWebhookRepository)
+		mockWebhookRepository.On("FindByID", mock.Anything, otherID).Return(nil, apperrors.NewNotFound("webhook", otherID.String()))
+		mockWebhookDeliveryRepository := new(mocks.MockWebhookDeliveryRepository)
+		s := NewWebhookService(&WSConfig{
+			WebhookRepository:         mockWebhookRepository,
+			WebhookDeliveryRepository: mockWebhookDeliveryRepository,
+		})
+
+		_, err := s.ReplayDelivery(context.TODO(), otherID, 10)
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+		mockWebhookDeliveryRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestListDeliveries(t *testing.T) {
+	webhookID := uuid.New()
+
+	mockWebhookRepository := new(mocks.MockWebhookRepository)
+	mockWebhookDeliveryRepository := new(mocks.MockWebhookDeliveryRepository)
+	s := NewWebhookService(&WSConfig{
+		WebhookRepository:         mockWebhookRepository,
+		WebhookDeliveryRepository: mockWebhookDeliveryRepository,
+	})
+
+	deliveries := []*model.WebhookDelivery{{ID: 30}, {ID: 29}, {ID: 28}}
+
+	mockWebhookRepository.On("FindByID", mock.Anything, webhookID).Return(&model.Webhook{ID: webhookID}, nil)
+	mockWebhookDeliveryRepository.On("List", mock.Anything, webhookID, model.WebhookDeliveryFailed, int64(31), 3).Return(deliveries, nil)
+
+	page, err := s.ListDeliveries(context.TODO(), webhookID, model.WebhookDeliveryFailed, "31", 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Deliveries, 2)
+	assert.Equal(t, fmt.Sprint(29), page.NextCursor)
+}